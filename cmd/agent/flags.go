@@ -1,11 +1,13 @@
 package main
 
 import (
+	"encoding/base64"
 	"flag"
 	"fmt"
 	stdnetip "net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/peterbourgon/ff/v4"
@@ -18,6 +20,8 @@ type config struct {
 	AgentID  string
 	LogLevel int
 	Options  *agent.Options
+	// Run holds the options of the run subcommand.
+	Run agent.LocalRun
 }
 
 func RegisterFlagsLegacy(c *config, fs *flag.FlagSet) {
@@ -27,11 +31,17 @@ func RegisterFlagsLegacy(c *config, fs *flag.FlagSet) {
 	fs.Var(&netip.AddrPort{AddrPort: &c.Options.Transport.GRPC.ServerAddrPort}, "tinkerbell-grpc-authority", "Tink server GRPC IP:Port")
 	fs.BoolVar(&c.Options.Transport.GRPC.TLSInsecure, "tinkerbell-insecure-tls", false, "Tink server GRPC insecure TLS")
 	fs.BoolVar(&c.Options.Transport.GRPC.TLSEnabled, "tinkerbell-tls", false, "Tink server GRPC use TLS")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSCAFile, "tinkerbell-tls-ca-file", "", "Tink server GRPC TLS CA bundle file")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSCA, "tinkerbell-tls-ca", "", "Tink server GRPC TLS base64 encoded CA bundle")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSCertFile, "tinkerbell-tls-cert-file", "", "Tink server GRPC TLS client certificate file")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSKeyFile, "tinkerbell-tls-key-file", "", "Tink server GRPC TLS client key file")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSServerName, "tinkerbell-tls-server-name", "", "Tink server GRPC TLS server name")
+	fs.StringVar(&c.Options.Transport.GRPC.WorkerToken, "tinkerbell-worker-token", "", "Tink server GRPC worker token")
 }
 
 // SetFromEnvLegacy gets any legacy cli flags from the environment and sets them in the config.
 func SetFromEnvLegacy(c *config) {
-	envs := []string{"REGISTRY_USERNAME", "REGISTRY_PASSWORD", "DOCKER_REGISTRY", "TINKERBELL_GRPC_AUTHORITY", "TINKERBELL_TLS", "TINKERBELL_INSECURE_TLS", "ID", "WORKER_ID"}
+	envs := []string{"REGISTRY_USERNAME", "REGISTRY_PASSWORD", "DOCKER_REGISTRY", "REGISTRY_AUTH", "REGISTRY_MIRRORS", "TINKERBELL_GRPC_AUTHORITY", "TINKERBELL_TLS", "TINKERBELL_INSECURE_TLS", "TINKERBELL_TLS_CA_FILE", "TINKERBELL_TLS_CA", "TINKERBELL_TLS_CERT_FILE", "TINKERBELL_TLS_KEY_FILE", "TINKERBELL_TLS_SERVER_NAME", "TINKERBELL_WORKER_TOKEN", "ID", "WORKER_ID"}
 	for _, env := range envs {
 		if v := os.Getenv(env); v != "" {
 			switch env {
@@ -41,6 +51,10 @@ func SetFromEnvLegacy(c *config) {
 				c.Options.Registry.Pass = v
 			case "DOCKER_REGISTRY":
 				c.Options.Registry.Name = v
+			case "REGISTRY_AUTH":
+				c.Options.Registry.Auths = append(c.Options.Registry.Auths, v)
+			case "REGISTRY_MIRRORS":
+				c.Options.Registry.Mirrors = append(c.Options.Registry.Mirrors, v)
 			case "TINKERBELL_GRPC_AUTHORITY":
 				ap, err := stdnetip.ParseAddrPort(v)
 				if err == nil {
@@ -56,6 +70,18 @@ func SetFromEnvLegacy(c *config) {
 				if err == nil {
 					c.Options.Transport.GRPC.TLSInsecure = b
 				}
+			case "TINKERBELL_TLS_CA_FILE":
+				c.Options.Transport.GRPC.TLSCAFile = v
+			case "TINKERBELL_TLS_CA":
+				c.Options.Transport.GRPC.TLSCA = v
+			case "TINKERBELL_TLS_CERT_FILE":
+				c.Options.Transport.GRPC.TLSCertFile = v
+			case "TINKERBELL_TLS_KEY_FILE":
+				c.Options.Transport.GRPC.TLSKeyFile = v
+			case "TINKERBELL_TLS_SERVER_NAME":
+				c.Options.Transport.GRPC.TLSServerName = v
+			case "TINKERBELL_WORKER_TOKEN":
+				c.Options.Transport.GRPC.WorkerToken = v
 			case "ID", "WORKER_ID":
 				c.AgentID = v
 			}
@@ -63,6 +89,53 @@ func SetFromEnvLegacy(c *config) {
 	}
 }
 
+// SetFromKernelCmdline gets registry, proxy, worker token and TLS configuration from the kernel command line and adds it to the config.
+// Values already set by a flag or environment variable take precedence over the kernel command line proxy, worker token and TLS configuration.
+// This allows Smee to pass it, using extra kernel args, without changes to the OS running the agent.
+func SetFromKernelCmdline(c *config, path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, param := range strings.Fields(string(b)) {
+		k, v, ok := strings.Cut(param, "=")
+		if !ok || v == "" {
+			continue
+		}
+		switch k {
+		case "registry_auth":
+			c.Options.Registry.Auths = append(c.Options.Registry.Auths, v)
+		case "registry_mirrors":
+			c.Options.Registry.Mirrors = append(c.Options.Registry.Mirrors, v)
+		case "HTTP_PROXY":
+			if c.Options.Proxy.HTTPProxy == "" {
+				c.Options.Proxy.HTTPProxy = v
+			}
+		case "HTTPS_PROXY":
+			if c.Options.Proxy.HTTPSProxy == "" {
+				c.Options.Proxy.HTTPSProxy = v
+			}
+		case "NO_PROXY":
+			if len(c.Options.Proxy.NoProxy) == 0 {
+				c.Options.Proxy.NoProxy = strings.Split(v, ",")
+			}
+		case "tinkerbell_worker_token":
+			if c.Options.Transport.GRPC.WorkerToken == "" {
+				c.Options.Transport.GRPC.WorkerToken = v
+			}
+		case "tinkerbell_tls_ca":
+			// Smee passes a base64 encoded CA bundle, one that doesn't decode is ignored.
+			if _, err := base64.StdEncoding.DecodeString(v); err == nil && c.Options.Transport.GRPC.TLSCA == "" && c.Options.Transport.GRPC.TLSCAFile == "" {
+				c.Options.Transport.GRPC.TLSCA = v
+			}
+		case "tinkerbell_tls_server_name":
+			if c.Options.Transport.GRPC.TLSServerName == "" {
+				c.Options.Transport.GRPC.TLSServerName = v
+			}
+		}
+	}
+}
+
 func RegisterAllFlags(c *config) *ff.FlagSet {
 	fst := flag.NewFlagSet("general", flag.ContinueOnError)
 	RegisterRootFlags(c, fst)
@@ -72,13 +145,21 @@ func RegisterAllFlags(c *config) *ff.FlagSet {
 	RegisterRepositoryFlags(c, fscr)
 	fsContainerRegistry := ff.NewFlagSetFrom("container registry", fscr).SetParent(fsTransport)
 
+	fsic := flag.NewFlagSet("image cache", flag.ContinueOnError)
+	RegisterImageCacheFlags(c, fsic)
+	fsImageCache := ff.NewFlagSetFrom("image cache", fsic).SetParent(fsContainerRegistry)
+
 	fsc := flag.NewFlagSet("containerd runtime", flag.ContinueOnError)
 	RegisterContainerdRuntimeFlags(c, fsc)
-	fsContainerd := ff.NewFlagSetFrom("containerd runtime", fsc).SetParent(fsContainerRegistry)
+	fsContainerd := ff.NewFlagSetFrom("containerd runtime", fsc).SetParent(fsImageCache)
+
+	fso := flag.NewFlagSet("oci runtime", flag.ContinueOnError)
+	RegisterOCIRuntimeFlags(c, fso)
+	fsOCI := ff.NewFlagSetFrom("oci runtime", fso).SetParent(fsContainerd)
 
 	fsd := flag.NewFlagSet("docker runtime", flag.ContinueOnError)
 	RegisterDockerRuntimeFlags(c, fsd)
-	fsDocker := ff.NewFlagSetFrom("docker runtime", fsd).SetParent(fsContainerd)
+	fsDocker := ff.NewFlagSetFrom("docker runtime", fsd).SetParent(fsOCI)
 
 	fsg := flag.NewFlagSet("grpc transport", flag.ContinueOnError)
 	RegisterGRPCTransportFlags(c, fsg)
@@ -92,17 +173,34 @@ func RegisterAllFlags(c *config) *ff.FlagSet {
 	RegisterNATSTransportFlags(c, fsn)
 	fsNats := ff.NewFlagSetFrom("nats transport", fsn).SetParent(fsFile)
 
+	fsp := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	RegisterProxyFlags(c, fsp)
+	fsProxy := ff.NewFlagSetFrom("proxy", fsp).SetParent(fsNats)
+
 	fsLegacy := flag.NewFlagSet("legacy", flag.ContinueOnError)
 	RegisterFlagsLegacy(c, fsLegacy)
-	fsl := ff.NewFlagSetFrom("legacy", fsLegacy).SetParent(fsNats)
+	fsl := ff.NewFlagSetFrom("legacy", fsLegacy).SetParent(fsProxy)
 
 	return fsl
 }
 
+// RegisterRunFlags registers the flags of the run subcommand, the flags of the runtimes are inherited from parent.
+func RegisterRunFlags(c *config, parent *ff.FlagSet) *ff.FlagSet {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.StringVar(&c.Run.TemplatePath, "template", "", "Template file, holding a Template object or the data of a Template")
+	fs.StringVar(&c.Run.HardwarePath, "hardware", "", "Hardware file, holding the Hardware object the Template is rendered with")
+	fs.Var(ffval.NewList(&c.Run.HardwareMap), "hardware-map", "Workflow hardware map value in the form key=value, for example device_1=00:00:5e:00:53:01, repeatable or comma separated")
+	fs.BoolVar(&c.Run.DryRun, "dry-run", false, "Print the resolved Actions instead of running them")
+
+	return ff.NewFlagSetFrom("run", fs).SetParent(parent)
+}
+
 func RegisterRootFlags(c *config, fs *flag.FlagSet) {
 	fs.StringVar(&c.AgentID, "id", "", "ID of the agent")
 	fs.IntVar(&c.LogLevel, "log-level", 0, "Log level")
-	fs.Var(&c.Options.RuntimeSelected, "runtime", fmt.Sprintf("Container runtime used to run Actions, must be one of [%s, %s]", agent.DockerRuntimeType, agent.ContainerdRuntimeType))
+	fs.Var(&c.Options.RuntimeSelected, "runtime", fmt.Sprintf("Container runtime used to run Actions, must be one of [%s, %s, %s]", agent.DockerRuntimeType, agent.ContainerdRuntimeType, agent.OCIRuntimeType))
+	fs.IntVar(&c.Options.PrefetchConcurrency, "prefetch-concurrency", 2, "Maximum number of images of upcoming Actions pulled in the background while an Action runs, 0 disables prefetching")
+	fs.Var(ffval.NewValueDefault(&c.Options.StopTimeout, 5*time.Second), "stop-timeout", "How long a canceled or timed out Action is given to exit gracefully before it is killed")
 	fs.Var(&c.Options.TransportSelected, "transport", fmt.Sprintf("Transport used to receive Workflows/Actions and to send results, must be one of [%s, %s, %s]", agent.GRPCTransportType, agent.NATSTransportType, agent.FileTransportType))
 }
 
@@ -110,17 +208,39 @@ func RegisterRepositoryFlags(c *config, fs *flag.FlagSet) {
 	fs.StringVar(&c.Options.Registry.Name, "registry-name", "", "Container image Registry name to which to log in")
 	fs.StringVar(&c.Options.Registry.User, "registry-user", "", "Container image Registry user for authentication")
 	fs.StringVar(&c.Options.Registry.Pass, "registry-pass", "", "Container image Registry pass for authentication")
+	fs.Var(ffval.NewList(&c.Options.Registry.Auths), "registry-auth", "Container image Registry credentials in the form registry=user:password, repeatable or comma separated")
+	fs.Var(ffval.NewList(&c.Options.Registry.Mirrors), "registry-mirror", "Container image Registry mirror in the form registry=[http://]mirror, for example docker.io=mirror.example.com:5000, repeatable or comma separated")
+}
+
+func RegisterImageCacheFlags(c *config, fs *flag.FlagSet) {
+	fs.StringVar(&c.Options.ImageCache.Device, "image-cache-device", "", "Device, or file system label in the form LABEL=<label>, mounted to persist pulled images across boots, empty disables the image cache")
+	fs.StringVar(&c.Options.ImageCache.MountPoint, "image-cache-mount-point", "/var/lib/tinkerbell/cache", "Directory where the image cache device is mounted")
+	fs.StringVar(&c.Options.ImageCache.MaxSize, "image-cache-max-size", "20Gi", "Maximum size of the image cache, least recently used images are removed to stay under it, 0 is no limit")
+}
+
+func RegisterProxyFlags(c *config, fs *flag.FlagSet) {
+	fs.StringVar(&c.Options.Proxy.HTTPProxy, "http-proxy", "", "HTTP proxy URL used to pull images and added to the environment of Actions")
+	fs.StringVar(&c.Options.Proxy.HTTPSProxy, "https-proxy", "", "HTTPS proxy URL used to pull images and added to the environment of Actions")
+	fs.Var(ffval.NewList(&c.Options.Proxy.NoProxy), "no-proxy", "Hosts, domains, IPs and CIDRs that are not proxied, repeatable")
 }
 
 func RegisterGRPCTransportFlags(c *config, fs *flag.FlagSet) {
 	fs.Var(&netip.AddrPort{AddrPort: &c.Options.Transport.GRPC.ServerAddrPort}, "grpc-server", "gRPC server address:port")
 	fs.BoolVar(&c.Options.Transport.GRPC.TLSEnabled, "grpc-tls", false, "gRPC TLS enabled")
 	fs.BoolVar(&c.Options.Transport.GRPC.TLSInsecure, "grpc-insecure-tls", false, "gRPC insecure TLS")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSCAFile, "grpc-tls-ca-file", "", "gRPC TLS PEM encoded CA bundle file used to verify the server, enables TLS")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSCA, "grpc-tls-ca", "", "gRPC TLS base64 encoded, PEM or DER, CA bundle used to verify the server, enables TLS")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSCertFile, "grpc-tls-cert-file", "", "gRPC TLS PEM encoded client certificate file, enables TLS")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSKeyFile, "grpc-tls-key-file", "", "gRPC TLS PEM encoded client key file")
+	fs.StringVar(&c.Options.Transport.GRPC.TLSServerName, "grpc-tls-server-name", "", "gRPC TLS name used to verify the server certificate, enables TLS")
 	fs.Var(ffval.NewValueDefault(&c.Options.Transport.GRPC.RetryInterval, 5*time.Second), "grpc-retry-interval", "gRPC retry interval in Seconds")
+	fs.StringVar(&c.Options.Transport.GRPC.WorkerToken, "grpc-worker-token", "", "gRPC worker token, sent to authenticate the worker ID to the Tink server")
+	fs.BoolVar(&c.Options.Transport.GRPC.StreamActions, "grpc-stream-actions", true, "gRPC receive Actions pushed by the Tink server instead of polling, falls back to polling when the server doesn't support it")
 }
 
 func RegisterFileTransportFlags(c *config, fs *flag.FlagSet) {
 	fs.StringVar(&c.Options.Transport.File.WorkflowPath, "workflow-path", "", "Workflow file path")
+	fs.StringVar(&c.Options.Transport.File.ResultsPath, "results-path", "", "Results file path, defaults to the workflow file path with a .results suffix before its extension")
 }
 
 func RegisterNATSTransportFlags(c *config, fs *flag.FlagSet) {
@@ -128,6 +248,8 @@ func RegisterNATSTransportFlags(c *config, fs *flag.FlagSet) {
 	fs.StringVar(&c.Options.Transport.NATS.StreamName, "nats-stream", "tinkerbell", "NATS stream name")
 	fs.StringVar(&c.Options.Transport.NATS.EventsSubject, "nats-events", "workflow_status", "NATS events subject")
 	fs.StringVar(&c.Options.Transport.NATS.ActionsSubject, "nats-actions", "workflow_actions", "NATS actions subject")
+	fs.BoolVar(&c.Options.Transport.NATS.JetStream, "nats-jetstream", false, "NATS read Actions from a durable JetStream consumer and publish JSON events, falls back to a core NATS subscription when the server has no JetStream or no stream")
+	fs.Var(ffval.NewValueDefault(&c.Options.Transport.NATS.AckWait, time.Minute), "nats-ack-wait", "NATS JetStream time to wait for the results of the Actions of a message before it is delivered again")
 }
 
 func RegisterDockerRuntimeFlags(c *config, fs *flag.FlagSet) {
@@ -137,4 +259,12 @@ func RegisterDockerRuntimeFlags(c *config, fs *flag.FlagSet) {
 func RegisterContainerdRuntimeFlags(c *config, fs *flag.FlagSet) {
 	fs.StringVar(&c.Options.Runtime.Containerd.Namespace, "containerd-namespace", "tinkerbell", "Containerd namespace")
 	fs.StringVar(&c.Options.Runtime.Containerd.SocketPath, "containerd-socket", "/run/containerd/containerd.sock", "Containerd socket path")
+	fs.StringVar(&c.Options.Runtime.Containerd.VolumesDir, "containerd-volumes-dir", "/var/lib/tinkerbell/volumes", "Directory under which named volumes are created")
+}
+
+func RegisterOCIRuntimeFlags(c *config, fs *flag.FlagSet) {
+	fs.StringVar(&c.Options.Runtime.OCI.Dir, "oci-dir", "/var/lib/tinkerbell/oci", "Directory holding the pulled images and the bundles of Actions")
+	fs.StringVar(&c.Options.Runtime.OCI.Mode, "oci-mode", "runc", "How Actions are run, must be one of [runc, chroot]")
+	fs.StringVar(&c.Options.Runtime.OCI.RuncPath, "oci-runc-path", "runc", "Path to the runc binary used in the runc mode")
+	fs.StringVar(&c.Options.Runtime.OCI.VolumesDir, "oci-volumes-dir", "/var/lib/tinkerbell/volumes", "Directory under which named volumes are created")
 }
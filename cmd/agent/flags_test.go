@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/tinkerbell/tinkerbell/tink/agent"
+)
+
+func TestSetFromKernelCmdline(t *testing.T) {
+	tests := map[string]struct {
+		cmdline string
+		options agent.Options
+		want    agent.Options
+	}{
+		"worker token": {
+			cmdline: "console=ttyS0 worker_id=00:00:5e:00:53:01 tinkerbell_worker_token=abc123",
+			want:    agent.Options{Transport: agent.Transport{GRPC: agent.GRPCTransport{WorkerToken: "abc123"}}},
+		},
+		"worker token from a flag": {
+			cmdline: "tinkerbell_worker_token=abc123",
+			options: agent.Options{Transport: agent.Transport{GRPC: agent.GRPCTransport{WorkerToken: "from-flag"}}},
+			want:    agent.Options{Transport: agent.Transport{GRPC: agent.GRPCTransport{WorkerToken: "from-flag"}}},
+		},
+		"empty worker token": {
+			cmdline: "tinkerbell_worker_token=",
+			want:    agent.Options{},
+		},
+		"tls ca and server name": {
+			cmdline: "tinkerbell_tls=true tinkerbell_tls_ca=MIIB+w== tinkerbell_tls_server_name=tink.example.com",
+			want:    agent.Options{Transport: agent.Transport{GRPC: agent.GRPCTransport{TLSCA: "MIIB+w==", TLSServerName: "tink.example.com"}}},
+		},
+		"tls ca file from a flag": {
+			cmdline: "tinkerbell_tls_ca=MIIB+w==",
+			options: agent.Options{Transport: agent.Transport{GRPC: agent.GRPCTransport{TLSCAFile: "/ca.pem"}}},
+			want:    agent.Options{Transport: agent.Transport{GRPC: agent.GRPCTransport{TLSCAFile: "/ca.pem"}}},
+		},
+		"tls ca that isn't base64": {
+			cmdline: "tinkerbell_tls_ca=not-base64!",
+			want:    agent.Options{},
+		},
+		"registry and proxy": {
+			cmdline: "registry_mirrors=docker.io=mirror.example.com HTTP_PROXY=http://192.0.2.1:3128 NO_PROXY=192.0.2.2,example.com",
+			want: agent.Options{
+				Registry: agent.Registry{Mirrors: []string{"docker.io=mirror.example.com"}},
+				Proxy:    agent.Proxy{HTTPProxy: "http://192.0.2.1:3128", NoProxy: []string{"192.0.2.2", "example.com"}},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cmdline")
+			if err := os.WriteFile(path, []byte(tt.cmdline+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			c := &config{Options: &tt.options}
+			SetFromKernelCmdline(c, path)
+			if diff := cmp.Diff(tt.want, *c.Options, cmpopts.EquateComparable(netip.AddrPort{})); diff != "" {
+				t.Errorf("unexpected options (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"math"
 	"net/netip"
@@ -55,15 +56,30 @@ func main() {
 		},
 	}
 
+	fs := RegisterAllFlags(c)
+	runCmd := &ff.Command{
+		Name:      "run",
+		Usage:     "tink-agent run --template <file> [--hardware <file>] [--dry-run] [flags]",
+		ShortHelp: "Render a Template and run its Actions on the local runtime.",
+		LongHelp: "Render a Template, with the same functions as the Tink controller, and run its Actions in order on the local runtime, " +
+			"without a Tink server. The Actions run on this machine, the reboot and kexec builtins are skipped. " +
+			"With --dry-run the resolved Actions are printed, in the format of the workflow file of the file transport, instead.",
+		Flags: RegisterRunFlags(c, fs),
+	}
 	rc := &ff.Command{
-		Name:     name,
-		Usage:    "tink-agent [flags]",
-		LongHelp: "Tink Agent runs the workflows.",
-		Flags:    RegisterAllFlags(c),
+		Name:        name,
+		Usage:       "tink-agent [flags]",
+		LongHelp:    "Tink Agent runs the workflows.",
+		Flags:       fs,
+		Subcommands: []*ff.Command{runCmd},
 	}
 
 	if err := rc.Parse(os.Args[1:], ff.WithEnvVarPrefix("AGENT")); err != nil {
-		e := errors.New(ffhelp.Command(rc).String())
+		help := rc
+		if sel := rc.GetSelected(); sel != nil {
+			help = sel
+		}
+		e := errors.New(ffhelp.Command(help).String())
 		if !errors.Is(err, ff.ErrHelp) {
 			e = fmt.Errorf("%w\n%s", e, err)
 		}
@@ -74,8 +90,24 @@ func main() {
 		return
 	}
 
+	if rc.GetSelected() == runCmd {
+		if c.Run.TemplatePath == "" {
+			fmt.Fprintf(os.Stderr, "%v\n--template is required\n", ffhelp.Command(runCmd))
+			exitCode = 1
+			return
+		}
+		// Logs go to stderr so that the output of a dry run can be redirected to a workflow file.
+		log := newLogger(os.Stderr, c.LogLevel)
+		if err := c.Options.RunTemplate(ctx, log, c.Run); err != nil {
+			log.Error(err, "failed to run template")
+			exitCode = 1
+		}
+		return
+	}
+
 	// For legacy flags, we need to check the environment variables without the prefix.
 	SetFromEnvLegacy(c)
+	SetFromKernelCmdline(c, "/proc/cmdline")
 
 	// TODO(jacobweinstock): do input validation. required fields, etc.
 	// ID is required
@@ -93,8 +125,13 @@ func main() {
 	log.Info("stopped Agent")
 }
 
-// defaultLogger uses the slog logr implementation.
+// defaultLogger uses the slog logr implementation and writes to stdout.
 func defaultLogger(level int) logr.Logger {
+	return newLogger(os.Stdout, level)
+}
+
+// newLogger uses the slog logr implementation.
+func newLogger(w io.Writer, level int) logr.Logger {
 	// source file and function can be long. This makes the logs less readable.
 	// for improved readability, truncate source file to last 3 parts and remove the function entirely.
 	customAttr := func(_ []string, a slog.Attr) slog.Attr {
@@ -136,7 +173,7 @@ func defaultLogger(level int) logr.Logger {
 		Level:       slog.Level(-level),
 		ReplaceAttr: customAttr,
 	}
-	log := slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	log := slog.New(slog.NewJSONHandler(w, opts))
 
 	return logr.FromSlogHandler(log.Handler())
 }
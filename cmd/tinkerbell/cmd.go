@@ -69,7 +69,14 @@ func Execute(ctx context.Context, cancel context.CancelFunc, args []string) erro
 		BindPort: 50061,
 	}
 	ts := &flag.TinkServerConfig{
-		Config:   server.NewConfig(),
+		Config: server.NewConfig(
+			server.WithActionLogLines(server.DefaultActionLogLines),
+			server.WithNATS(server.NATS{
+				StreamName:     "tinkerbell",
+				ActionsSubject: "workflow_actions",
+				EventsSubject:  "workflow_status",
+			}),
+		),
 		BindAddr: detectPublicIPv4(),
 		BindPort: 42113,
 	}
@@ -139,12 +146,13 @@ func Execute(ctx context.Context, cancel context.CancelFunc, args []string) erro
 
 	// Smee
 	s.Convert(&globals.TrustedProxies, globals.PublicIP)
+	s.Config.TinkServer.WorkerTokenSecret = globals.WorkerTokenSecret
 
 	// Tootles
 	h.Convert(&globals.TrustedProxies)
 
 	// Tink Server
-	ts.Convert()
+	ts.Convert(globals.BackendKubeNamespace, globals.WorkerTokenSecret)
 
 	// Tink Controller
 	tc.Config.LeaderElectionNamespace = leaderElectionNamespace(inCluster(), tc.Config.EnableLeaderElection, tc.Config.LeaderElectionNamespace)
@@ -24,6 +24,7 @@ type GlobalConfig struct {
 	EnableRufio          bool
 	EnableSecondStar     bool
 	EnableCRDMigrations  bool
+	WorkerTokenSecret    string
 	EmbeddedGlobalConfig EmbeddedGlobalConfig
 }
 
@@ -51,6 +52,7 @@ func RegisterGlobal(fs *Set, gc *GlobalConfig) {
 	fs.Register(EnableKubeAPIServer, ffval.NewValueDefault(&gc.EmbeddedGlobalConfig.EnableKubeAPIServer, gc.EmbeddedGlobalConfig.EnableKubeAPIServer))
 	fs.Register(EnableETCD, ffval.NewValueDefault(&gc.EmbeddedGlobalConfig.EnableETCD, gc.EmbeddedGlobalConfig.EnableETCD))
 	fs.Register(EnableCRDMigrations, ffval.NewValueDefault(&gc.EnableCRDMigrations, gc.EnableCRDMigrations))
+	fs.Register(WorkerTokenSecret, ffval.NewValueDefault(&gc.WorkerTokenSecret, gc.WorkerTokenSecret))
 }
 
 // All these flags are used by at least two services or
@@ -147,3 +149,8 @@ var EnableCRDMigrations = Config{
 	Name:  "enable-crd-migrations",
 	Usage: "create CRDs in the cluster",
 }
+
+var WorkerTokenSecret = Config{
+	Name:  "worker-token-secret",
+	Usage: "secret from which per-worker tokens are derived, when set Smee passes each worker its token on the kernel command line and the Tink server requires it",
+}
@@ -90,6 +90,13 @@ func RegisterSmeeFlags(fs *Set, sc *SmeeConfig) {
 	fs.Register(TinkServerAddrPort, ffval.NewValueDefault(&sc.Config.TinkServer.AddrPort, sc.Config.TinkServer.AddrPort))
 	fs.Register(TinkServerUseTLS, ffval.NewValueDefault(&sc.Config.TinkServer.UseTLS, sc.Config.TinkServer.UseTLS))
 	fs.Register(TinkServerInsecureTLS, ffval.NewValueDefault(&sc.Config.TinkServer.InsecureTLS, sc.Config.TinkServer.InsecureTLS))
+	fs.Register(TinkServerTLSCAFile, ffval.NewValueDefault(&sc.Config.TinkServer.CAFile, sc.Config.TinkServer.CAFile))
+	fs.Register(TinkServerTLSServerName, ffval.NewValueDefault(&sc.Config.TinkServer.ServerName, sc.Config.TinkServer.ServerName))
+
+	// Proxy Flags
+	fs.Register(ProxyHTTP, ffval.NewValueDefault(&sc.Config.Proxy.HTTPProxy, sc.Config.Proxy.HTTPProxy))
+	fs.Register(ProxyHTTPS, ffval.NewValueDefault(&sc.Config.Proxy.HTTPSProxy, sc.Config.Proxy.HTTPSProxy))
+	fs.Register(ProxyNoProxy, ffval.NewList(&sc.Config.Proxy.NoProxy))
 }
 
 // Convert CLI specific fields to smee.Config fields.
@@ -377,6 +384,31 @@ var TinkServerInsecureTLS = Config{
 	Usage: "[tink] Skip TLS verification when connecting to the Tink server",
 }
 
+var TinkServerTLSCAFile = Config{
+	Name:  "ipxe-script-tink-server-tls-ca-file",
+	Usage: "[tink] path to a PEM encoded CA bundle passed to workers to verify the Tink server",
+}
+
+var TinkServerTLSServerName = Config{
+	Name:  "ipxe-script-tink-server-tls-server-name",
+	Usage: "[tink] name passed to workers to verify the certificate of the Tink server",
+}
+
+var ProxyHTTP = Config{
+	Name:  "ipxe-script-http-proxy",
+	Usage: "[proxy] HTTP proxy URL passed to workers, on the kernel command line, for pulling images and in Actions",
+}
+
+var ProxyHTTPS = Config{
+	Name:  "ipxe-script-https-proxy",
+	Usage: "[proxy] HTTPS proxy URL passed to workers, on the kernel command line, for pulling images and in Actions",
+}
+
+var ProxyNoProxy = Config{
+	Name:  "ipxe-script-no-proxy",
+	Usage: "[proxy] hosts, domains, IPs and CIDRs passed to workers that are not proxied",
+}
+
 var SmeeLogLevel = Config{
 	Name:  "smee-log-level",
 	Usage: "the higher the number the more verbose, level 0 inherits the global log level",
@@ -4,6 +4,7 @@ import (
 	"net/netip"
 
 	"github.com/peterbourgon/ff/v4/ffval"
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
 	"github.com/tinkerbell/tinkerbell/pkg/backend/kube"
 	ntip "github.com/tinkerbell/tinkerbell/pkg/flag/netip"
 	"github.com/tinkerbell/tinkerbell/tink/server"
@@ -18,17 +19,40 @@ type TinkServerConfig struct {
 
 var KubeIndexesTinkServer = map[kube.IndexType]kube.Index{
 	kube.IndexTypeWorkflowByNonTerminalState: kube.Indexes[kube.IndexTypeWorkflowByNonTerminalState],
+	kube.IndexTypeMACAddr:                    kube.Indexes[kube.IndexTypeMACAddr],
 }
 
 func RegisterTinkServerFlags(fs *Set, t *TinkServerConfig) {
 	fs.Register(TinkServerBindAddr, &ntip.Addr{Addr: &t.BindAddr})
 	fs.Register(TinkServerBindPort, ffval.NewValueDefault(&t.BindPort, t.BindPort))
 	fs.Register(TinkServerLogLevel, ffval.NewValueDefault(&t.LogLevel, t.LogLevel))
+	fs.Register(TinkServerAutoEnrollmentEnabled, ffval.NewValueDefault(&t.Config.AutoEnrollment.Enabled, t.Config.AutoEnrollment.Enabled))
+	fs.Register(TinkServerAutoEnrollmentNamespace, ffval.NewValueDefault(&t.Config.AutoEnrollment.Namespace, t.Config.AutoEnrollment.Namespace))
+	fs.Register(TinkServerAutoEnrollmentTemplate, ffval.NewValueDefault(&t.Config.AutoEnrollment.TemplateRef, t.Config.AutoEnrollment.TemplateRef))
+	fs.Register(TinkServerBlockOnInventoryDrift, ffval.NewValueDefault(&t.Config.BlockOnInventoryDrift, t.Config.BlockOnInventoryDrift))
+	fs.Register(TinkServerTLSCertFile, ffval.NewValueDefault(&t.Config.TLS.CertFile, t.Config.TLS.CertFile))
+	fs.Register(TinkServerTLSKeyFile, ffval.NewValueDefault(&t.Config.TLS.KeyFile, t.Config.TLS.KeyFile))
+	fs.Register(TinkServerTLSClientCAFile, ffval.NewValueDefault(&t.Config.TLS.ClientCAFile, t.Config.TLS.ClientCAFile))
+	fs.Register(TinkServerAdminToken, ffval.NewValueDefault(&t.Config.AdminToken, t.Config.AdminToken))
+	fs.Register(TinkServerActionLogLines, ffval.NewValueDefault(&t.Config.ActionLogLines, t.Config.ActionLogLines))
+	fs.Register(TinkServerNATSEnabled, ffval.NewValueDefault(&t.Config.NATS.Enabled, t.Config.NATS.Enabled))
+	fs.Register(TinkServerNATSURL, ffval.NewValueDefault(&t.Config.NATS.URL, t.Config.NATS.URL))
+	fs.Register(TinkServerNATSEmbeddedBindAddrPort, &ntip.AddrPort{AddrPort: &t.Config.NATS.EmbeddedBindAddrPort})
+	fs.Register(TinkServerNATSEmbeddedStoreDir, ffval.NewValueDefault(&t.Config.NATS.EmbeddedStoreDir, t.Config.NATS.EmbeddedStoreDir))
+	fs.Register(TinkServerNATSStream, ffval.NewValueDefault(&t.Config.NATS.StreamName, t.Config.NATS.StreamName))
+	fs.Register(TinkServerNATSActionsSubject, ffval.NewValueDefault(&t.Config.NATS.ActionsSubject, t.Config.NATS.ActionsSubject))
+	fs.Register(TinkServerNATSEventsSubject, ffval.NewValueDefault(&t.Config.NATS.EventsSubject, t.Config.NATS.EventsSubject))
 }
 
 // Convert TinkServerConfig data types to tink server server.Config data types.
-func (t *TinkServerConfig) Convert() {
+// namespace is the namespace the backend watches, it is the default namespace for auto-enrolled Hardware.
+// workerTokenSecret is the secret from which worker tokens are derived.
+func (t *TinkServerConfig) Convert(namespace, workerTokenSecret string) {
 	t.Config.BindAddrPort = netip.AddrPortFrom(t.BindAddr, t.BindPort)
+	t.Config.WorkerTokenSecret = workerTokenSecret
+	if t.Config.AutoEnrollment.Namespace == "" {
+		t.Config.AutoEnrollment.Namespace = namespace
+	}
 }
 
 var TinkServerBindAddr = Config{
@@ -45,3 +69,83 @@ var TinkServerLogLevel = Config{
 	Name:  "tink-server-log-level",
 	Usage: "the higher the number the more verbose, level 0 inherits the global log level",
 }
+
+var TinkServerAutoEnrollmentEnabled = Config{
+	Name:  "tink-server-auto-enrollment-enabled",
+	Usage: "create Hardware, labelled " + server.DiscoveredLabel + ", from the attributes of workers that are not known",
+}
+
+var TinkServerAutoEnrollmentNamespace = Config{
+	Name:  "tink-server-auto-enrollment-namespace",
+	Usage: "namespace in which auto-enrolled Hardware and Workflows are created, defaults to the backend kube namespace",
+}
+
+var TinkServerAutoEnrollmentTemplate = Config{
+	Name:  "tink-server-auto-enrollment-template",
+	Usage: "name of a Template used to create a Workflow for auto-enrolled Hardware, no Workflow is created when empty",
+}
+
+var TinkServerBlockOnInventoryDrift = Config{
+	Name:  "tink-server-block-on-inventory-drift",
+	Usage: "don't start Workflows on Hardware with an InventoryDrift condition until it is acknowledged with the " + v1alpha1.InventoryDriftAcknowledgedAnnotation + " annotation",
+}
+
+var TinkServerTLSCertFile = Config{
+	Name:  "tink-server-tls-cert-file",
+	Usage: "path to a PEM encoded certificate for serving gRPC over TLS, plaintext is served when not set",
+}
+
+var TinkServerTLSKeyFile = Config{
+	Name:  "tink-server-tls-key-file",
+	Usage: "path to the PEM encoded private key of the TLS certificate",
+}
+
+var TinkServerTLSClientCAFile = Config{
+	Name:  "tink-server-tls-client-ca-file",
+	Usage: "path to a PEM encoded CA bundle, when set clients must present a certificate signed by it that names their worker ID (mTLS)",
+}
+
+var TinkServerAdminToken = Config{
+	Name:  "tink-server-admin-token",
+	Usage: "token that callers of admin RPCs, like CancelWorkflow, send in the x-tinkerbell-admin-token gRPC metadata key, admin RPCs are denied when not set",
+}
+
+var TinkServerActionLogLines = Config{
+	Name:  "tink-server-action-log-lines",
+	Usage: "number of lines of the output of an Action kept and added to its status message when it fails or times out",
+}
+
+var TinkServerNATSEnabled = Config{
+	Name:  "tink-server-nats-enabled",
+	Usage: "publish Actions to, and read events from, workers using the NATS transport of the Tink agent with JetStream, workers are not authenticated so it can't be used with worker tokens or client certificates",
+}
+
+var TinkServerNATSURL = Config{
+	Name:  "tink-server-nats-url",
+	Usage: "NATS server URL, for example nats://127.0.0.1:4222, not used with an embedded NATS server",
+}
+
+var TinkServerNATSEmbeddedBindAddrPort = Config{
+	Name:  "tink-server-nats-embedded-bind-addr-port",
+	Usage: "ip:port on which an embedded NATS server, with JetStream and without authentication or TLS, listens, no NATS server is embedded when not set",
+}
+
+var TinkServerNATSEmbeddedStoreDir = Config{
+	Name:  "tink-server-nats-embedded-store-dir",
+	Usage: "directory in which the embedded NATS server stores streams, defaults to a directory in the system temp directory",
+}
+
+var TinkServerNATSStream = Config{
+	Name:  "tink-server-nats-stream",
+	Usage: "NATS JetStream stream of Actions and events, created when it doesn't exist",
+}
+
+var TinkServerNATSActionsSubject = Config{
+	Name:  "tink-server-nats-actions-subject",
+	Usage: "NATS subject, after the stream name and worker ID, to which Actions are published",
+}
+
+var TinkServerNATSEventsSubject = Config{
+	Name:  "tink-server-nats-events-subject",
+	Usage: "NATS subject, after the stream name and worker ID, from which events are read",
+}
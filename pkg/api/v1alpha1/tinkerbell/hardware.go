@@ -21,6 +21,18 @@ const (
 	HardwareReady = HardwareState("Ready")
 )
 
+// HardwareConditionType is a type of Hardware condition.
+type HardwareConditionType string
+
+const (
+	// InventoryDrift indicates the inventory reported by the Tink agent differs from the previously recorded inventory.
+	InventoryDrift HardwareConditionType = "InventoryDrift"
+)
+
+// InventoryDriftAcknowledgedAnnotation is the annotation used to acknowledge an InventoryDrift condition.
+// Once acknowledged the condition is set to false and the annotation is removed.
+const InventoryDriftAcknowledgedAnnotation = "tinkerbell.org/inventory-drift-acknowledged"
+
 // +kubebuilder:object:root=true
 
 // HardwareList contains a list of Hardware.
@@ -275,4 +287,170 @@ type Disk struct {
 type HardwareStatus struct {
 	//+optional
 	State HardwareState `json:"state,omitempty"`
+
+	// Inventory is the hardware inventory reported by the Tink agent running on the Hardware.
+	//+optional
+	Inventory *Inventory `json:"inventory,omitempty"`
+
+	// LastSeen is the last time the Tink agent running on the Hardware contacted the Tink server.
+	//+optional
+	LastSeen *metav1.Time `json:"lastSeen,omitempty"`
+
+	// Conditions are the latest available observations of an object's current state.
+	//
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=atomic
+	Conditions []HardwareCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// HardwareCondition describes the current state of Hardware.
+type HardwareCondition struct {
+	// Type of the condition.
+	Type HardwareConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status metav1.ConditionStatus `json:"status"`
+	// Reason is a (brief) reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time when the condition was created.
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
+}
+
+// HasCondition checks if the hct condition is present with status cs.
+func (h *HardwareStatus) HasCondition(hct HardwareConditionType, cs metav1.ConditionStatus) bool {
+	for _, c := range h.Conditions {
+		if c.Type == hct {
+			return c.Status == cs
+		}
+	}
+
+	return false
+}
+
+// SetCondition updates conditions. If the condition already exists, it updates it.
+// If the condition doesn't exist then it appends the new one (hc).
+func (h *HardwareStatus) SetCondition(hc HardwareCondition) {
+	for i, c := range h.Conditions {
+		if c.Type == hc.Type {
+			h.Conditions[i] = hc
+			return
+		}
+	}
+
+	h.Conditions = append(h.Conditions, hc)
+}
+
+// Inventory is the hardware inventory of a machine as discovered by the Tink agent.
+type Inventory struct {
+	//+optional
+	CPU *InventoryCPU `json:"cpu,omitempty"`
+
+	//+optional
+	Memory *InventoryMemory `json:"memory,omitempty"`
+
+	// BlockDevices are sorted by name.
+	//+optional
+	BlockDevices []InventoryBlockDevice `json:"blockDevices,omitempty"`
+
+	// NICs are sorted by name.
+	//+optional
+	NICs []InventoryNIC `json:"nics,omitempty"`
+
+	//+optional
+	PCIDevices []InventoryPCIDevice `json:"pciDevices,omitempty"`
+
+	//+optional
+	GPUs []InventoryPCIDevice `json:"gpus,omitempty"`
+
+	//+optional
+	Chassis *InventoryChassis `json:"chassis,omitempty"`
+
+	//+optional
+	BIOS *InventoryBIOS `json:"bios,omitempty"`
+
+	//+optional
+	Baseboard *InventoryBaseboard `json:"baseboard,omitempty"`
+
+	//+optional
+	Product *InventoryProduct `json:"product,omitempty"`
+}
+
+// InventoryCPU describes the processors of a machine.
+type InventoryCPU struct {
+	TotalCores   int64                `json:"totalCores,omitempty"`
+	TotalThreads int64                `json:"totalThreads,omitempty"`
+	Processors   []InventoryProcessor `json:"processors,omitempty"`
+}
+
+// InventoryProcessor describes a physical processor.
+type InventoryProcessor struct {
+	ID      int64  `json:"id"`
+	Cores   int64  `json:"cores,omitempty"`
+	Threads int64  `json:"threads,omitempty"`
+	Vendor  string `json:"vendor,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
+
+// InventoryMemory describes the memory of a machine.
+type InventoryMemory struct {
+	Total  *resource.Quantity `json:"total,omitempty"`
+	Usable *resource.Quantity `json:"usable,omitempty"`
+}
+
+// InventoryBlockDevice describes a block device, a disk for example.
+type InventoryBlockDevice struct {
+	Name              string             `json:"name"`
+	ControllerType    string             `json:"controllerType,omitempty"`
+	DriveType         string             `json:"driveType,omitempty"`
+	Size              *resource.Quantity `json:"size,omitempty"`
+	PhysicalBlockSize int64              `json:"physicalBlockSize,omitempty"`
+	Vendor            string             `json:"vendor,omitempty"`
+	Model             string             `json:"model,omitempty"`
+}
+
+// InventoryNIC describes a network interface.
+type InventoryNIC struct {
+	Name  string `json:"name"`
+	MAC   string `json:"mac,omitempty"`
+	Speed string `json:"speed,omitempty"`
+}
+
+// InventoryPCIDevice describes a PCI device.
+type InventoryPCIDevice struct {
+	Vendor  string `json:"vendor,omitempty"`
+	Product string `json:"product,omitempty"`
+	Class   string `json:"class,omitempty"`
+	Driver  string `json:"driver,omitempty"`
+}
+
+// InventoryChassis describes the chassis of a machine.
+type InventoryChassis struct {
+	Serial string `json:"serial,omitempty"`
+	Vendor string `json:"vendor,omitempty"`
+}
+
+// InventoryBIOS describes the BIOS of a machine.
+type InventoryBIOS struct {
+	Vendor      string `json:"vendor,omitempty"`
+	Version     string `json:"version,omitempty"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+}
+
+// InventoryBaseboard describes the baseboard of a machine.
+type InventoryBaseboard struct {
+	Vendor  string `json:"vendor,omitempty"`
+	Product string `json:"product,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// InventoryProduct describes the product of a machine.
+type InventoryProduct struct {
+	Name   string `json:"name,omitempty"`
+	Vendor string `json:"vendor,omitempty"`
 }
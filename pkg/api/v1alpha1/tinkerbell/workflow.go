@@ -24,6 +24,11 @@ const (
 	WorkflowStateSuccess   = WorkflowState("SUCCESS")
 	WorkflowStateFailed    = WorkflowState("FAILED")
 	WorkflowStateTimeout   = WorkflowState("TIMEOUT")
+	// WorkflowStateVerificationFailed is the state of an Action, and its Workflow, whose image didn't satisfy the
+	// ImagePolicy of the Workflow. The Action is not run.
+	WorkflowStateVerificationFailed = WorkflowState("VERIFICATION_FAILED")
+	// WorkflowStateCanceled is the state of a Workflow that was canceled, and of the Action that was stopped because of it.
+	WorkflowStateCanceled = WorkflowState("CANCELED")
 
 	BootJobFailed           WorkflowConditionType = "BootJobFailed"
 	BootJobComplete         WorkflowConditionType = "BootJobComplete"
@@ -33,6 +38,9 @@ const (
 	ToggleAllowNetbootTrue  WorkflowConditionType = "AllowNetbootTrue"
 	ToggleAllowNetbootFalse WorkflowConditionType = "AllowNetbootFalse"
 	TemplateRenderedSuccess WorkflowConditionType = "TemplateRenderedSuccess"
+	// CancelRequested is set when the controller first sees a canceled Workflow with a running Action.
+	// Its time is when the deadline for the worker to report the stopped Action starts.
+	CancelRequested WorkflowConditionType = "CancelRequested"
 
 	TemplateRenderingSuccessful TemplateRendering = "successful"
 	TemplateRenderingFailed     TemplateRendering = "failed"
@@ -83,6 +91,30 @@ type WorkflowSpec struct {
 
 	// BootOptions are options that control the booting of Hardware.
 	BootOptions BootOptions `json:"bootOptions,omitempty"`
+
+	// ImagePolicy is how the images of Actions are verified before they run.
+	// Images are not verified when it is not set.
+	// +optional
+	ImagePolicy *ImagePolicy `json:"imagePolicy,omitempty"`
+
+	// Cancel cancels the Workflow. No more Actions are served, a running Action is stopped by its worker,
+	// and the Workflow ends in the CANCELED state. A Workflow that already ended is not changed.
+	// +optional
+	Cancel bool `json:"cancel,omitempty"`
+}
+
+// ImagePolicy is how the images of Actions are verified before they run.
+// An image is allowed when it is pinned in Digests and resolves to its pinned digest or, when it is not pinned,
+// when it has a cosign signature made with one of the PublicKeys. Images are run by the digest that was verified.
+type ImagePolicy struct {
+	// PublicKeys are PEM encoded ECDSA, RSA or Ed25519 public keys, for example cosign public keys.
+	// +optional
+	PublicKeys []string `json:"publicKeys,omitempty"`
+
+	// Digests pins images to a manifest digest, in the form "sha256:<hex>", by image reference.
+	// For example "quay.io/tinkerbell/actions/image2disk:latest": "sha256:...".
+	// +optional
+	Digests map[string]string `json:"digests,omitempty"`
 }
 
 // BootOptions are options that control the booting of Hardware.
@@ -148,6 +180,10 @@ type WorkflowStatus struct {
 	// Tasks are the tasks to be run by the worker(s).
 	Tasks []Task `json:"tasks,omitempty"`
 
+	// Failure tracks the handling of a failed or timed out Action that has on-failure or on-timeout actions.
+	// +optional
+	Failure *FailureState `json:"failure,omitempty"`
+
 	// Conditions are the latest available observations of an object's current state.
 	//
 	// +optional
@@ -191,6 +227,19 @@ type WorkflowCondition struct {
 	Time *metav1.Time `json:"time,omitempty" protobuf:"bytes,7,opt,name=time"`
 }
 
+// FailureState holds the on-failure or on-timeout actions being run because an Action failed or timed out.
+type FailureState struct {
+	// TaskID is the ID of the Task with the Action that failed.
+	TaskID string `json:"taskID"`
+	// ActionID is the ID of the Action that failed.
+	ActionID string `json:"actionID"`
+	// State is the state of the Action that failed, FAILED or TIMEOUT.
+	// The Workflow ends in this state once all Actions have run.
+	State WorkflowState `json:"state"`
+	// Actions are run in order, regardless of whether a previous one failed.
+	Actions []Action `json:"actions,omitempty"`
+}
+
 // Task represents a series of actions to be completed by a worker.
 type Task struct {
 	ID          string            `json:"id"`
@@ -199,23 +248,57 @@ type Task struct {
 	Actions     []Action          `json:"actions"`
 	Volumes     []string          `json:"volumes,omitempty"`
 	Environment map[string]string `json:"environment,omitempty"`
+	// OnFailure are actions run when any Action in the Task fails or times out.
+	OnFailure []Action `json:"onFailure,omitempty"`
+	// DependsOn are the names of Tasks that must complete successfully before this Task runs.
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 // Action represents a workflow action.
 type Action struct {
-	ID                string            `json:"id"`
-	Name              string            `json:"name,omitempty"`
-	Image             string            `json:"image,omitempty"`
-	Timeout           int64             `json:"timeout,omitempty"`
-	Command           []string          `json:"command,omitempty"`
-	Volumes           []string          `json:"volumes,omitempty"`
-	Pid               string            `json:"pid,omitempty"`
-	Environment       map[string]string `json:"environment,omitempty"`
-	State             WorkflowState     `json:"state,omitempty"`
-	ExecutionStart    *metav1.Time      `json:"executionStart,omitempty"`
-	ExecutionStop     *metav1.Time      `json:"executionStop,omitempty"`
-	ExecutionDuration string            `json:"executionDuration,omitempty"`
-	Message           string            `json:"message,omitempty"`
+	ID      string   `json:"id"`
+	Name    string   `json:"name,omitempty"`
+	Image   string   `json:"image,omitempty"`
+	Timeout int64    `json:"timeout,omitempty"`
+	Command []string `json:"command,omitempty"`
+	Volumes []string `json:"volumes,omitempty"`
+	Pid     string   `json:"pid,omitempty"`
+	// Network is the network namespace of the Action, for example "host".
+	Network     string            `json:"network,omitempty"`
+	Environment map[string]string `json:"environment,omitempty"`
+	// OnTimeout is a command run, in the Action's image, when the Action times out.
+	OnTimeout []string `json:"onTimeout,omitempty"`
+	// OnFailure is a command run, in the Action's image, when the Action fails.
+	OnFailure         []string      `json:"onFailure,omitempty"`
+	State             WorkflowState `json:"state,omitempty"`
+	ExecutionStart    *metav1.Time  `json:"executionStart,omitempty"`
+	ExecutionStop     *metav1.Time  `json:"executionStop,omitempty"`
+	ExecutionDuration string        `json:"executionDuration,omitempty"`
+	Message           string        `json:"message,omitempty"`
+	// Retries is the number of times to retry the Action after it fails.
+	Retries int64 `json:"retries,omitempty"`
+	// Backoff is the number of seconds to wait between retries.
+	Backoff int64 `json:"backoff,omitempty"`
+	// Attempts holds the result of each execution of the Action when it is retried.
+	Attempts []ActionAttempt `json:"attempts,omitempty"`
+	// Reboot reboots the worker once the Action succeeds. The Action is reported as successful before the reboot
+	// and the Workflow continues with the next Action when the worker boots again.
+	Reboot bool `json:"reboot,omitempty"`
+	// BootID is the boot ID of the worker when it reported the Action running. An Action that reboots the worker
+	// is complete once the worker asks for Actions with another boot ID.
+	BootID string `json:"bootID,omitempty"`
+	// Served is true once the Action was sent to its worker. Front ends that push Actions to workers only send
+	// an Action that was served again when sending it failed.
+	Served bool `json:"served,omitempty"`
+}
+
+// ActionAttempt is the result of a single execution of an Action.
+type ActionAttempt struct {
+	Attempt        int64         `json:"attempt"`
+	State          WorkflowState `json:"state,omitempty"`
+	ExecutionStart *metav1.Time  `json:"executionStart,omitempty"`
+	ExecutionStop  *metav1.Time  `json:"executionStop,omitempty"`
+	Message        string        `json:"message,omitempty"`
 }
 
 // HasCondition checks if the cType condition is present with status cStatus on a bmj.
@@ -46,6 +46,16 @@ func (in *Action) DeepCopyInto(out *Action) {
 			(*out)[key] = val
 		}
 	}
+	if in.OnTimeout != nil {
+		in, out := &in.OnTimeout, &out.OnTimeout
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OnFailure != nil {
+		in, out := &in.OnFailure, &out.OnFailure
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExecutionStart != nil {
 		in, out := &in.ExecutionStart, &out.ExecutionStart
 		*out = (*in).DeepCopy()
@@ -54,6 +64,13 @@ func (in *Action) DeepCopyInto(out *Action) {
 		in, out := &in.ExecutionStop, &out.ExecutionStop
 		*out = (*in).DeepCopy()
 	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]ActionAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Action.
@@ -66,6 +83,29 @@ func (in *Action) DeepCopy() *Action {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionAttempt) DeepCopyInto(out *ActionAttempt) {
+	*out = *in
+	if in.ExecutionStart != nil {
+		in, out := &in.ExecutionStart, &out.ExecutionStart
+		*out = (*in).DeepCopy()
+	}
+	if in.ExecutionStop != nil {
+		in, out := &in.ExecutionStop, &out.ExecutionStop
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionAttempt.
+func (in *ActionAttempt) DeepCopy() *ActionAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AllowNetbootStatus) DeepCopyInto(out *AllowNetbootStatus) {
 	*out = *in
@@ -179,13 +219,35 @@ func (in *Disk) DeepCopy() *Disk {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureState) DeepCopyInto(out *FailureState) {
+	*out = *in
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]Action, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureState.
+func (in *FailureState) DeepCopy() *FailureState {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureState)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Hardware) DeepCopyInto(out *Hardware) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hardware.
@@ -206,6 +268,25 @@ func (in *Hardware) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareCondition) DeepCopyInto(out *HardwareCondition) {
+	*out = *in
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareCondition.
+func (in *HardwareCondition) DeepCopy() *HardwareCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HardwareList) DeepCopyInto(out *HardwareList) {
 	*out = *in
@@ -330,6 +411,22 @@ func (in *HardwareSpec) DeepCopy() *HardwareSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HardwareStatus) DeepCopyInto(out *HardwareStatus) {
 	*out = *in
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = new(Inventory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastSeen != nil {
+		in, out := &in.LastSeen, &out.LastSeen
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]HardwareCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareStatus.
@@ -372,6 +469,33 @@ func (in *IPXE) DeepCopy() *IPXE {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePolicy) DeepCopyInto(out *ImagePolicy) {
+	*out = *in
+	if in.PublicKeys != nil {
+		in, out := &in.PublicKeys, &out.PublicKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Digests != nil {
+		in, out := &in.Digests, &out.Digests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePolicy.
+func (in *ImagePolicy) DeepCopy() *ImagePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Interface) DeepCopyInto(out *Interface) {
 	*out = *in
@@ -397,6 +521,243 @@ func (in *Interface) DeepCopy() *Interface {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Inventory) DeepCopyInto(out *Inventory) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		*out = new(InventoryCPU)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = new(InventoryMemory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BlockDevices != nil {
+		in, out := &in.BlockDevices, &out.BlockDevices
+		*out = make([]InventoryBlockDevice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NICs != nil {
+		in, out := &in.NICs, &out.NICs
+		*out = make([]InventoryNIC, len(*in))
+		copy(*out, *in)
+	}
+	if in.PCIDevices != nil {
+		in, out := &in.PCIDevices, &out.PCIDevices
+		*out = make([]InventoryPCIDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.GPUs != nil {
+		in, out := &in.GPUs, &out.GPUs
+		*out = make([]InventoryPCIDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.Chassis != nil {
+		in, out := &in.Chassis, &out.Chassis
+		*out = new(InventoryChassis)
+		**out = **in
+	}
+	if in.BIOS != nil {
+		in, out := &in.BIOS, &out.BIOS
+		*out = new(InventoryBIOS)
+		**out = **in
+	}
+	if in.Baseboard != nil {
+		in, out := &in.Baseboard, &out.Baseboard
+		*out = new(InventoryBaseboard)
+		**out = **in
+	}
+	if in.Product != nil {
+		in, out := &in.Product, &out.Product
+		*out = new(InventoryProduct)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Inventory.
+func (in *Inventory) DeepCopy() *Inventory {
+	if in == nil {
+		return nil
+	}
+	out := new(Inventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryBIOS) DeepCopyInto(out *InventoryBIOS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryBIOS.
+func (in *InventoryBIOS) DeepCopy() *InventoryBIOS {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryBIOS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryBaseboard) DeepCopyInto(out *InventoryBaseboard) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryBaseboard.
+func (in *InventoryBaseboard) DeepCopy() *InventoryBaseboard {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryBaseboard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryBlockDevice) DeepCopyInto(out *InventoryBlockDevice) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryBlockDevice.
+func (in *InventoryBlockDevice) DeepCopy() *InventoryBlockDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryBlockDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryCPU) DeepCopyInto(out *InventoryCPU) {
+	*out = *in
+	if in.Processors != nil {
+		in, out := &in.Processors, &out.Processors
+		*out = make([]InventoryProcessor, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryCPU.
+func (in *InventoryCPU) DeepCopy() *InventoryCPU {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryCPU)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryChassis) DeepCopyInto(out *InventoryChassis) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryChassis.
+func (in *InventoryChassis) DeepCopy() *InventoryChassis {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryChassis)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryMemory) DeepCopyInto(out *InventoryMemory) {
+	*out = *in
+	if in.Total != nil {
+		in, out := &in.Total, &out.Total
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Usable != nil {
+		in, out := &in.Usable, &out.Usable
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryMemory.
+func (in *InventoryMemory) DeepCopy() *InventoryMemory {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryMemory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryNIC) DeepCopyInto(out *InventoryNIC) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryNIC.
+func (in *InventoryNIC) DeepCopy() *InventoryNIC {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryNIC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryPCIDevice) DeepCopyInto(out *InventoryPCIDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryPCIDevice.
+func (in *InventoryPCIDevice) DeepCopy() *InventoryPCIDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryPCIDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryProcessor) DeepCopyInto(out *InventoryProcessor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryProcessor.
+func (in *InventoryProcessor) DeepCopy() *InventoryProcessor {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryProcessor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryProduct) DeepCopyInto(out *InventoryProduct) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryProduct.
+func (in *InventoryProduct) DeepCopy() *InventoryProduct {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryProduct)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JobStatus) DeepCopyInto(out *JobStatus) {
 	*out = *in
@@ -810,6 +1171,18 @@ func (in *Task) DeepCopyInto(out *Task) {
 			(*out)[key] = val
 		}
 	}
+	if in.OnFailure != nil {
+		in, out := &in.OnFailure, &out.OnFailure
+		*out = make([]Action, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Task.
@@ -1005,6 +1378,11 @@ func (in *WorkflowSpec) DeepCopyInto(out *WorkflowSpec) {
 		}
 	}
 	out.BootOptions = in.BootOptions
+	if in.ImagePolicy != nil {
+		in, out := &in.ImagePolicy, &out.ImagePolicy
+		*out = new(ImagePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkflowSpec.
@@ -1033,6 +1411,11 @@ func (in *WorkflowStatus) DeepCopyInto(out *WorkflowStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Failure != nil {
+		in, out := &in.Failure, &out.Failure
+		*out = new(FailureState)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]WorkflowCondition, len(*in))
@@ -3,10 +3,13 @@ package kube
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -31,6 +34,21 @@ func (b *Backend) ReadAll(ctx context.Context, workerID string) ([]v1alpha1.Work
 	return wfs, nil
 }
 
+// ReadWorkers returns the workers that have a Task in a Workflow that is pending or running, sorted and without duplicates.
+func (b *Backend) ReadWorkers(ctx context.Context) ([]string, error) {
+	stored := &v1alpha1.WorkflowList{}
+	if err := b.cluster.GetClient().List(ctx, stored); err != nil {
+		return nil, err
+	}
+	workers := []string{}
+	for _, wf := range stored.Items {
+		workers = append(workers, WorkflowByNonTerminalStateFunc(&wf)...)
+	}
+	slices.Sort(workers)
+
+	return slices.Compact(workers), nil
+}
+
 func (b *Backend) Read(ctx context.Context, workflowID, namespace string) (*v1alpha1.Workflow, error) {
 	workflowNamespace, workflowName, found := strings.Cut(workflowID, "/")
 	if !found {
@@ -53,3 +71,116 @@ func (b *Backend) Write(ctx context.Context, wf *v1alpha1.Workflow) error {
 
 	return nil
 }
+
+// CancelWorkflow sets the Cancel field of the spec of a Workflow.
+func (b *Backend) CancelWorkflow(ctx context.Context, workflowID, namespace string) error {
+	wf := &v1alpha1.Workflow{ObjectMeta: metav1.ObjectMeta{Name: workflowID, Namespace: namespace}}
+	patch := client.RawPatch(types.MergePatchType, []byte(`{"spec":{"cancel":true}}`))
+	if err := b.cluster.GetClient().Patch(ctx, wf, patch); err != nil {
+		return fmt.Errorf("failed to cancel workflow %s: %w", workflowID, err)
+	}
+
+	return nil
+}
+
+// WatchWorkflows returns a channel that receives a value whenever a Workflow with a Task assigned to workerID
+// is added, updated, or deleted. Notifications are coalesced, a receiver is only guaranteed that at least one
+// change happened since its last receive. The watch is stopped when ctx is canceled.
+func (b *Backend) WatchWorkflows(ctx context.Context, workerID string) (<-chan struct{}, error) {
+	informer, err := b.cluster.GetCache().GetInformer(ctx, &v1alpha1.Workflow{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow informer: %w", err)
+	}
+
+	ch := make(chan struct{}, 1)
+	notify := func(obj interface{}) {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		wf, ok := obj.(*v1alpha1.Workflow)
+		if !ok {
+			return
+		}
+		for _, task := range wf.Status.Tasks {
+			if task.WorkerAddr == workerID {
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+				return
+			}
+		}
+	}
+	reg, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj interface{}) { notify(obj) },
+		DeleteFunc: notify,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add workflow event handler: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = informer.RemoveEventHandler(reg)
+	}()
+
+	return ch, nil
+}
+
+// ReadHardwareByMAC returns the Hardware with an Interface using the MAC address.
+// The returned error satisfies apierrors.IsNotFound when no Hardware uses the MAC address.
+func (b *Backend) ReadHardwareByMAC(ctx context.Context, mac string) (*v1alpha1.Hardware, error) {
+	hardwareList := &v1alpha1.HardwareList{}
+	if err := b.cluster.GetClient().List(ctx, hardwareList, &client.MatchingFields{MACAddrIndex: mac}); err != nil {
+		return nil, fmt.Errorf("failed listing hardware for (%v): %w", mac, err)
+	}
+	if len(hardwareList.Items) == 0 {
+		return nil, hardwareNotFoundError{name: mac, namespace: ternary(b.Namespace == "", "all namespaces", b.Namespace)}
+	}
+	if len(hardwareList.Items) > 1 {
+		return nil, fmt.Errorf("got %d hardware objects for mac %s, expected only 1", len(hardwareList.Items), mac)
+	}
+
+	return &hardwareList.Items[0], nil
+}
+
+// CreateHardware creates a Hardware object.
+func (b *Backend) CreateHardware(ctx context.Context, hw *v1alpha1.Hardware) error {
+	if err := b.cluster.GetClient().Create(ctx, hw); err != nil {
+		return fmt.Errorf("failed to create hardware %s: %w", hw.Name, err)
+	}
+
+	return nil
+}
+
+// CreateWorkflow creates a Workflow object.
+func (b *Backend) CreateWorkflow(ctx context.Context, wf *v1alpha1.Workflow) error {
+	if err := b.cluster.GetClient().Create(ctx, wf); err != nil {
+		return fmt.Errorf("failed to create workflow %s: %w", wf.Name, err)
+	}
+
+	return nil
+}
+
+// WriteHardwareStatus updates the status of a Hardware object.
+func (b *Backend) WriteHardwareStatus(ctx context.Context, hw *v1alpha1.Hardware) error {
+	if err := b.cluster.GetClient().Status().Update(ctx, hw); err != nil {
+		return fmt.Errorf("failed to update hardware status %s: %w", hw.Name, err)
+	}
+
+	return nil
+}
+
+// WriteHardware updates the metadata and spec of a Hardware object.
+func (b *Backend) WriteHardware(ctx context.Context, hw *v1alpha1.Hardware) error {
+	if err := b.cluster.GetClient().Update(ctx, hw); err != nil {
+		return fmt.Errorf("failed to update hardware %s: %w", hw.Name, err)
+	}
+
+	return nil
+}
+
+// RecordHardwareEvent records a Kubernetes event for a Hardware object.
+func (b *Backend) RecordHardwareEvent(hw *v1alpha1.Hardware, eventType, reason, message string) {
+	b.cluster.GetEventRecorderFor("tink-server").Event(hw, eventType, reason, message)
+}
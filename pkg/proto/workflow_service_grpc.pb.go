@@ -21,7 +21,11 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	WorkflowService_GetAction_FullMethodName          = "/proto.WorkflowService/GetAction"
+	WorkflowService_StreamActions_FullMethodName      = "/proto.WorkflowService/StreamActions"
 	WorkflowService_ReportActionStatus_FullMethodName = "/proto.WorkflowService/ReportActionStatus"
+	WorkflowService_StreamActionLogs_FullMethodName   = "/proto.WorkflowService/StreamActionLogs"
+	WorkflowService_WatchActionCancel_FullMethodName  = "/proto.WorkflowService/WatchActionCancel"
+	WorkflowService_CancelWorkflow_FullMethodName     = "/proto.WorkflowService/CancelWorkflow"
 )
 
 // WorkflowServiceClient is the client API for WorkflowService service.
@@ -31,7 +35,13 @@ const (
 // WorkflowService for getting actions and reporting the status of the actions
 type WorkflowServiceClient interface {
 	GetAction(ctx context.Context, in *ActionRequest, opts ...grpc.CallOption) (*ActionResponse, error)
+	StreamActions(ctx context.Context, in *ActionRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ActionResponse], error)
 	ReportActionStatus(ctx context.Context, in *ActionStatusRequest, opts ...grpc.CallOption) (*ActionStatusResponse, error)
+	StreamActionLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ActionLogRequest, ActionLogResponse], error)
+	// WatchActionCancel sends a single response, and ends, once the Workflow of a running action is canceled.
+	WatchActionCancel(ctx context.Context, in *ActionCancelRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ActionCancelResponse], error)
+	// CancelWorkflow is an admin RPC, callers must send the admin token of the server.
+	CancelWorkflow(ctx context.Context, in *CancelWorkflowRequest, opts ...grpc.CallOption) (*CancelWorkflowResponse, error)
 }
 
 type workflowServiceClient struct {
@@ -52,6 +62,25 @@ func (c *workflowServiceClient) GetAction(ctx context.Context, in *ActionRequest
 	return out, nil
 }
 
+func (c *workflowServiceClient) StreamActions(ctx context.Context, in *ActionRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ActionResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WorkflowService_ServiceDesc.Streams[0], WorkflowService_StreamActions_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ActionRequest, ActionResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkflowService_StreamActionsClient = grpc.ServerStreamingClient[ActionResponse]
+
 func (c *workflowServiceClient) ReportActionStatus(ctx context.Context, in *ActionStatusRequest, opts ...grpc.CallOption) (*ActionStatusResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ActionStatusResponse)
@@ -62,6 +91,48 @@ func (c *workflowServiceClient) ReportActionStatus(ctx context.Context, in *Acti
 	return out, nil
 }
 
+func (c *workflowServiceClient) StreamActionLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ActionLogRequest, ActionLogResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WorkflowService_ServiceDesc.Streams[1], WorkflowService_StreamActionLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ActionLogRequest, ActionLogResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkflowService_StreamActionLogsClient = grpc.ClientStreamingClient[ActionLogRequest, ActionLogResponse]
+
+func (c *workflowServiceClient) WatchActionCancel(ctx context.Context, in *ActionCancelRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ActionCancelResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WorkflowService_ServiceDesc.Streams[2], WorkflowService_WatchActionCancel_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ActionCancelRequest, ActionCancelResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkflowService_WatchActionCancelClient = grpc.ServerStreamingClient[ActionCancelResponse]
+
+func (c *workflowServiceClient) CancelWorkflow(ctx context.Context, in *CancelWorkflowRequest, opts ...grpc.CallOption) (*CancelWorkflowResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelWorkflowResponse)
+	err := c.cc.Invoke(ctx, WorkflowService_CancelWorkflow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WorkflowServiceServer is the server API for WorkflowService service.
 // All implementations must embed UnimplementedWorkflowServiceServer
 // for forward compatibility.
@@ -69,7 +140,13 @@ func (c *workflowServiceClient) ReportActionStatus(ctx context.Context, in *Acti
 // WorkflowService for getting actions and reporting the status of the actions
 type WorkflowServiceServer interface {
 	GetAction(context.Context, *ActionRequest) (*ActionResponse, error)
+	StreamActions(*ActionRequest, grpc.ServerStreamingServer[ActionResponse]) error
 	ReportActionStatus(context.Context, *ActionStatusRequest) (*ActionStatusResponse, error)
+	StreamActionLogs(grpc.ClientStreamingServer[ActionLogRequest, ActionLogResponse]) error
+	// WatchActionCancel sends a single response, and ends, once the Workflow of a running action is canceled.
+	WatchActionCancel(*ActionCancelRequest, grpc.ServerStreamingServer[ActionCancelResponse]) error
+	// CancelWorkflow is an admin RPC, callers must send the admin token of the server.
+	CancelWorkflow(context.Context, *CancelWorkflowRequest) (*CancelWorkflowResponse, error)
 	mustEmbedUnimplementedWorkflowServiceServer()
 }
 
@@ -83,9 +160,21 @@ type UnimplementedWorkflowServiceServer struct{}
 func (UnimplementedWorkflowServiceServer) GetAction(context.Context, *ActionRequest) (*ActionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAction not implemented")
 }
+func (UnimplementedWorkflowServiceServer) StreamActions(*ActionRequest, grpc.ServerStreamingServer[ActionResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamActions not implemented")
+}
 func (UnimplementedWorkflowServiceServer) ReportActionStatus(context.Context, *ActionStatusRequest) (*ActionStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReportActionStatus not implemented")
 }
+func (UnimplementedWorkflowServiceServer) StreamActionLogs(grpc.ClientStreamingServer[ActionLogRequest, ActionLogResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamActionLogs not implemented")
+}
+func (UnimplementedWorkflowServiceServer) WatchActionCancel(*ActionCancelRequest, grpc.ServerStreamingServer[ActionCancelResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchActionCancel not implemented")
+}
+func (UnimplementedWorkflowServiceServer) CancelWorkflow(context.Context, *CancelWorkflowRequest) (*CancelWorkflowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelWorkflow not implemented")
+}
 func (UnimplementedWorkflowServiceServer) mustEmbedUnimplementedWorkflowServiceServer() {}
 func (UnimplementedWorkflowServiceServer) testEmbeddedByValue()                         {}
 
@@ -125,6 +214,17 @@ func _WorkflowService_GetAction_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WorkflowService_StreamActions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ActionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WorkflowServiceServer).StreamActions(m, &grpc.GenericServerStream[ActionRequest, ActionResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkflowService_StreamActionsServer = grpc.ServerStreamingServer[ActionResponse]
+
 func _WorkflowService_ReportActionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ActionStatusRequest)
 	if err := dec(in); err != nil {
@@ -143,6 +243,42 @@ func _WorkflowService_ReportActionStatus_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WorkflowService_StreamActionLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WorkflowServiceServer).StreamActionLogs(&grpc.GenericServerStream[ActionLogRequest, ActionLogResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkflowService_StreamActionLogsServer = grpc.ClientStreamingServer[ActionLogRequest, ActionLogResponse]
+
+func _WorkflowService_WatchActionCancel_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ActionCancelRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WorkflowServiceServer).WatchActionCancel(m, &grpc.GenericServerStream[ActionCancelRequest, ActionCancelResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkflowService_WatchActionCancelServer = grpc.ServerStreamingServer[ActionCancelResponse]
+
+func _WorkflowService_CancelWorkflow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelWorkflowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkflowServiceServer).CancelWorkflow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkflowService_CancelWorkflow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkflowServiceServer).CancelWorkflow(ctx, req.(*CancelWorkflowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // WorkflowService_ServiceDesc is the grpc.ServiceDesc for WorkflowService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -158,7 +294,27 @@ var WorkflowService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReportActionStatus",
 			Handler:    _WorkflowService_ReportActionStatus_Handler,
 		},
+		{
+			MethodName: "CancelWorkflow",
+			Handler:    _WorkflowService_CancelWorkflow_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamActions",
+			Handler:       _WorkflowService_StreamActions_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamActionLogs",
+			Handler:       _WorkflowService_StreamActionLogs_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchActionCancel",
+			Handler:       _WorkflowService_WatchActionCancel_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "workflow_service.proto",
 }
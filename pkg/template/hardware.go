@@ -0,0 +1,47 @@
+package template
+
+import (
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+)
+
+// HardwareData defines the data exposed for a Hardware instance to a Template.
+type HardwareData struct {
+	Disks      []string
+	Interfaces []v1alpha1.Interface
+	UserData   string
+	Metadata   v1alpha1.HardwareMetadata
+	VendorData string
+}
+
+// Data returns the data a Template is rendered with. It holds the hardware map of a Workflow and the Hardware,
+// under the "Hardware" key.
+func Data(hardwareMap map[string]string, hardware v1alpha1.Hardware) map[string]interface{} {
+	data := make(map[string]interface{})
+	for key, val := range hardwareMap {
+		data[key] = val
+	}
+	data["Hardware"] = ToHardwareData(hardware)
+
+	return data
+}
+
+// ToHardwareData converts a Hardware instance to HardwareData for use in template rendering.
+func ToHardwareData(hardware v1alpha1.Hardware) HardwareData {
+	var contract HardwareData
+	for _, disk := range hardware.Spec.Disks {
+		contract.Disks = append(contract.Disks, disk.Device)
+	}
+	if len(hardware.Spec.Interfaces) > 0 {
+		contract.Interfaces = hardware.Spec.Interfaces
+	}
+	if hardware.Spec.UserData != nil {
+		contract.UserData = *hardware.Spec.UserData
+	}
+	if hardware.Spec.Metadata != nil {
+		contract.Metadata = *hardware.Spec.Metadata
+	}
+	if hardware.Spec.VendorData != nil {
+		contract.VendorData = *hardware.Spec.VendorData
+	}
+	return contract
+}
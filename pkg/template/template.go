@@ -0,0 +1,194 @@
+// Package template renders Templates into Workflows. It is used by the Tink controller to render the Template of a
+// Workflow and by the Tink agent to run Templates locally.
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/distribution/reference"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	errInvalidLength   = "name cannot be empty or have more than 200 characters: %s"
+	errTemplateParsing = "failed to parse template with ID %s"
+)
+
+// parse parses the template yaml content into a Workflow.
+func parse(yamlContent []byte) (*Workflow, error) {
+	var workflow Workflow
+	if err := yaml.Unmarshal(yamlContent, &workflow); err != nil {
+		// The yamlContent is normally quite large but is invaluable in debugging.
+		return &Workflow{}, fmt.Errorf("parsing yaml data: err: %w, content: %s", err, yamlContent)
+	}
+
+	if err := validate(&workflow); err != nil {
+		return &Workflow{}, fmt.Errorf("validating workflow template: %w", err)
+	}
+
+	return &workflow, nil
+}
+
+// Render renders the workflow template with data, see Data, and returns the validated Workflow.
+func Render(templateID, templateData string, data map[string]interface{}) (*Workflow, error) {
+	t := template.New("workflow-template").
+		Option("missingkey=error").
+		Funcs(sprig.FuncMap()).
+		Funcs(templateFuncs)
+
+	_, err := t.Parse(templateData)
+	if err != nil {
+		err = fmt.Errorf("%s: err: %w", fmt.Sprintf(errTemplateParsing, templateID), err)
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		err = fmt.Errorf("%s: err: %w", fmt.Sprintf(errTemplateParsing, templateID), err)
+		return nil, err
+	}
+
+	wf, err := parse(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range wf.Tasks {
+		if task.WorkerAddr == "" {
+			return nil, fmt.Errorf("failed to render template, empty hardware address (%v)", data)
+		}
+	}
+
+	return wf, nil
+}
+
+// validate validates a workflow template against certain requirements.
+func validate(wf *Workflow) error {
+	if !hasValidLength(wf.Name) {
+		return fmt.Errorf(errInvalidLength, wf.Name)
+	}
+
+	if len(wf.Tasks) == 0 {
+		return errors.New("template must have at least one task defined")
+	}
+
+	taskNameMap := make(map[string]struct{})
+	for _, task := range wf.Tasks {
+		if !hasValidLength(task.Name) {
+			return fmt.Errorf(errInvalidLength, task.Name)
+		}
+
+		if _, ok := taskNameMap[task.Name]; ok {
+			return fmt.Errorf("two tasks in a template cannot have same name (%s)", task.Name)
+		}
+
+		taskNameMap[task.Name] = struct{}{}
+		if err := validateActions(task.Actions); err != nil {
+			return err
+		}
+		if err := validateActions(task.OnFailure); err != nil {
+			return fmt.Errorf("invalid on-failure actions for task %s: %w", task.Name, err)
+		}
+	}
+
+	return validateDependencies(wf.Tasks)
+}
+
+// validateDependencies validates that the tasks a task depends on exist and that there are no dependency cycles.
+func validateDependencies(tasks []Task) error {
+	deps := make(map[string][]string, len(tasks))
+	for _, task := range tasks {
+		deps[task.Name] = task.DependsOn
+	}
+	for _, task := range tasks {
+		for _, dep := range task.DependsOn {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("task %s depends on unknown task: %s", task.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(tasks))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("task dependency cycle detected at task: %s", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, task := range tasks {
+		if err := visit(task.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateActions validates a list of actions in a task.
+func validateActions(actions []Action) error {
+	actionNameMap := make(map[string]struct{})
+	for _, action := range actions {
+		if !hasValidLength(action.Name) {
+			return fmt.Errorf(errInvalidLength, action.Name)
+		}
+
+		if err := validateImageName(action.Image); err != nil {
+			return fmt.Errorf("invalid action image (%s): %v", action.Image, err)
+		}
+
+		if action.Retries < 0 {
+			return fmt.Errorf("invalid action retries (%d) for action: %s, must not be negative", action.Retries, action.Name)
+		}
+
+		if action.Backoff < 0 {
+			return fmt.Errorf("invalid action backoff (%d) for action: %s, must not be negative", action.Backoff, action.Name)
+		}
+
+		_, ok := actionNameMap[action.Name]
+		if ok {
+			return fmt.Errorf("two actions in a task cannot have same name: %s", action.Name)
+		}
+		actionNameMap[action.Name] = struct{}{}
+	}
+	return nil
+}
+
+func hasValidLength(name string) bool {
+	return len(name) > 0 && len(name) < 200
+}
+
+// builtinImage matches the images of Actions that are compiled into the agent, for example "builtin://image2disk".
+var builtinImage = regexp.MustCompile(`^builtin://[a-z0-9][a-z0-9-]*$`)
+
+func validateImageName(name string) error {
+	if strings.HasPrefix(name, "builtin://") {
+		if !builtinImage.MatchString(name) {
+			return fmt.Errorf("invalid builtin image: %s", name)
+		}
+		return nil
+	}
+	_, err := reference.ParseNormalizedNamed(name)
+	return err
+}
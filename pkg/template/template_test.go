@@ -1,4 +1,4 @@
-package workflow
+package template
 
 import (
 	"testing"
@@ -128,6 +128,34 @@ func TestValidateTemplate(t *testing.T) {
 			wf:            toWorkflow(withActionInvalidImage()),
 			expectedError: true,
 		},
+		{
+			name: "action image is builtin",
+			wf:   toWorkflow(withActionImage("builtin://image2disk")),
+		},
+		{
+			name:          "action image is an invalid builtin",
+			wf:            toWorkflow(withActionImage("builtin://Image2Disk/x")),
+			expectedError: true,
+		},
+		{
+			name:          "task depends on unknown task",
+			wf:            toWorkflow(withTaskDependsOn("pre-installation", "missing")),
+			expectedError: true,
+		},
+		{
+			name:          "task depends on itself",
+			wf:            toWorkflow(withTaskDependsOn("pre-installation", "pre-installation")),
+			expectedError: true,
+		},
+		{
+			name:          "task dependency cycle",
+			wf:            toWorkflow(withSecondTask("post-installation"), withTaskDependsOn("pre-installation", "post-installation"), withTaskDependsOn("post-installation", "pre-installation")),
+			expectedError: true,
+		},
+		{
+			name: "valid task dependency",
+			wf:   toWorkflow(withSecondTask("post-installation"), withTaskDependsOn("post-installation", "pre-installation")),
+		},
 		{
 			name: "valid task name",
 			wf:   toWorkflow(),
@@ -238,6 +266,10 @@ func withActionInvalidImage() workflowModifier {
 	return func(wf *Workflow) { wf.Tasks[0].Actions[0].Image = "action-image-with-$#@-" }
 }
 
+func withActionImage(image string) workflowModifier {
+	return func(wf *Workflow) { wf.Tasks[0].Actions[0].Image = image }
+}
+
 // invalid template modifiers
 
 func withTemplateInvalidName() workflowModifier {
@@ -261,3 +293,29 @@ func withTemplateEmptyTasks() workflowModifier {
 		wf.Tasks = []Task{}
 	}
 }
+
+func withSecondTask(name string) workflowModifier {
+	return func(wf *Workflow) {
+		wf.Tasks = append(wf.Tasks, Task{
+			Name:       name,
+			WorkerAddr: "08:00:27:00:00:02",
+			Actions: []Action{
+				{
+					Name:    "reboot",
+					Image:   "reboot",
+					Timeout: 90,
+				},
+			},
+		})
+	}
+}
+
+func withTaskDependsOn(task string, dependsOn ...string) workflowModifier {
+	return func(wf *Workflow) {
+		for i := range wf.Tasks {
+			if wf.Tasks[i].Name == task {
+				wf.Tasks[i].DependsOn = append(wf.Tasks[i].DependsOn, dependsOn...)
+			}
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package workflow
+package template
 
 // Workflow represents a workflow to be executed.
 type Workflow struct {
@@ -16,6 +16,8 @@ type Task struct {
 	Actions     []Action          `yaml:"actions"`
 	Volumes     []string          `yaml:"volumes,omitempty"`
 	Environment map[string]string `yaml:"environment,omitempty"`
+	OnFailure   []Action          `yaml:"on-failure,omitempty"`
+	DependsOn   []string          `yaml:"depends-on,omitempty"`
 }
 
 // Action is the basic executional unit for a workflow.
@@ -29,4 +31,8 @@ type Action struct {
 	Volumes     []string          `yaml:"volumes,omitempty"`
 	Environment map[string]string `yaml:"environment,omitempty"`
 	Pid         string            `yaml:"pid,omitempty"`
+	Network     string            `yaml:"network,omitempty"`
+	Retries     int64             `yaml:"retries,omitempty"`
+	Backoff     int64             `yaml:"backoff,omitempty"`
+	Reboot      bool              `yaml:"reboot,omitempty"`
 }
@@ -0,0 +1,32 @@
+// Package workertoken creates and verifies the bootstrap tokens that bind a worker ID to a credential.
+// A token is derived from a secret shared by the services that hand out tokens, like Smee,
+// and the services that verify them, like the Tink server. No per-worker state needs to be stored.
+package workertoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+const (
+	// MetadataKey is the gRPC metadata key in which a worker sends its token.
+	MetadataKey = "x-tinkerbell-worker-token"
+	// KernelParam is the kernel command line parameter in which Smee passes a token to a worker.
+	KernelParam = "tinkerbell_worker_token"
+)
+
+// New returns the token for a worker ID.
+func New(secret []byte, workerID string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(workerID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Valid reports whether token is the token for a worker ID. An empty secret or token is never valid.
+func Valid(secret []byte, workerID, token string) bool {
+	if len(secret) == 0 || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(New(secret, workerID)), []byte(token))
+}
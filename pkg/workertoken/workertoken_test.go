@@ -0,0 +1,27 @@
+package workertoken
+
+import "testing"
+
+func TestValid(t *testing.T) {
+	secret := []byte("secret")
+	tests := map[string]struct {
+		secret   []byte
+		workerID string
+		token    string
+		want     bool
+	}{
+		"valid":           {secret: secret, workerID: "de:ad:be:ef:00:01", token: New(secret, "de:ad:be:ef:00:01"), want: true},
+		"other worker":    {secret: secret, workerID: "de:ad:be:ef:00:02", token: New(secret, "de:ad:be:ef:00:01")},
+		"other secret":    {secret: []byte("other"), workerID: "de:ad:be:ef:00:01", token: New(secret, "de:ad:be:ef:00:01")},
+		"empty token":     {secret: secret, workerID: "de:ad:be:ef:00:01"},
+		"empty secret":    {workerID: "de:ad:be:ef:00:01", token: New(nil, "de:ad:be:ef:00:01")},
+		"malformed token": {secret: secret, workerID: "de:ad:be:ef:00:01", token: "not-a-token"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Valid(tt.secret, tt.workerID, tt.token); got != tt.want {
+				t.Errorf("Valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
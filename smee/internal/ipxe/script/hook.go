@@ -18,7 +18,7 @@ set retry_delay:int32 {{ .RetryDelay }}
 set idx:int32 0
 :retry_kernel
 kernel ${download-url}/${kernel} {{- if ne .VLANID "" }} vlan_id={{ .VLANID }} {{- end }} {{- range .ExtraKernelParams}} {{.}} {{- end}} \
-facility={{ .Facility }} syslog_host={{ .SyslogHost }} grpc_authority={{ .TinkGRPCAuthority }} tinkerbell_tls={{ .TinkerbellTLS }} tinkerbell_insecure_tls={{ .TinkerbellInsecureTLS }} worker_id={{ .WorkerID }} hw_addr={{ .HWAddr }} \
+facility={{ .Facility }} syslog_host={{ .SyslogHost }} grpc_authority={{ .TinkGRPCAuthority }} tinkerbell_tls={{ .TinkerbellTLS }} tinkerbell_insecure_tls={{ .TinkerbellInsecureTLS }} {{- if .TinkerbellTLSCA }} tinkerbell_tls_ca={{ .TinkerbellTLSCA }} {{- end }} {{- if .TinkerbellTLSServerName }} tinkerbell_tls_server_name={{ .TinkerbellTLSServerName }} {{- end }} worker_id={{ .WorkerID }} {{- if .WorkerToken }} tinkerbell_worker_token={{ .WorkerToken }} {{- end }} hw_addr={{ .HWAddr }} \
 modules=loop,squashfs,sd-mod,usb-storage intel_iommu=on iommu=pt initrd=initramfs-${arch} console=tty0 console=ttyS1,115200 && goto download_initrd || iseq ${idx} ${retries} && goto kernel-error || inc idx && echo retry in ${retry_delay} seconds ; sleep ${retry_delay} ; goto retry_kernel
 
 :download_initrd
@@ -49,21 +49,24 @@ exit
 
 // Hook holds the values used to generate the iPXE script that loads the Hook OS.
 type Hook struct {
-	Arch                  string   // example x86_64
-	Console               string   // example ttyS1,115200
-	DownloadURL           string   // example https://location:8080/to/kernel/and/initrd
-	ExtraKernelParams     []string // example tink_worker_image=quay.io/tinkerbell/tink-worker:v0.8.0
-	Facility              string
-	HWAddr                string // example 3c:ec:ef:4c:4f:54
-	SyslogHost            string
-	TinkerbellTLS         bool
-	TinkerbellInsecureTLS bool
-	TinkGRPCAuthority     string // example 192.168.2.111:42113
-	TraceID               string
-	VLANID                string // string number between 1-4095
-	WorkerID              string // example 3c:ec:ef:4c:4f:54 or worker1
-	Retries               int    // number of retries to attempt when fetching kernel and initrd files
-	RetryDelay            int    // number of seconds to wait between retries
-	Kernel                string // name of the kernel file
-	Initrd                string // name of the initrd file
+	Arch                    string   // example x86_64
+	Console                 string   // example ttyS1,115200
+	DownloadURL             string   // example https://location:8080/to/kernel/and/initrd
+	ExtraKernelParams       []string // example tink_worker_image=quay.io/tinkerbell/tink-worker:v0.8.0
+	Facility                string
+	HWAddr                  string // example 3c:ec:ef:4c:4f:54
+	SyslogHost              string
+	TinkerbellTLS           bool
+	TinkerbellInsecureTLS   bool
+	TinkerbellTLSCA         string // base64 encoded DER CA bundle used by the worker to verify the Tink server
+	TinkerbellTLSServerName string // name used by the worker to verify the certificate of the Tink server
+	TinkGRPCAuthority       string // example 192.168.2.111:42113
+	TraceID                 string
+	VLANID                  string // string number between 1-4095
+	WorkerID                string // example 3c:ec:ef:4c:4f:54 or worker1
+	WorkerToken             string // token binding the worker ID to a credential, see the workertoken package
+	Retries                 int    // number of retries to attempt when fetching kernel and initrd files
+	RetryDelay              int    // number of seconds to wait between retries
+	Kernel                  string // name of the kernel file
+	Initrd                  string // name of the initrd file
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tinkerbell/tinkerbell/pkg/data"
+	"github.com/tinkerbell/tinkerbell/pkg/workertoken"
 	"github.com/tinkerbell/tinkerbell/smee/internal/metric"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -37,9 +38,15 @@ type Handler struct {
 	TinkServerTLS         bool
 	TinkServerInsecureTLS bool
 	TinkServerGRPCAddr    string
-	IPXEScriptRetries     int
-	IPXEScriptRetryDelay  int
-	StaticIPXEEnabled     bool
+	// TinkServerTLSCA is a base64 encoded DER CA bundle passed to workers to verify the Tink server.
+	TinkServerTLSCA string
+	// TinkServerTLSServerName is passed to workers to verify the certificate of the Tink server.
+	TinkServerTLSServerName string
+	IPXEScriptRetries       int
+	IPXEScriptRetryDelay    int
+	StaticIPXEEnabled       bool
+	// WorkerTokenSecret, when set, is used to add a worker token for the worker ID to the kernel command line.
+	WorkerTokenSecret []byte
 }
 
 type info struct {
@@ -190,6 +197,9 @@ func (h *Handler) serveStaticIPXEScript(w http.ResponseWriter) {
 		TinkGRPCAuthority: h.TinkServerGRPCAddr,
 		Retries:           h.IPXEScriptRetries,
 		RetryDelay:        h.IPXEScriptRetryDelay,
+
+		TinkerbellTLSCA:         h.TinkServerTLSCA,
+		TinkerbellTLSServerName: h.TinkServerTLSServerName,
 	}
 	script, err := GenerateTemplate(auto, StaticScript)
 	if err != nil {
@@ -294,10 +304,16 @@ func (h *Handler) defaultScript(span trace.Span, hw info) (string, error) {
 		TinkerbellTLS:         h.TinkServerTLS,
 		TinkerbellInsecureTLS: h.TinkServerInsecureTLS,
 		TinkGRPCAuthority:     h.TinkServerGRPCAddr,
-		VLANID:                hw.VLANID,
-		WorkerID:              wID,
-		Retries:               h.IPXEScriptRetries,
-		RetryDelay:            h.IPXEScriptRetryDelay,
+
+		TinkerbellTLSCA:         h.TinkServerTLSCA,
+		TinkerbellTLSServerName: h.TinkServerTLSServerName,
+		VLANID:                  hw.VLANID,
+		WorkerID:                wID,
+		Retries:                 h.IPXEScriptRetries,
+		RetryDelay:              h.IPXEScriptRetryDelay,
+	}
+	if len(h.WorkerTokenSecret) > 0 {
+		auto.WorkerToken = workertoken.New(h.WorkerTokenSecret, wID)
 	}
 	if hw.OSIE.BaseURL != nil && hw.OSIE.BaseURL.String() != "" {
 		auto.DownloadURL = hw.OSIE.BaseURL.String()
@@ -31,7 +31,7 @@ echo tinkerbell_tls={{ .TinkerbellTLS }}
 set idx:int32 0
 :retry_kernel
 kernel ${download-url}/vmlinuz-${arch} \
-syslog_host=${syslog_host} grpc_authority=${grpc_authority} tinkerbell_tls=${tinkerbell_tls} worker_id=${worker_id} hw_addr=${mac} \
+syslog_host=${syslog_host} grpc_authority=${grpc_authority} tinkerbell_tls=${tinkerbell_tls} {{- if .TinkerbellTLSCA }} tinkerbell_tls_ca={{ .TinkerbellTLSCA }} {{- end }} {{- if .TinkerbellTLSServerName }} tinkerbell_tls_server_name={{ .TinkerbellTLSServerName }} {{- end }} worker_id=${worker_id} hw_addr=${mac} \
 console=tty1 console=tty2 console=ttyAMA0,115200 console=ttyAMA1,115200 console=ttyS0,115200 console=ttyS1,115200 {{- range .ExtraKernelParams}} {{.}} {{- end}} \
 intel_iommu=on iommu=pt {{- range .ExtraKernelParams}} {{.}} {{- end}} initrd=initramfs-${arch} && goto download_initrd || iseq ${idx} ${retries} && goto kernel-error || inc idx && echo retry in ${retry_delay} seconds ; sleep ${retry_delay} ; goto retry_kernel
 
@@ -19,6 +19,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/tinkerbell/tinkerbell/pkg/data"
+	"github.com/tinkerbell/tinkerbell/pkg/workertoken"
 	"github.com/tinkerbell/tinkerbell/smee/internal/iso/internal"
 )
 
@@ -50,7 +51,13 @@ type Handler struct {
 	Syslog             string
 	TinkServerTLS      bool
 	TinkServerGRPCAddr string
-	StaticIPAMEnabled  bool
+	// TinkServerTLSCA is a base64 encoded DER CA bundle passed to workers to verify the Tink server.
+	TinkServerTLSCA string
+	// TinkServerTLSServerName is passed to workers to verify the certificate of the Tink server.
+	TinkServerTLSServerName string
+	StaticIPAMEnabled       bool
+	// WorkerTokenSecret, when set, is used to add a worker token for the worker ID to the kernel command line.
+	WorkerTokenSecret []byte
 	// parsedURL derives a url.URL from the SourceISO field.
 	// It needed for validation of SourceISO and easier modification.
 	parsedURL       *url.URL
@@ -236,6 +243,15 @@ func (h *Handler) constructPatch(console, mac string, d *data.DHCP) string {
 	}()
 	hwAddr := fmt.Sprintf("hw_addr=%s", mac)
 	all := []string{strings.Join(h.ExtraKernelParams, " "), console, vlanID, hwAddr, syslogHost, grpcAuthority, tinkerbellTLS, workerID}
+	if h.TinkServerTLSCA != "" {
+		all = append(all, fmt.Sprintf("tinkerbell_tls_ca=%s", h.TinkServerTLSCA))
+	}
+	if h.TinkServerTLSServerName != "" {
+		all = append(all, fmt.Sprintf("tinkerbell_tls_server_name=%s", h.TinkServerTLSServerName))
+	}
+	if len(h.WorkerTokenSecret) > 0 {
+		all = append(all, fmt.Sprintf("%s=%s", workertoken.KernelParam, workertoken.New(h.WorkerTokenSecret, mac)))
+	}
 	if h.StaticIPAMEnabled {
 		all = append(all, parseIPAM(d))
 	}
@@ -2,13 +2,17 @@ package smee
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"net"
 	"net/netip"
 	"net/url"
+	"os"
 	"path"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -98,6 +102,31 @@ type Config struct {
 	TFTP TFTP
 	// TinkServer is the configuration for the Tinkerbell server.
 	TinkServer TinkServer
+	// Proxy is the HTTP(S) proxy configuration passed to workers on the kernel command line.
+	Proxy Proxy
+}
+
+// Proxy is an HTTP(S) proxy configuration passed to workers, as the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY kernel command line parameters, for pulling images and in Actions.
+type Proxy struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    []string
+}
+
+// kernelParams returns the kernel command line parameters for the proxy configuration.
+func (p Proxy) kernelParams() []string {
+	var params []string
+	if p.HTTPProxy != "" {
+		params = append(params, "HTTP_PROXY="+p.HTTPProxy)
+	}
+	if p.HTTPSProxy != "" {
+		params = append(params, "HTTPS_PROXY="+p.HTTPSProxy)
+	}
+	if len(p.NoProxy) > 0 {
+		params = append(params, "NO_PROXY="+strings.Join(p.NoProxy, ","))
+	}
+	return params
 }
 
 type Syslog struct {
@@ -190,6 +219,14 @@ type TinkServer struct {
 	UseTLS      bool
 	InsecureTLS bool
 	AddrPort    string
+	// CAFile is the path to a PEM encoded CA bundle passed to workers, on the kernel command line, to verify the Tink server.
+	// Kernel command lines are limited in size, keep the bundle to the CAs needed to verify the Tink server.
+	CAFile string
+	// ServerName is passed to workers to verify the certificate of the Tink server.
+	ServerName string
+	// WorkerTokenSecret, when set, is used to pass each worker a token for its worker ID on the kernel command line.
+	// The Tink server uses the same secret to verify the token.
+	WorkerTokenSecret string
 }
 
 // NewConfig is a constructor for the Config struct. It will set default values for the Config struct.
@@ -326,6 +363,13 @@ func (c *Config) Start(ctx context.Context, log logr.Logger) error {
 		})
 	}
 
+	tinkServerCA, err := encodeCA(c.TinkServer.CAFile)
+	if err != nil {
+		return err
+	}
+
+	extraKernelParams := append(slices.Clone(c.IPXE.HTTPScriptServer.ExtraKernelArgs), c.Proxy.kernelParams()...)
+
 	handlers := http.HandlerMapping{}
 	// http ipxe binaries
 	if c.IPXE.HTTPBinaryServer.Enabled {
@@ -341,17 +385,20 @@ func (c *Config) Start(ctx context.Context, log logr.Logger) error {
 	// http ipxe script
 	if c.IPXE.HTTPScriptServer.Enabled {
 		jh := script.Handler{
-			Logger:                log,
-			Backend:               c.Backend,
-			OSIEURL:               c.IPXE.HTTPScriptServer.OSIEURL.String(),
-			ExtraKernelParams:     c.IPXE.HTTPScriptServer.ExtraKernelArgs,
-			PublicSyslogFQDN:      c.DHCP.SyslogIP.String(),
-			TinkServerTLS:         c.TinkServer.UseTLS,
-			TinkServerInsecureTLS: c.TinkServer.InsecureTLS,
-			TinkServerGRPCAddr:    c.TinkServer.AddrPort,
-			IPXEScriptRetries:     c.IPXE.HTTPScriptServer.Retries,
-			IPXEScriptRetryDelay:  c.IPXE.HTTPScriptServer.RetryDelay,
-			StaticIPXEEnabled:     (c.DHCP.Mode == DHCPModeAutoProxy),
+			Logger:                  log,
+			Backend:                 c.Backend,
+			OSIEURL:                 c.IPXE.HTTPScriptServer.OSIEURL.String(),
+			ExtraKernelParams:       extraKernelParams,
+			PublicSyslogFQDN:        c.DHCP.SyslogIP.String(),
+			TinkServerTLS:           c.TinkServer.UseTLS,
+			TinkServerInsecureTLS:   c.TinkServer.InsecureTLS,
+			TinkServerGRPCAddr:      c.TinkServer.AddrPort,
+			TinkServerTLSCA:         tinkServerCA,
+			TinkServerTLSServerName: c.TinkServer.ServerName,
+			IPXEScriptRetries:       c.IPXE.HTTPScriptServer.Retries,
+			IPXEScriptRetryDelay:    c.IPXE.HTTPScriptServer.RetryDelay,
+			StaticIPXEEnabled:       (c.DHCP.Mode == DHCPModeAutoProxy),
+			WorkerTokenSecret:       []byte(c.TinkServer.WorkerTokenSecret),
 		}
 
 		// serve ipxe script from the "/" URI.
@@ -362,14 +409,17 @@ func (c *Config) Start(ctx context.Context, log logr.Logger) error {
 		// 1. data validation
 		// 2. start the http server for iso images
 		ih := iso.Handler{
-			Logger:             log,
-			Backend:            c.Backend,
-			SourceISO:          c.ISO.UpstreamURL.String(),
-			ExtraKernelParams:  c.IPXE.HTTPScriptServer.ExtraKernelArgs,
-			Syslog:             c.DHCP.SyslogIP.String(),
-			TinkServerTLS:      c.TinkServer.UseTLS,
-			TinkServerGRPCAddr: c.TinkServer.AddrPort,
-			StaticIPAMEnabled:  c.ISO.StaticIPAMEnabled,
+			Logger:                  log,
+			Backend:                 c.Backend,
+			SourceISO:               c.ISO.UpstreamURL.String(),
+			ExtraKernelParams:       extraKernelParams,
+			Syslog:                  c.DHCP.SyslogIP.String(),
+			TinkServerTLS:           c.TinkServer.UseTLS,
+			TinkServerGRPCAddr:      c.TinkServer.AddrPort,
+			TinkServerTLSCA:         tinkServerCA,
+			TinkServerTLSServerName: c.TinkServer.ServerName,
+			StaticIPAMEnabled:       c.ISO.StaticIPAMEnabled,
+			WorkerTokenSecret:       []byte(c.TinkServer.WorkerTokenSecret),
 			MagicString: func() string {
 				if c.ISO.PatchMagicString == "" {
 					return isoMagicString
@@ -594,3 +644,32 @@ func (c *Config) Transformer(typ reflect.Type) func(dst, src reflect.Value) erro
 func (c *Config) noServicesEnabled() bool {
 	return !c.DHCP.Enabled && !c.TFTP.Enabled && !c.ISO.Enabled && !c.Syslog.Enabled && !c.IPXE.HTTPBinaryServer.Enabled && !c.IPXE.HTTPScriptServer.Enabled
 }
+
+// encodeCA returns the certificates of a PEM encoded CA bundle file as base64 encoded, concatenated, DER.
+// DER is used as it is smaller than PEM, which matters on the kernel command line.
+// An empty string is returned when no file is given.
+func encodeCA(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Tink server CA file: %w", err)
+	}
+	var der []byte
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			der = append(der, block.Bytes...)
+		}
+	}
+	if len(der) == 0 {
+		return "", fmt.Errorf("no PEM encoded certificates found in Tink server CA file %q", file)
+	}
+
+	return base64.StdEncoding.EncodeToString(der), nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/netip"
+	"os"
 	"strings"
 	"time"
 
@@ -12,13 +14,20 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/tinkerbell/tinkerbell/pkg/proto"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/attribute"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/imagecache"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/imageverify"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/proxy"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/registry"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/runtime/builtin"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/runtime/containerd"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/runtime/docker"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/runtime/oci"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/transport/file"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/transport/grpc"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/transport/nats"
 	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 // TransportReader provides a method to read an action.
@@ -29,8 +38,22 @@ type TransportReader interface {
 
 // RuntimeExecutor provides a method to execute an action.
 type RuntimeExecutor interface {
-	// Execute blocks until the action is completed or an error occurs
-	Execute(ctx context.Context, action spec.Action) error
+	// Execute blocks until the action is completed or an error occurs.
+	// The stdout and stderr of the action are written to output.
+	Execute(ctx context.Context, action spec.Action, output io.Writer) error
+}
+
+// ImageVerifier verifies the image of an Action against its image policy.
+type ImageVerifier interface {
+	// Verify returns the image pinned to the digest that was verified. Images that don't satisfy
+	// the policy return an error that wraps imageverify.ErrVerification.
+	Verify(ctx context.Context, image string, policy spec.ImagePolicy) (string, error)
+}
+
+// Rebooter reboots the worker.
+type Rebooter interface {
+	// Reboot doesn't return when the reboot succeeds.
+	Reboot(ctx context.Context) error
 }
 
 // TransportWriter provides a method to write an event.
@@ -39,10 +62,40 @@ type TransportWriter interface {
 	Write(ctx context.Context, event spec.Event) error
 }
 
+// TransportLogWriter provides a method to send the output of an action.
+type TransportLogWriter interface {
+	// LogWriter returns a writer for the output of an action. The writer is closed once the action has completed.
+	LogWriter(ctx context.Context, action spec.Action) (io.WriteCloser, error)
+}
+
+// TransportCancelWatcher provides a method to learn that a running action must be stopped.
+type TransportCancelWatcher interface {
+	// WatchCancel blocks until the action must be stopped, because its Workflow was canceled, and returns the reason.
+	// It returns an error when the context is done.
+	WatchCancel(ctx context.Context, action spec.Action) (string, error)
+}
+
+// errCanceled is the cause of the cancellation of the context of an action that was stopped by the server.
+var errCanceled = errors.New("action canceled")
+
 type Config struct {
 	TransportReader TransportReader
 	RuntimeExecutor RuntimeExecutor
 	TransportWriter TransportWriter
+	// TransportLogWriter is optional. When nil, the output of actions is discarded.
+	TransportLogWriter TransportLogWriter
+	// PrefetchConcurrency is the maximum number of images of upcoming Actions that are pulled in the background
+	// while an Action runs. Prefetching is disabled when it is 0 or the RuntimeExecutor is not an ImagePrefetcher.
+	PrefetchConcurrency int
+	// Rebooter reboots the worker after Actions that reboot it.
+	Rebooter Rebooter
+	// ImageVerifier verifies the images of Actions that have an image policy.
+	// Actions with an image policy fail verification when it is nil.
+	ImageVerifier ImageVerifier
+	// TransportCancelWatcher is optional. When nil, running actions are not stopped when their Workflow is canceled.
+	TransportCancelWatcher TransportCancelWatcher
+
+	prefetcher *prefetcher
 }
 
 func (c *Config) Run(ctx context.Context, log logr.Logger) {
@@ -53,6 +106,9 @@ func (c *Config) Run(ctx context.Context, log logr.Logger) {
 	// 5. send the result event to the output transport
 	// 6. go to step 1
 
+	if ip, ok := c.RuntimeExecutor.(ImagePrefetcher); ok && c.PrefetchConcurrency > 0 {
+		c.prefetcher = newPrefetcher(ip, c.PrefetchConcurrency)
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -70,6 +126,9 @@ func (c *Config) Run(ctx context.Context, log logr.Logger) {
 		}
 
 		log.Info("received action", "action", action)
+		if c.prefetcher != nil {
+			c.prefetcher.prefetch(ctx, log, action.UpcomingImages)
+		}
 		if err := c.TransportWriter.Write(ctx, spec.Event{Action: action, Message: "running action", State: spec.StateRunning}); err != nil {
 			if errors.Is(err, context.Canceled) {
 				return
@@ -79,54 +138,179 @@ func (c *Config) Run(ctx context.Context, log logr.Logger) {
 		}
 		log.Info("reported action status", "action", action, "state", spec.StateRunning)
 
-		state := spec.StateSuccess
-		// TODO(jacobweinstock): Add a retry count that comes from a CLI flag. It should only take precedence if the action has a retry count of 0.
-		retries := ternary(action.Retries == 0, 1, action.Retries)
-
-		responseEvent := spec.Event{}
-		action.ExecutionStart = time.Now().UTC()
-		timeoutCtx, timeoutDone := context.WithTimeout(ctx, time.Duration(action.TimeoutSeconds)*time.Second)
-		for i := 1; i <= retries; i++ {
-			if err := c.RuntimeExecutor.Execute(timeoutCtx, action); err != nil {
-				log.Info("error executing action", "error", err, "maxRetries", retries, "currentTry", i)
-				state = spec.StateFailure
-				if errors.Is(err, context.DeadlineExceeded) {
-					state = spec.StateTimeout
-					timeoutDone()
-					break
-				}
-				if i == retries {
-					timeoutDone()
-					break
-				}
-				continue
+		output := c.logWriter(ctx, log, action)
+		responseEvent := c.runAction(ctx, log, action, output)
+		if err := output.Close(); err != nil {
+			log.Info("error closing action output", "error", err)
+		}
+
+		if err := c.TransportWriter.Write(ctx, responseEvent); err != nil {
+			log.Info("error writing event", "error", err)
+		} else {
+			log.Info("reported action status", "action", responseEvent.Action, "state", responseEvent.State)
+		}
+		// The worker is rebooted even when the success couldn't be reported. The Tink server completes
+		// an Action that reboots the worker when the worker asks for the next Action after the reboot.
+		// The reboot and kexec builtins don't reboot the worker themselves, so that their success is reported first.
+		if responseEvent.State == spec.StateSuccess && (action.Reboot || builtin.Reboots(action.Image)) {
+			c.reboot(ctx, log)
+		}
+	}
+}
+
+// runAction runs an action, within its timeout, and returns the event with its result.
+func (c *Config) runAction(ctx context.Context, log logr.Logger, action spec.Action, output io.Writer) spec.Event {
+	action.ExecutionStart = time.Now().UTC()
+	actionCtx, stopAction := context.WithCancelCause(ctx)
+	c.watchCancel(actionCtx, log, action, stopAction)
+	timeoutCtx, timeoutDone := context.WithTimeout(actionCtx, time.Duration(action.TimeoutSeconds)*time.Second)
+	state, message, attempts := c.execute(timeoutCtx, log, action, output)
+	timeoutDone()
+	stopAction(nil)
+
+	action.ExecutionStop = time.Now().UTC()
+	action.ExecutionDuration = humanDuration(action.ExecutionStop.Sub(action.ExecutionStart), 2)
+
+	return spec.Event{Action: action, Message: message, State: state, Attempts: attempts}
+}
+
+// watchCancel stops the action, by canceling its context with a cause that wraps errCanceled, when the
+// TransportCancelWatcher says it must be stopped. The runtime stops the action gracefully.
+func (c *Config) watchCancel(ctx context.Context, log logr.Logger, action spec.Action, stop context.CancelCauseFunc) {
+	if c.TransportCancelWatcher == nil {
+		return
+	}
+	go func() {
+		reason, err := c.TransportCancelWatcher.WatchCancel(ctx, action)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Info("unable to watch for action cancellation", "error", err)
 			}
-			state = spec.StateSuccess
+			return
+		}
+		log.Info("stopping canceled action", "action", action, "reason", reason)
+		stop(fmt.Errorf("%w: %s", errCanceled, reason))
+	}()
+}
+
+// reboot reboots the worker after an Action that reboots it. It doesn't return when the reboot succeeds.
+func (c *Config) reboot(ctx context.Context, log logr.Logger) {
+	if c.Rebooter == nil {
+		log.Info("unable to reboot after the action, no rebooter is configured")
+		return
+	}
+	log.Info("rebooting after the action")
+	if err := c.Rebooter.Reboot(ctx); err != nil {
+		log.Info("error rebooting after the action", "error", err)
+	}
+}
+
+// execute runs an action until it succeeds, times out, is canceled, or has been retried action.Retries times.
+// When the action has retries, the result of each attempt is returned and each failed attempt is reported
+// with a running state so that it is visible while the action is retried.
+func (c *Config) execute(ctx context.Context, log logr.Logger, action spec.Action, output io.Writer) (spec.State, string, []spec.Attempt) {
+	// TODO(jacobweinstock): Add a retry count that comes from a CLI flag. It should only take precedence if the action has a retry count of 0.
+	maxAttempts := action.Retries + 1
+	var attempts []spec.Attempt
+	if c.prefetcher != nil {
+		c.prefetcher.wait(ctx, action.Image)
+	}
+	for i := 1; ; i++ {
+		attempt := spec.Attempt{Attempt: i, ExecutionStart: time.Now().UTC()}
+		err := c.run(ctx, action, output)
+		attempt.ExecutionStop = time.Now().UTC()
+		if err == nil {
 			log.Info("executed action", "action", action)
-			timeoutDone()
-			break
+			attempt.State = spec.StateSuccess
+			return spec.StateSuccess, "action completed", appendAttempt(attempts, attempt, action.Retries)
 		}
-		timeoutDone()
 
-		action.ExecutionStop = time.Now().UTC()
-		action.ExecutionDuration = humanDuration(action.ExecutionStop.Sub(action.ExecutionStart), 2)
-		responseEvent.Action = action
-		responseEvent.Message = "action completed"
-		responseEvent.State = state
+		log.Info("error executing action", "error", err, "maxAttempts", maxAttempts, "currentAttempt", i)
+		attempt.State = spec.StateFailure
+		attempt.Message = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			attempt.State = spec.StateTimeout
+		}
+		if errors.Is(err, imageverify.ErrVerification) {
+			attempt.State = spec.StateVerificationFailed
+		}
+		if cause := context.Cause(ctx); errors.Is(cause, errCanceled) {
+			attempt.State = spec.StateCanceled
+			attempt.Message = cause.Error()
+		}
+		attempts = appendAttempt(attempts, attempt, action.Retries)
+		if attempt.State == spec.StateTimeout || attempt.State == spec.StateVerificationFailed || attempt.State == spec.StateCanceled || i >= maxAttempts {
+			return attempt.State, attempt.Message, attempts
+		}
 
-		if err := c.TransportWriter.Write(ctx, responseEvent); err != nil {
+		event := spec.Event{
+			Action:   action,
+			Message:  fmt.Sprintf("attempt %d of %d failed, retrying: %v", i, maxAttempts, err),
+			State:    spec.StateRunning,
+			Attempts: attempts,
+		}
+		if err := c.TransportWriter.Write(ctx, event); err != nil {
 			log.Info("error writing event", "error", err)
-			continue
 		}
-		log.Info("reported action status", "action", action, "state", state)
+
+		if action.BackoffSeconds > 0 {
+			select {
+			case <-ctx.Done():
+				if cause := context.Cause(ctx); errors.Is(cause, errCanceled) {
+					return spec.StateCanceled, cause.Error(), attempts
+				}
+				return spec.StateTimeout, fmt.Sprintf("action timed out waiting to retry: %v", ctx.Err()), attempts
+			case <-time.After(time.Duration(action.BackoffSeconds) * time.Second):
+			}
+		}
 	}
 }
 
-func ternary[T any](condition bool, valueIfTrue, valueIfFalse T) T {
-	if condition {
-		return valueIfTrue
+// run verifies the image of an action, when it has an image policy, and then runs the action with the verified image.
+// Images that don't satisfy the policy are not run and the returned error wraps imageverify.ErrVerification.
+func (c *Config) run(ctx context.Context, action spec.Action, output io.Writer) error {
+	if action.ImagePolicy != nil && !builtin.IsBuiltin(action.Image) {
+		if c.ImageVerifier == nil {
+			return fmt.Errorf("%w: the action has an image policy but the agent has no image verifier", imageverify.ErrVerification)
+		}
+		pinned, err := c.ImageVerifier.Verify(ctx, action.Image, *action.ImagePolicy)
+		if err != nil {
+			return err
+		}
+		action.Image = pinned
 	}
-	return valueIfFalse
+
+	return c.RuntimeExecutor.Execute(ctx, action, output)
+}
+
+// appendAttempt only records attempts for actions that can be retried.
+func appendAttempt(attempts []spec.Attempt, attempt spec.Attempt, retries int) []spec.Attempt {
+	if retries <= 0 {
+		return attempts
+	}
+	return append(attempts, attempt)
+}
+
+// logWriter returns the writer for the output of an action.
+// When no TransportLogWriter is configured or it errors, the output is discarded.
+func (c *Config) logWriter(ctx context.Context, log logr.Logger, action spec.Action) io.WriteCloser {
+	if c.TransportLogWriter == nil {
+		return nopWriteCloser{io.Discard}
+	}
+	w, err := c.TransportLogWriter.LogWriter(ctx, action)
+	if err != nil {
+		log.Info("unable to send action output, it will be discarded", "error", err)
+		return nopWriteCloser{io.Discard}
+	}
+	return w
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
 }
 
 const (
@@ -136,6 +320,7 @@ const (
 
 	DockerRuntimeType     RuntimeType = "docker"
 	ContainerdRuntimeType RuntimeType = "containerd"
+	OCIRuntimeType        RuntimeType = "oci"
 )
 
 type TransportType string
@@ -147,9 +332,14 @@ type Options struct {
 	Runtime                   Runtime
 	Registry                  Registry
 	Proxy                     Proxy
+	ImageCache                ImageCache
 	TransportSelected         TransportType
 	RuntimeSelected           RuntimeType
 	AttributeDetectionEnabled bool
+	// PrefetchConcurrency is the maximum number of images of upcoming Actions pulled in the background, 0 disables prefetching.
+	PrefetchConcurrency int
+	// StopTimeout is how long a canceled, or timed out, Action is given to exit gracefully before it is killed.
+	StopTimeout time.Duration
 }
 
 type Transport struct {
@@ -161,34 +351,71 @@ type Transport struct {
 type Runtime struct {
 	Docker     DockerRuntime
 	Containerd ContainerdRuntime
+	OCI        OCIRuntime
 }
 
 type Registry struct {
 	Name string
 	User string
 	Pass string
+	// Auths are credentials for registries in the form "registry=user:password".
+	Auths []string
+	// Mirrors are registries pulled from before the registry they mirror, in the form "registry=[http://]mirror".
+	// For example "docker.io=mirror.example.com:5000".
+	Mirrors []string
 }
 
+// Proxy is the HTTP(S) proxy used to pull images and that is added to the environment of Actions.
+// The Docker runtime pulls images through it with the agent and loads them into the Docker daemon.
 type Proxy struct {
-	HTTPProxy  []string
-	HTTPSProxy []string
+	HTTPProxy  string
+	HTTPSProxy string
 	NoProxy    []string
 }
 
+// ImageCache is a device that persists pulled images across boots of the OSIE.
+type ImageCache struct {
+	// Device is a device path or a file system label in the form "LABEL=<label>". The image cache is disabled when it is empty.
+	Device string
+	// MountPoint is where Device is mounted.
+	MountPoint string
+	// MaxSize is the maximum size of the image cache as a quantity, for example "20Gi". There is no limit when it is "0".
+	MaxSize string
+}
+
 type GRPCTransport struct {
 	ServerAddrPort netip.AddrPort
 	TLSEnabled     bool
 	TLSInsecure    bool
-	RetryInterval  time.Duration
+	// TLSCAFile is the path to a PEM encoded CA bundle used to verify the Tink server.
+	TLSCAFile string
+	// TLSCA is a base64 encoded, PEM or DER, CA bundle used to verify the Tink server.
+	TLSCA string
+	// TLSCertFile and TLSKeyFile are the client certificate and key presented to the Tink server.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSServerName overrides the name used to verify the certificate of the Tink server.
+	TLSServerName string
+	RetryInterval time.Duration
+	// StreamActions enables receiving Actions pushed by the Tink server instead of polling for them.
+	StreamActions bool
+	// WorkerToken authenticates the worker ID to the Tink server.
+	WorkerToken string
 }
 type FileTransport struct {
 	WorkflowPath string
+	// ResultsPath is the file the results of the Actions are written to.
+	ResultsPath string
 }
 type NATSTransport struct {
 	ServerAddrPort netip.AddrPort
 	StreamName     string
 	EventsSubject  string
 	ActionsSubject string
+	// JetStream enables reading Actions from a durable JetStream consumer of the worker and publishing JSON events.
+	JetStream bool
+	// AckWait is how long JetStream waits for the results of the Actions of a message before delivering it again.
+	AckWait time.Duration
 }
 
 type DockerRuntime struct {
@@ -197,6 +424,20 @@ type DockerRuntime struct {
 type ContainerdRuntime struct {
 	Namespace  string
 	SocketPath string
+	// VolumesDir is the directory under which named volumes are created.
+	VolumesDir string
+}
+
+// OCIRuntime runs Actions without a container daemon.
+type OCIRuntime struct {
+	// Dir holds the content store of pulled images and the bundles of Actions.
+	Dir string
+	// Mode is how Actions are run, one of "runc" or "chroot".
+	Mode string
+	// RuncPath is the path to the runc binary used in the "runc" mode.
+	RuncPath string
+	// VolumesDir is the directory under which named volumes are created.
+	VolumesDir string
 }
 
 func (o *Options) ConfigureAndRun(ctx context.Context, log logr.Logger, id string) error {
@@ -209,12 +450,14 @@ func (o *Options) ConfigureAndRun(ctx context.Context, log logr.Logger, id strin
 	ctx = ectx
 	var tr TransportReader
 	var tw TransportWriter
+	var tlw TransportLogWriter
+	var tcw TransportCancelWatcher
 	switch o.TransportSelected {
 	case FileTransportType:
 		readWriter := &file.Config{
-			Log:     log,
-			Actions: make(chan spec.Action),
-			FileLoc: "./example/file_template.yaml",
+			Log:          log,
+			WorkflowPath: o.Transport.File.WorkflowPath,
+			ResultsPath:  o.Transport.File.ResultsPath,
 		}
 		eg.Go(func() error {
 			return readWriter.Start(ctx)
@@ -230,6 +473,9 @@ func (o *Options) ConfigureAndRun(ctx context.Context, log logr.Logger, id strin
 			Log:            log,
 			AgentID:        id,
 			Actions:        make(chan spec.Action),
+			JetStream:      o.Transport.NATS.JetStream,
+			AckWait:        o.Transport.NATS.AckWait,
+			BootID:         bootID(),
 		}
 		log.Info("starting NATS transport", "server", o.Transport.NATS.ServerAddrPort)
 		eg.Go(func() error {
@@ -237,8 +483,18 @@ func (o *Options) ConfigureAndRun(ctx context.Context, log logr.Logger, id strin
 		})
 		tr = readWriter
 		tw = readWriter
+		tcw = readWriter
 	default:
-		conn, err := grpc.NewClientConn(o.Transport.GRPC.ServerAddrPort.String(), o.Transport.GRPC.TLSEnabled, o.Transport.GRPC.TLSInsecure)
+		t := grpc.TLS{
+			Enabled:    o.Transport.GRPC.TLSEnabled,
+			Insecure:   o.Transport.GRPC.TLSInsecure,
+			CAFile:     o.Transport.GRPC.TLSCAFile,
+			CA:         o.Transport.GRPC.TLSCA,
+			CertFile:   o.Transport.GRPC.TLSCertFile,
+			KeyFile:    o.Transport.GRPC.TLSKeyFile,
+			ServerName: o.Transport.GRPC.TLSServerName,
+		}
+		conn, err := grpc.NewClientConn(o.Transport.GRPC.ServerAddrPort.String(), t, o.Transport.GRPC.WorkerToken)
 		if err != nil {
 			return fmt.Errorf("unable to create gRPC client: %w", err)
 		}
@@ -248,6 +504,8 @@ func (o *Options) ConfigureAndRun(ctx context.Context, log logr.Logger, id strin
 			WorkerID:         id,
 			RetryInterval:    time.Second * 5,
 			Actions:          make(chan spec.Action),
+			StreamActions:    o.Transport.GRPC.StreamActions,
+			BootID:           bootID(),
 		}
 		if o.AttributeDetectionEnabled {
 			readWriter.Attributes = grpc.ToProto(attribute.DiscoverAll())
@@ -255,24 +513,106 @@ func (o *Options) ConfigureAndRun(ctx context.Context, log logr.Logger, id strin
 		log.Info("starting gRPC transport", "server", o.Transport.GRPC.ServerAddrPort)
 		tr = readWriter
 		tw = readWriter
+		tlw = readWriter
+		tcw = readWriter
+	}
+
+	re, verifier, err := o.configureRuntime(log)
+	if err != nil {
+		return err
+	}
+
+	a := &Config{
+		TransportReader:        tr,
+		RuntimeExecutor:        re,
+		TransportWriter:        tw,
+		TransportLogWriter:     tlw,
+		PrefetchConcurrency:    o.PrefetchConcurrency,
+		ImageVerifier:          verifier,
+		Rebooter:               re,
+		TransportCancelWatcher: tcw,
+	}
+
+	eg.Go(func() error {
+		a.Run(ctx, log)
+		return nil
+	})
+
+	if err := eg.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
+
+// bootID returns the boot ID of the worker, it changes every time the worker boots. It is empty when it can't be read.
+func bootID() string {
+	b, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
+
+// configureRuntime returns the runtime that runs Actions, and the verifier of their images, from the runtime,
+// registry, proxy and image cache options. opts configure the builtin Actions.
+func (o *Options) configureRuntime(log logr.Logger, opts ...builtin.Opt) (*builtin.Config, *imageverify.Config, error) {
+	auths := o.Registry.Auths
+	if o.Registry.Name != "" && o.Registry.User != "" {
+		auths = append(auths, fmt.Sprintf("%s=%s:%s", o.Registry.Name, o.Registry.User, o.Registry.Pass))
+	}
+	reg, err := registry.Parse(auths, o.Registry.Mirrors)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid registry configuration: %w", err)
+	}
+
+	px := proxy.Config{HTTPProxy: o.Proxy.HTTPProxy, HTTPSProxy: o.Proxy.HTTPSProxy, NoProxy: o.Proxy.NoProxy}
+
+	cache, err := o.ImageCache.configure(log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to configure the image cache: %w", err)
 	}
 
 	var re RuntimeExecutor
 	switch o.RuntimeSelected {
 	case ContainerdRuntimeType:
-		opts := []containerd.Opt{}
+		opts := []containerd.Opt{containerd.WithRegistry(reg), containerd.WithProxy(px), containerd.WithCache(cache), containerd.WithStopTimeout(o.StopTimeout)}
 		if o.Runtime.Containerd.Namespace != "" {
 			opts = append(opts, containerd.WithNamespace(o.Runtime.Containerd.Namespace))
 		}
 		if o.Runtime.Containerd.SocketPath != "" {
 			opts = append(opts, containerd.WithSocketPath(o.Runtime.Containerd.SocketPath))
 		}
+		if o.Runtime.Containerd.VolumesDir != "" {
+			opts = append(opts, containerd.WithVolumesDir(o.Runtime.Containerd.VolumesDir))
+		}
 		cd, err := containerd.NewConfig(log, opts...)
 		if err != nil {
-			return fmt.Errorf("unable to create Containerd config: %w", err)
+			return nil, nil, fmt.Errorf("unable to create Containerd config: %w", err)
 		}
 		re = cd
 		log.Info("using Containerd runtime")
+	case OCIRuntimeType:
+		opts := []oci.Opt{oci.WithRegistry(reg), oci.WithProxy(px), oci.WithCache(cache), oci.WithStopTimeout(o.StopTimeout)}
+		if o.Runtime.OCI.Dir != "" {
+			opts = append(opts, oci.WithDir(o.Runtime.OCI.Dir))
+		}
+		if o.Runtime.OCI.Mode != "" {
+			opts = append(opts, oci.WithMode(oci.Mode(o.Runtime.OCI.Mode)))
+		}
+		if o.Runtime.OCI.RuncPath != "" {
+			opts = append(opts, oci.WithRuncPath(o.Runtime.OCI.RuncPath))
+		}
+		if o.Runtime.OCI.VolumesDir != "" {
+			opts = append(opts, oci.WithVolumesDir(o.Runtime.OCI.VolumesDir))
+		}
+		oc, err := oci.NewConfig(log, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to create OCI runtime config: %w", err)
+		}
+		re = oc
+		log.Info("using OCI runtime", "mode", oc.Mode)
 	default:
 		opts := []client.Opt{
 			client.FromEnv,
@@ -283,33 +623,50 @@ func (o *Options) ConfigureAndRun(ctx context.Context, log logr.Logger, id strin
 		}
 		dclient, err := client.NewClientWithOpts(opts...)
 		if err != nil {
-			return fmt.Errorf("unable to create Docker client: %w", err)
+			return nil, nil, fmt.Errorf("unable to create Docker client: %w", err)
 		}
-		// TODO(jacobweinstock): handle auth
 		dockerExecutor := &docker.Config{
-			Client: dclient,
-			Log:    log,
+			Client:      dclient,
+			Log:         log,
+			Registry:    reg,
+			Proxy:       px,
+			Cache:       cache,
+			StopTimeout: o.StopTimeout,
 		}
 		re = dockerExecutor
 		log.Info("using Docker runtime")
 	}
+	// Actions with a builtin:// image run in the agent, all other Actions run in the selected runtime.
+	return builtin.NewConfig(log, re, append([]builtin.Opt{builtin.WithProxy(px)}, opts...)...), &imageverify.Config{Log: log, Registry: reg, Proxy: px}, nil
+}
 
-	a := &Config{
-		TransportReader: tr,
-		RuntimeExecutor: re,
-		TransportWriter: tw,
+// configure mounts the image cache device, removes corrupt blobs and returns the cache.
+// A nil cache is returned when the image cache is disabled.
+func (i ImageCache) configure(log logr.Logger) (*imagecache.Cache, error) {
+	if i.Device == "" {
+		return nil, nil
 	}
-
-	eg.Go(func() error {
-		a.Run(ctx, log)
-		return nil
-	})
-
-	if err := eg.Wait(); err != nil && !errors.Is(err, context.Canceled) {
-		return err
+	maxSize := int64(0)
+	if i.MaxSize != "" {
+		q, err := resource.ParseQuantity(i.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max size %q: %w", i.MaxSize, err)
+		}
+		maxSize = q.Value()
+	}
+	if err := imagecache.Mount(i.Device, i.MountPoint); err != nil {
+		return nil, err
 	}
+	cache := &imagecache.Cache{Log: log.WithName("imagecache"), Dir: i.MountPoint, MaxBytes: maxSize}
+	if err := cache.Verify(); err != nil {
+		return nil, err
+	}
+	if err := cache.GC(); err != nil {
+		return nil, err
+	}
+	log.Info("using image cache", "device", i.Device, "mountPoint", i.MountPoint, "maxSize", i.MaxSize)
 
-	return nil
+	return cache, nil
 }
 
 func (t TransportType) String() string {
@@ -336,11 +693,11 @@ func (t *TransportType) Type() string {
 
 func (r *RuntimeType) Set(s string) error {
 	switch strings.ToLower(s) {
-	case DockerRuntimeType.String(), ContainerdRuntimeType.String():
+	case DockerRuntimeType.String(), ContainerdRuntimeType.String(), OCIRuntimeType.String():
 		*r = RuntimeType(s)
 		return nil
 	default:
-		return fmt.Errorf("invalid Runtime type: %q, must be one of [%s, %s, %s]", s, GRPCTransportType, NATSTransportType, FileTransportType)
+		return fmt.Errorf("invalid Runtime type: %q, must be one of [%s, %s, %s]", s, DockerRuntimeType, ContainerdRuntimeType, OCIRuntimeType)
 	}
 }
 
@@ -2,10 +2,15 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/imageverify"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
 )
 
@@ -15,7 +20,7 @@ func (m *mock) Read(_ context.Context) (spec.Action, error) {
 	return spec.Action{}, nil
 }
 
-func (m *mock) Execute(_ context.Context, _ spec.Action) error {
+func (m *mock) Execute(_ context.Context, _ spec.Action, _ io.Writer) error {
 	return nil
 }
 
@@ -30,3 +35,268 @@ func TestRun(_ *testing.T) {
 	<-time.After(1 * time.Second)
 	cancel()
 }
+
+type failingExecutor struct {
+	failures int
+	calls    int
+}
+
+func (f *failingExecutor) Execute(_ context.Context, _ spec.Action, _ io.Writer) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+type recordingWriter struct {
+	events []spec.Event
+}
+
+func (r *recordingWriter) Write(_ context.Context, event spec.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestExecuteRetries(t *testing.T) {
+	tests := map[string]struct {
+		failures     int
+		retries      int
+		wantState    spec.State
+		wantCalls    int
+		wantAttempts int
+		wantEvents   int
+	}{
+		"success without retries": {failures: 0, retries: 0, wantState: spec.StateSuccess, wantCalls: 1},
+		"failure without retries": {failures: 1, retries: 0, wantState: spec.StateFailure, wantCalls: 1},
+		"success after retry":     {failures: 2, retries: 3, wantState: spec.StateSuccess, wantCalls: 3, wantAttempts: 3, wantEvents: 2},
+		"retries exhausted":       {failures: 5, retries: 2, wantState: spec.StateFailure, wantCalls: 3, wantAttempts: 3, wantEvents: 2},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			re := &failingExecutor{failures: tt.failures}
+			tw := &recordingWriter{}
+			c := &Config{RuntimeExecutor: re, TransportWriter: tw}
+
+			state, _, attempts := c.execute(context.Background(), logr.Discard(), spec.Action{Retries: tt.retries}, io.Discard)
+			if state != tt.wantState {
+				t.Errorf("got state %v, want %v", state, tt.wantState)
+			}
+			if re.calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", re.calls, tt.wantCalls)
+			}
+			if len(attempts) != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", len(attempts), tt.wantAttempts)
+			}
+			if len(tw.events) != tt.wantEvents {
+				t.Errorf("got %d events, want %d", len(tw.events), tt.wantEvents)
+			}
+		})
+	}
+}
+
+type fakeVerifier struct {
+	err error
+}
+
+func (f fakeVerifier) Verify(_ context.Context, image string, _ spec.ImagePolicy) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return image + "@sha256:1234", nil
+}
+
+type imageRecorder struct {
+	images []string
+}
+
+func (r *imageRecorder) Execute(_ context.Context, a spec.Action, _ io.Writer) error {
+	r.images = append(r.images, a.Image)
+	return nil
+}
+
+func TestExecuteImageVerification(t *testing.T) {
+	tests := map[string]struct {
+		image      string
+		policy     *spec.ImagePolicy
+		verifier   ImageVerifier
+		wantState  spec.State
+		wantImages []string
+	}{
+		"no policy": {
+			image:      "alpine",
+			verifier:   fakeVerifier{err: imageverify.ErrVerification},
+			wantState:  spec.StateSuccess,
+			wantImages: []string{"alpine"},
+		},
+		"verified": {
+			image:      "alpine",
+			policy:     &spec.ImagePolicy{},
+			verifier:   fakeVerifier{},
+			wantState:  spec.StateSuccess,
+			wantImages: []string{"alpine@sha256:1234"},
+		},
+		"verification failed": {
+			image:     "alpine",
+			policy:    &spec.ImagePolicy{},
+			verifier:  fakeVerifier{err: fmt.Errorf("%w: not signed", imageverify.ErrVerification)},
+			wantState: spec.StateVerificationFailed,
+		},
+		"unable to verify": {
+			image:     "alpine",
+			policy:    &spec.ImagePolicy{},
+			verifier:  fakeVerifier{err: errors.New("registry unreachable")},
+			wantState: spec.StateFailure,
+		},
+		"no verifier": {
+			image:     "alpine",
+			policy:    &spec.ImagePolicy{},
+			wantState: spec.StateVerificationFailed,
+		},
+		"builtin": {
+			image:      "builtin://reboot",
+			policy:     &spec.ImagePolicy{},
+			verifier:   fakeVerifier{err: imageverify.ErrVerification},
+			wantState:  spec.StateSuccess,
+			wantImages: []string{"builtin://reboot"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			re := &imageRecorder{}
+			c := &Config{RuntimeExecutor: re, TransportWriter: &recordingWriter{}, ImageVerifier: tt.verifier}
+
+			action := spec.Action{Image: tt.image, ImagePolicy: tt.policy, Retries: 2}
+			state, _, _ := c.execute(context.Background(), logr.Discard(), action, io.Discard)
+			if state != tt.wantState {
+				t.Errorf("got state %v, want %v", state, tt.wantState)
+			}
+			if diff := cmp.Diff(tt.wantImages, re.images); diff != "" {
+				t.Errorf("unexpected images run (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// onceReader returns its actions, in order, and then blocks until the context is done.
+type onceReader struct {
+	actions []spec.Action
+}
+
+func (o *onceReader) Read(ctx context.Context) (spec.Action, error) {
+	if len(o.actions) == 0 {
+		<-ctx.Done()
+		return spec.Action{}, context.Canceled
+	}
+	a := o.actions[0]
+	o.actions = o.actions[1:]
+	return a, nil
+}
+
+type fakeRebooter struct {
+	// events is the number of events written before the reboot.
+	events int
+	tw     *recordingWriter
+	cancel context.CancelFunc
+}
+
+func (f *fakeRebooter) Reboot(_ context.Context) error {
+	f.events = len(f.tw.events)
+	f.cancel()
+	return nil
+}
+
+func TestRunReboot(t *testing.T) {
+	tests := map[string]struct {
+		reboot     bool
+		image      string
+		failures   int
+		wantReboot bool
+	}{
+		"reboot after success": {reboot: true, wantReboot: true},
+		"no reboot":            {reboot: false},
+		"no reboot on failure": {reboot: true, failures: 1},
+		"reboot builtin":       {image: "builtin://reboot", wantReboot: true},
+		"kexec builtin":        {image: "builtin://kexec", wantReboot: true},
+		"other builtin":        {image: "builtin://writefile"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			tw := &recordingWriter{}
+			rb := &fakeRebooter{tw: tw, cancel: cancel}
+			c := &Config{
+				TransportReader: &onceReader{actions: []spec.Action{{Name: "firmware", Image: tt.image, Reboot: tt.reboot, TimeoutSeconds: 10}}},
+				RuntimeExecutor: &failingExecutor{failures: tt.failures},
+				TransportWriter: tw,
+				Rebooter:        rb,
+			}
+			c.Run(ctx, logr.Discard())
+
+			if rebooted := rb.events > 0; rebooted != tt.wantReboot {
+				t.Fatalf("rebooted = %v, want %v", rebooted, tt.wantReboot)
+			}
+			// The running and success events are written before the reboot.
+			if tt.wantReboot && rb.events != 2 {
+				t.Errorf("got %d events before the reboot, want 2", rb.events)
+			}
+		})
+	}
+}
+
+// blockingExecutor runs actions until their context is done.
+type blockingExecutor struct {
+	calls int
+}
+
+func (b *blockingExecutor) Execute(ctx context.Context, _ spec.Action, _ io.Writer) error {
+	b.calls++
+	<-ctx.Done()
+	return fmt.Errorf("context error: %w", ctx.Err())
+}
+
+// fakeCancelWatcher cancels every action with reason.
+type fakeCancelWatcher struct {
+	reason string
+}
+
+func (f fakeCancelWatcher) WatchCancel(_ context.Context, _ spec.Action) (string, error) {
+	return f.reason, nil
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	tw := &recordingWriter{}
+	re := &blockingExecutor{}
+	rb := &fakeRebooter{tw: tw, cancel: cancel}
+	c := &Config{
+		TransportReader:        &onceReader{actions: []spec.Action{{Name: "stream", Retries: 2, Reboot: true, TimeoutSeconds: 10}}},
+		RuntimeExecutor:        re,
+		TransportWriter:        tw,
+		Rebooter:               rb,
+		TransportCancelWatcher: fakeCancelWatcher{reason: "workflow canceled"},
+	}
+	c.Run(ctx, logr.Discard())
+
+	// A canceled action is not retried and the worker is not rebooted.
+	if re.calls != 1 {
+		t.Errorf("got %d calls, want 1", re.calls)
+	}
+	if rb.events > 0 {
+		t.Error("rebooted after a canceled action")
+	}
+	if len(tw.events) != 2 {
+		t.Fatalf("got %d events, want 2", len(tw.events))
+	}
+	got := tw.events[1]
+	if got.State != spec.StateCanceled || got.Message != "action canceled: workflow canceled" {
+		t.Errorf("got state %v and message %q, want %v and %q", got.State, got.Message, spec.StateCanceled, "action canceled: workflow canceled")
+	}
+	if len(got.Attempts) != 1 || got.Attempts[0].State != spec.StateCanceled {
+		t.Errorf("unexpected attempts %v", got.Attempts)
+	}
+}
@@ -0,0 +1,226 @@
+// Package imagecache is a persistent, size limited, cache of Action images on a dedicated partition or device.
+// It lets repeat provisioning of a machine skip most image pulls even though the OSIE, and the image store of its
+// container runtime, lives in memory.
+//
+// Blobs are stored by digest, in the same layout as a containerd content store, so that the OCI runtime can use the
+// cache as its content store directly, it records the descriptor of each image it pulls under the reference of the image.
+// The Docker and containerd runtimes store an archive of each image they pull as a blob and record the digest of the
+// archive under the reference of the image.
+package imagecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/moby/sys/mountinfo"
+	"golang.org/x/sys/unix"
+)
+
+// fsTypes are tried, in order, when mounting the cache device.
+var fsTypes = []string{"ext4", "xfs", "btrfs", "ext3", "ext2", "vfat"}
+
+// Cache is a directory of blobs stored by their sha256 digest and of references to them.
+type Cache struct {
+	Log logr.Logger
+	// Dir is the root of the cache, usually the mount point of the cache device.
+	Dir string
+	// MaxBytes is the maximum size of the blobs in the cache, the least recently used blobs are removed to stay under it.
+	// There is no limit when it is 0.
+	MaxBytes int64
+}
+
+// Mount mounts device at mountPoint unless something is already mounted there.
+// device is a path, for example "/dev/sdb1", or a file system label in the form "LABEL=<label>".
+func Mount(device, mountPoint string) error {
+	if label, ok := strings.CutPrefix(device, "LABEL="); ok {
+		device = filepath.Join("/dev/disk/by-label", label)
+	}
+	if mounted, err := mountinfo.Mounted(mountPoint); err == nil && mounted {
+		return nil
+	}
+	if err := os.MkdirAll(mountPoint, 0o700); err != nil {
+		return err
+	}
+	var errs error
+	for _, t := range fsTypes {
+		err := unix.Mount(device, mountPoint, t, 0, "")
+		if err == nil {
+			return nil
+		}
+		errs = errors.Join(errs, fmt.Errorf("%s: %w", t, err))
+	}
+
+	return fmt.Errorf("unable to mount %s at %s: %w", device, mountPoint, errs)
+}
+
+func (c *Cache) blobPath(digest string) string {
+	return filepath.Join(c.Dir, "blobs", "sha256", digest)
+}
+
+// refPath is the file that holds the digest of the blob of an image reference.
+func (c *Cache) refPath(ref string) string {
+	sum := sha256.Sum256([]byte(ref))
+	return filepath.Join(c.Dir, "refs", hex.EncodeToString(sum[:]))
+}
+
+// Get returns the blob of the image ref. The blob is verified against its digest before it is returned,
+// a blob that is missing or doesn't match is removed and reported as not found with fs.ErrNotExist.
+func (c *Cache) Get(ref string) (io.ReadCloser, error) {
+	b, err := os.ReadFile(c.refPath(ref))
+	if err != nil {
+		return nil, err
+	}
+	digest := strings.TrimSpace(string(b))
+	p := c.blobPath(digest)
+	if err := verify(p, digest); err != nil {
+		_ = os.Remove(p)
+		_ = os.Remove(c.refPath(ref))
+		return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	}
+	c.Touch(digest)
+
+	return os.Open(p) // #nosec G304 -- the path is made of a digest in the cache directory
+}
+
+// Touch records that the blob digest was used so that it is garbage collected after blobs that were not used since.
+func (c *Cache) Touch(digest string) {
+	now := time.Now()
+	_ = os.Chtimes(c.blobPath(digest), now, now)
+}
+
+// Put stores the blob, written by write, of the image ref and then garbage collects the cache.
+func (c *Cache) Put(ref string, write func(io.Writer) error) error {
+	dir := filepath.Join(c.Dir, "blobs", "sha256")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.refPath(ref)), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	h := sha256.New()
+	if err := write(io.MultiWriter(f, h)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(f.Name(), c.blobPath(digest)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.refPath(ref), []byte(digest), 0o600); err != nil {
+		return err
+	}
+
+	return c.GC()
+}
+
+// Verify removes the blobs whose content doesn't match their digest, for example after an unclean shutdown.
+func (c *Cache) Verify() error {
+	blobs, err := c.blobs()
+	if err != nil {
+		return err
+	}
+	for _, b := range blobs {
+		if err := verify(b.path, filepath.Base(b.path)); err != nil {
+			c.Log.Info("removing corrupt blob from the image cache", "blob", b.path, "error", err)
+			if err := os.Remove(b.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GC removes the least recently used blobs until the cache is no larger than MaxBytes.
+// Removed blobs are pulled again when they are next needed.
+func (c *Cache) GC() error {
+	if c.MaxBytes <= 0 {
+		return nil
+	}
+	blobs, err := c.blobs()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, b := range blobs {
+		total += b.size
+	}
+	slices.SortFunc(blobs, func(a, b blob) int { return a.modTime.Compare(b.modTime) })
+	for _, b := range blobs {
+		if total <= c.MaxBytes {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		c.Log.V(1).Info("removed blob from the image cache", "blob", b.path, "size", b.size)
+		total -= b.size
+	}
+
+	return nil
+}
+
+type blob struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) blobs() ([]blob, error) {
+	entries, err := os.ReadDir(filepath.Join(c.Dir, "blobs", "sha256"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	blobs := make([]blob, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		blobs = append(blobs, blob{path: filepath.Join(c.Dir, "blobs", "sha256", e.Name()), size: fi.Size(), modTime: fi.ModTime()})
+	}
+
+	return blobs, nil
+}
+
+// verify returns an error when the sha256 digest of the file at path is not digest.
+func verify(path, digest string) error {
+	f, err := os.Open(path) // #nosec G304 -- the path is made of a digest in the cache directory
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != digest {
+		return fmt.Errorf("digest mismatch, got %s, want %s", got, digest)
+	}
+
+	return nil
+}
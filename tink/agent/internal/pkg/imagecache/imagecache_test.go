@@ -0,0 +1,112 @@
+package imagecache
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func put(t *testing.T, c *Cache, ref, content string) {
+	t.Helper()
+	err := c.Put(ref, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	c := &Cache{Log: logr.Discard(), Dir: t.TempDir()}
+	if _, err := c.Get("docker.io/library/alpine:latest"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Get() error = %v, want %v", err, fs.ErrNotExist)
+	}
+	put(t, c, "docker.io/library/alpine:latest", "archive")
+
+	rc, err := c.Get("docker.io/library/alpine:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(rc)
+	if string(b) != "archive" {
+		t.Errorf("Get() = %q, want %q", b, "archive")
+	}
+}
+
+func TestGetCorrupt(t *testing.T) {
+	c := &Cache{Log: logr.Discard(), Dir: t.TempDir()}
+	put(t, c, "alpine", "archive")
+	blobs, _ := c.blobs()
+	if err := os.WriteFile(blobs[0].path, []byte("corrupt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get("alpine"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Get() error = %v, want %v", err, fs.ErrNotExist)
+	}
+	if blobs, _ := c.blobs(); len(blobs) != 0 {
+		t.Errorf("corrupt blob was not removed")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	c := &Cache{Log: logr.Discard(), Dir: t.TempDir()}
+	put(t, c, "good", "good")
+	put(t, c, "bad", "bad")
+	bad := filepath.Join(c.Dir, "blobs", "sha256", strings.Repeat("0", 64))
+	if err := os.WriteFile(bad, []byte("bad"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	blobs, _ := c.blobs()
+	if len(blobs) != 2 {
+		t.Errorf("got %d blobs after Verify(), want 2", len(blobs))
+	}
+	if _, err := os.Stat(bad); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("blob with mismatched digest was not removed")
+	}
+}
+
+func TestGC(t *testing.T) {
+	c := &Cache{Log: logr.Discard(), Dir: t.TempDir()}
+	put(t, c, "old", strings.Repeat("o", 10))
+	put(t, c, "used", strings.Repeat("u", 10))
+	// Make the blobs look older and then use one of them so that the other is the least recently used.
+	blobs, _ := c.blobs()
+	for _, b := range blobs {
+		past := time.Now().Add(-time.Hour)
+		_ = os.Chtimes(b.path, past, past)
+	}
+	rc, err := c.Get("used")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	c.MaxBytes = 15
+	put(t, c, "new", strings.Repeat("n", 5))
+
+	if _, err := c.Get("old"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("least recently used image was not removed: %v", err)
+	}
+	for _, ref := range []string{"used", "new"} {
+		rc, err := c.Get(ref)
+		if err != nil {
+			t.Errorf("image %q was removed: %v", ref, err)
+			continue
+		}
+		rc.Close()
+	}
+}
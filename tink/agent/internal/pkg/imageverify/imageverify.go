@@ -0,0 +1,232 @@
+// Package imageverify verifies the image of an Action against the image policy of its Workflow before the Action runs.
+// Images are pinned by a manifest digest in the policy or signed with cosign. Signatures are read from the
+// "sha256-<hex>.sig" tag in the repository of the image, where cosign stores them.
+package imageverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/distribution/reference"
+	"github.com/go-logr/logr"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/proxy"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/registry"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+// ErrVerification is wrapped by the errors of images that don't satisfy their image policy.
+// Other errors, for example when the registry can't be reached, mean that the image could not be verified yet.
+var ErrVerification = errors.New("image verification failed")
+
+const (
+	// signatureAnnotation holds the base64 encoded signature of the payload in a layer of a cosign signature manifest.
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	// maxBlobSize limits the size of the signature manifests and payloads that are read.
+	maxBlobSize = 4 << 20
+)
+
+type Config struct {
+	Log logr.Logger
+	// Registry holds the credentials and mirrors used to resolve images and their signatures.
+	Registry registry.Config
+	// Proxy is used to reach registries.
+	Proxy proxy.Config
+
+	// client overrides the HTTP client of the proxy configuration.
+	client *http.Client
+}
+
+// payload is the part of a cosign signature payload that is verified.
+type payload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// Verify verifies image against policy and returns the image pinned to the digest that was verified.
+// The pinned image must be run instead of image so that the image can't change after it was verified.
+func (c *Config) Verify(ctx context.Context, image string, policy spec.ImagePolicy) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid image reference %q: %w", ErrVerification, image, err)
+	}
+	named = reference.TagNameOnly(named)
+
+	client := c.client
+	if client == nil {
+		client = c.Proxy.HTTPClient()
+	}
+	resolver := c.Registry.Resolver(client)
+	_, desc, err := resolver.Resolve(ctx, named.String())
+	if err != nil {
+		return "", fmt.Errorf("error resolving %q: %w", named, err)
+	}
+	pinned, err := reference.WithDigest(reference.TrimNamed(named), desc.Digest)
+	if err != nil {
+		return "", err
+	}
+
+	if want, ok := pinnedDigest(policy.Digests, named); ok {
+		if desc.Digest.String() != want {
+			return "", fmt.Errorf("%w: %s resolved to %s, the policy pins it to %s", ErrVerification, named, desc.Digest, want)
+		}
+		c.Log.Info("image verified", "image", image, "digest", desc.Digest, "pinned", true)
+		return pinned.String(), nil
+	}
+	if len(policy.PublicKeys) == 0 {
+		return "", fmt.Errorf("%w: %s is not pinned by the policy and the policy has no public keys", ErrVerification, named)
+	}
+	keys, err := parseKeys(policy.PublicKeys)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrVerification, err)
+	}
+	if err := verifySignature(ctx, resolver, pinned, keys); err != nil {
+		return "", err
+	}
+	c.Log.Info("image verified", "image", image, "digest", desc.Digest, "signed", true)
+
+	return pinned.String(), nil
+}
+
+// pinnedDigest returns the digest that image is pinned to by digests.
+func pinnedDigest(digests map[string]string, image reference.Named) (string, bool) {
+	for ref, d := range digests {
+		n, err := reference.ParseNormalizedNamed(ref)
+		if err != nil {
+			continue
+		}
+		if reference.TagNameOnly(n).String() == image.String() {
+			return d, true
+		}
+	}
+
+	return "", false
+}
+
+// verifySignature returns nil when the image has a cosign signature of its digest made with one of keys.
+func verifySignature(ctx context.Context, resolver remotes.Resolver, image reference.Canonical, keys []crypto.PublicKey) error {
+	d := image.Digest()
+	sigRef := fmt.Sprintf("%s:%s-%s.sig", image.Name(), d.Algorithm(), d.Encoded())
+	name, desc, err := resolver.Resolve(ctx, sigRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return fmt.Errorf("%w: %s is not signed", ErrVerification, image)
+		}
+		return fmt.Errorf("error resolving signature %q: %w", sigRef, err)
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return err
+	}
+	b, err := fetch(ctx, fetcher, desc)
+	if err != nil {
+		return fmt.Errorf("error fetching signature %q: %w", sigRef, err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return fmt.Errorf("%w: invalid signature manifest %q: %w", ErrVerification, sigRef, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		sig, err := base64.StdEncoding.DecodeString(layer.Annotations[signatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+		b, err := fetch(ctx, fetcher, layer)
+		if err != nil {
+			return fmt.Errorf("error fetching signature payload %s: %w", layer.Digest, err)
+		}
+		if !verifyPayload(keys, b, sig) {
+			continue
+		}
+		var p payload
+		if err := json.Unmarshal(b, &p); err != nil {
+			continue
+		}
+		if p.Critical.Image.DockerManifestDigest == d.String() {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s has no signature made with a trusted key", ErrVerification, image)
+}
+
+// fetch reads a blob and checks it against its descriptor.
+func fetch(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	if desc.Size > maxBlobSize {
+		return nil, fmt.Errorf("blob %s is larger than %d bytes", desc.Digest, maxBlobSize)
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, maxBlobSize))
+	if err != nil {
+		return nil, err
+	}
+	if got := digest.FromBytes(b); got != desc.Digest {
+		return nil, fmt.Errorf("blob digest mismatch, got %s, want %s", got, desc.Digest)
+	}
+
+	return b, nil
+}
+
+// verifyPayload returns true when sig is a signature of payload made with one of keys.
+func verifyPayload(keys []crypto.PublicKey, payload, sig []byte) bool {
+	h := sha256.Sum256(payload)
+	for _, k := range keys {
+		switch k := k.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, h[:], sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, payload, sig) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// parseKeys parses PEM encoded public keys.
+func parseKeys(keys []string) ([]crypto.PublicKey, error) {
+	parsed := make([]crypto.PublicKey, 0, len(keys))
+	for i, k := range keys {
+		block, _ := pem.Decode([]byte(k))
+		if block == nil {
+			return nil, fmt.Errorf("public key %d is not PEM encoded", i)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %d: %w", i, err)
+		}
+		parsed = append(parsed, pub)
+	}
+
+	return parsed, nil
+}
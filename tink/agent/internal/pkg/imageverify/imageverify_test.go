@@ -0,0 +1,183 @@
+package imageverify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+// fakeRegistry serves manifests, by tag or digest, and blobs of a single repository.
+type fakeRegistry struct {
+	manifests map[string][]byte
+	blobs     map[digest.Digest][]byte
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v2/" {
+		return
+	}
+	var b []byte
+	if _, ref, ok := strings.Cut(r.URL.Path, "/manifests/"); ok {
+		b, ok = f.manifests[ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+	} else if _, d, ok := strings.Cut(r.URL.Path, "/blobs/"); ok {
+		b, ok = f.blobs[digest.Digest(d)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	w.Header().Set("Docker-Content-Digest", digest.FromBytes(b).String())
+	w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(b)
+	}
+}
+
+func (f *fakeRegistry) addManifest(ref string, m ocispec.Manifest) digest.Digest {
+	b, _ := json.Marshal(m)
+	d := digest.FromBytes(b)
+	f.manifests[ref] = b
+	f.manifests[d.String()] = b
+	return d
+}
+
+// sign adds a cosign signature of imageDigest, made with key, to the registry.
+func (f *fakeRegistry) sign(t *testing.T, key *ecdsa.PrivateKey, imageDigest digest.Digest) {
+	t.Helper()
+	var p payload
+	p.Critical.Image.DockerManifestDigest = imageDigest.String()
+	b, _ := json.Marshal(p)
+	h := sha256.Sum256(b)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := digest.FromBytes(b)
+	f.blobs[d] = b
+	f.addManifest(fmt.Sprintf("sha256-%s.sig", imageDigest.Encoded()), ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Layers: []ocispec.Descriptor{{
+			MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+			Digest:      d,
+			Size:        int64(len(b)),
+			Annotations: map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+		}},
+	})
+}
+
+func publicKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	b, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+}
+
+func TestVerify(t *testing.T) {
+	trusted, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	untrusted, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	reg := &fakeRegistry{manifests: map[string][]byte{}, blobs: map[digest.Digest][]byte{}}
+	signed := reg.addManifest("signed", ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest, Annotations: map[string]string{"image": "signed"}})
+	reg.sign(t, trusted, signed)
+	otherKey := reg.addManifest("other-key", ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest, Annotations: map[string]string{"image": "other-key"}})
+	reg.sign(t, untrusted, otherKey)
+	unsigned := reg.addManifest("unsigned", ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest, Annotations: map[string]string{"image": "unsigned"}})
+
+	srv := httptest.NewTLSServer(reg)
+	defer srv.Close()
+	repo := strings.TrimPrefix(srv.URL, "https://") + "/actions/test"
+	// The client of the test server trusts its certificate.
+	c := &Config{Log: logr.Discard(), client: srv.Client()}
+
+	tests := map[string]struct {
+		image      string
+		policy     spec.ImagePolicy
+		wantPinned string
+		wantErr    error
+	}{
+		"signed": {
+			image:      repo + ":signed",
+			policy:     spec.ImagePolicy{PublicKeys: []string{publicKey(t, trusted)}},
+			wantPinned: repo + "@" + signed.String(),
+		},
+		"signed with another key": {
+			image:   repo + ":other-key",
+			policy:  spec.ImagePolicy{PublicKeys: []string{publicKey(t, trusted)}},
+			wantErr: ErrVerification,
+		},
+		"unsigned": {
+			image:   repo + ":unsigned",
+			policy:  spec.ImagePolicy{PublicKeys: []string{publicKey(t, trusted)}},
+			wantErr: ErrVerification,
+		},
+		"pinned": {
+			image:      repo + ":unsigned",
+			policy:     spec.ImagePolicy{Digests: map[string]string{repo + ":unsigned": unsigned.String()}},
+			wantPinned: repo + "@" + unsigned.String(),
+		},
+		"pinned to another digest": {
+			image:   repo + ":unsigned",
+			policy:  spec.ImagePolicy{PublicKeys: []string{publicKey(t, trusted)}, Digests: map[string]string{repo + ":unsigned": signed.String()}},
+			wantErr: ErrVerification,
+		},
+		"not pinned and no keys": {
+			image:   repo + ":signed",
+			policy:  spec.ImagePolicy{Digests: map[string]string{repo + ":unsigned": unsigned.String()}},
+			wantErr: ErrVerification,
+		},
+		"invalid key": {
+			image:   repo + ":signed",
+			policy:  spec.ImagePolicy{PublicKeys: []string{"not a key"}},
+			wantErr: ErrVerification,
+		},
+		"image not found": {
+			image:   repo + ":missing",
+			policy:  spec.ImagePolicy{PublicKeys: []string{publicKey(t, trusted)}},
+			wantErr: errors.New("not found"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := c.Verify(context.Background(), tt.image, tt.policy)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("Verify() = %v, want error", got)
+				}
+				if errors.Is(tt.wantErr, ErrVerification) != errors.Is(err, ErrVerification) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got != tt.wantPinned {
+				t.Errorf("Verify() = %v, want %v", got, tt.wantPinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package proxy holds the HTTP(S) proxy settings applied to image pulls and Action containers.
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Config is an HTTP(S) proxy configuration.
+type Config struct {
+	// HTTPProxy is the proxy URL for HTTP requests.
+	HTTPProxy string
+	// HTTPSProxy is the proxy URL for HTTPS requests.
+	HTTPSProxy string
+	// NoProxy are the hosts, domains, IPs and CIDRs that are not proxied.
+	NoProxy []string
+}
+
+// Enabled reports whether a proxy is configured.
+func (c Config) Enabled() bool {
+	return c.HTTPProxy != "" || c.HTTPSProxy != ""
+}
+
+// Env returns env, in k=v form, with the proxy environment variables added.
+// Both the upper and lower case variables are added as tools differ in which they read.
+// Variables already set in env are not overridden so an Action can opt out of, or change, the proxy.
+func (c Config) Env(env []string) []string {
+	if !c.Enabled() {
+		return env
+	}
+	set := map[string]bool{}
+	for _, e := range env {
+		k, _, _ := strings.Cut(e, "=")
+		set[strings.ToUpper(k)] = true
+	}
+	vars := []struct{ key, value string }{
+		{"HTTP_PROXY", c.HTTPProxy},
+		{"HTTPS_PROXY", c.HTTPSProxy},
+		{"NO_PROXY", strings.Join(c.NoProxy, ",")},
+	}
+	for _, v := range vars {
+		if v.value == "" || set[v.key] {
+			continue
+		}
+		env = append(env, v.key+"="+v.value, strings.ToLower(v.key)+"="+v.value)
+	}
+
+	return env
+}
+
+// Func returns a function, for use in http.Transport.Proxy, that proxies requests according to the Config.
+func (c Config) Func() func(*http.Request) (*url.URL, error) {
+	pc := &httpproxy.Config{
+		HTTPProxy:  c.HTTPProxy,
+		HTTPSProxy: c.HTTPSProxy,
+		NoProxy:    strings.Join(c.NoProxy, ","),
+	}
+	f := pc.ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return f(r.URL)
+	}
+}
+
+// HTTPClient returns an http.Client that proxies requests according to the Config.
+// http.DefaultClient is returned when no proxy is configured.
+func (c Config) HTTPClient() *http.Client {
+	if !c.Enabled() {
+		return http.DefaultClient
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = c.Func()
+
+	return &http.Client{Transport: t}
+}
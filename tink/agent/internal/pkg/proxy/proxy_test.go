@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEnv(t *testing.T) {
+	tests := map[string]struct {
+		config Config
+		env    []string
+		want   []string
+	}{
+		"no proxy": {
+			env:  []string{"A=1"},
+			want: []string{"A=1"},
+		},
+		"proxy added": {
+			config: Config{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3129", NoProxy: []string{"10.0.0.0/8", ".example.com"}},
+			env:    []string{"A=1"},
+			want: []string{
+				"A=1",
+				"HTTP_PROXY=http://proxy:3128", "http_proxy=http://proxy:3128",
+				"HTTPS_PROXY=http://proxy:3129", "https_proxy=http://proxy:3129",
+				"NO_PROXY=10.0.0.0/8,.example.com", "no_proxy=10.0.0.0/8,.example.com",
+			},
+		},
+		"action env wins": {
+			config: Config{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3128"},
+			env:    []string{"https_proxy="},
+			want:   []string{"https_proxy=", "HTTP_PROXY=http://proxy:3128", "http_proxy=http://proxy:3128"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.config.Env(tt.env)); diff != "" {
+				t.Errorf("Env() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFunc(t *testing.T) {
+	f := Config{HTTPSProxy: "http://proxy:3128", NoProxy: []string{"registry.local"}}.Func()
+	tests := map[string]string{
+		"https://quay.io/v2/":         "http://proxy:3128",
+		"https://registry.local/v2/":  "",
+		"http://insecure.example/v2/": "",
+	}
+	for u, want := range tests {
+		r, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got == nil && want != "") || (got != nil && got.String() != want) {
+			t.Errorf("proxy for %s = %v, want %q", u, got, want)
+		}
+	}
+}
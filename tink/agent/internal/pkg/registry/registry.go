@@ -0,0 +1,152 @@
+// Package registry holds the container image registry credentials and mirrors used by the runtimes to pull Action images.
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// dockerHub is the normalized name of Docker Hub.
+const dockerHub = "docker.io"
+
+// Credential authenticates to a registry.
+type Credential struct {
+	// Registry is the registry host, with an optional port, for example "harbor.example.com".
+	Registry string
+	Username string
+	Password string
+}
+
+// Mirror is a registry that is pulled from before the registry it mirrors.
+type Mirror struct {
+	// Registry is the registry host being mirrored, for example "docker.io".
+	Registry string
+	// Host is the mirror host, with an optional port, for example "mirror.example.com:5000".
+	Host string
+	// PlainHTTP connects to the mirror without TLS.
+	PlainHTTP bool
+}
+
+// Config is the registry configuration used when pulling images.
+type Config struct {
+	Credentials []Credential
+	Mirrors     []Mirror
+}
+
+// Parse returns a Config from credentials in the form "registry=user:password" and mirrors in the form
+// "registry=[http://]mirror". Each entry can hold multiple, comma separated, values so that lists can be
+// passed in a single environment variable or kernel command line parameter, as a result passwords can't contain commas.
+func Parse(credentials, mirrors []string) (Config, error) {
+	c := Config{}
+	for _, v := range split(credentials) {
+		reg, userPass, ok := strings.Cut(v, "=")
+		user, pass, ok2 := strings.Cut(userPass, ":")
+		if !ok || !ok2 || reg == "" || user == "" {
+			return Config{}, fmt.Errorf("invalid registry credential %q, must be in the form registry=user:password", redact(v))
+		}
+		c.Credentials = append(c.Credentials, Credential{Registry: normalizeHost(reg), Username: user, Password: pass})
+	}
+	for _, v := range split(mirrors) {
+		reg, mirror, ok := strings.Cut(v, "=")
+		if !ok || reg == "" || mirror == "" {
+			return Config{}, fmt.Errorf("invalid registry mirror %q, must be in the form registry=mirror", v)
+		}
+		m := Mirror{Registry: normalizeHost(reg)}
+		switch {
+		case strings.HasPrefix(mirror, "http://"):
+			m.PlainHTTP = true
+			m.Host = strings.TrimPrefix(mirror, "http://")
+		default:
+			m.Host = strings.TrimPrefix(mirror, "https://")
+		}
+		m.Host = strings.TrimSuffix(m.Host, "/")
+		c.Mirrors = append(c.Mirrors, m)
+	}
+
+	return c, nil
+}
+
+// Credential returns the credential for a registry host.
+func (c Config) Credential(host string) (Credential, bool) {
+	host = normalizeHost(host)
+	for _, cr := range c.Credentials {
+		if cr.Registry == host {
+			return cr, true
+		}
+	}
+	return Credential{}, false
+}
+
+// MirrorsFor returns the mirrors of a registry host in the order they were configured.
+func (c Config) MirrorsFor(host string) []Mirror {
+	host = normalizeHost(host)
+	var ms []Mirror
+	for _, m := range c.Mirrors {
+		if m.Registry == host {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// PullRefs returns the references to try, in order, when pulling an image.
+// The image rewritten to each of its registry's mirrors comes first, the image itself is always last.
+func (c Config) PullRefs(image string) []string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return []string{image}
+	}
+	named = reference.TagNameOnly(named)
+	// The tag and/or digest of the image.
+	suffix := strings.TrimPrefix(named.String(), named.Name())
+	refs := []string{}
+	for _, m := range c.MirrorsFor(reference.Domain(named)) {
+		refs = append(refs, m.Host+"/"+reference.Path(named)+suffix)
+	}
+
+	return append(refs, image)
+}
+
+// Host returns the registry host of an image.
+func Host(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	return reference.Domain(named)
+}
+
+// normalizeHost returns the host of a registry with Docker Hub aliases normalized to "docker.io".
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://"), "/")
+	if h, _, ok := strings.Cut(host, "/"); ok {
+		host = h
+	}
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHub
+	}
+	return host
+}
+
+func split(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// redact removes the password from a credential so it can be used in an error.
+func redact(credential string) string {
+	if i := strings.LastIndex(credential, ":"); i >= 0 {
+		return credential[:i+1] + "REDACTED"
+	}
+	return credential
+}
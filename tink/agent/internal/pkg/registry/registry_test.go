@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		credentials []string
+		mirrors     []string
+		want        Config
+		wantErr     bool
+	}{
+		"empty": {},
+		"credentials and mirrors": {
+			credentials: []string{"harbor.example.com=robot:pa:ss", "index.docker.io=user:pass,quay.io=q:p"},
+			mirrors:     []string{"docker.io=http://mirror.example.com:5000/", "quay.io=https://quay-mirror.example.com"},
+			want: Config{
+				Credentials: []Credential{
+					{Registry: "harbor.example.com", Username: "robot", Password: "pa:ss"},
+					{Registry: "docker.io", Username: "user", Password: "pass"},
+					{Registry: "quay.io", Username: "q", Password: "p"},
+				},
+				Mirrors: []Mirror{
+					{Registry: "docker.io", Host: "mirror.example.com:5000", PlainHTTP: true},
+					{Registry: "quay.io", Host: "quay-mirror.example.com"},
+				},
+			},
+		},
+		"invalid credential": {
+			credentials: []string{"harbor.example.com=robot"},
+			wantErr:     true,
+		},
+		"invalid mirror": {
+			mirrors: []string{"docker.io"},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse(tt.credentials, tt.mirrors)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Parse() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullRefs(t *testing.T) {
+	c := Config{
+		Mirrors: []Mirror{
+			{Registry: "docker.io", Host: "mirror1.example.com"},
+			{Registry: "docker.io", Host: "mirror2.example.com:5000"},
+			{Registry: "quay.io", Host: "quay-mirror.example.com"},
+		},
+	}
+	tests := map[string]struct {
+		image string
+		want  []string
+	}{
+		"docker hub short name": {
+			image: "alpine",
+			want:  []string{"mirror1.example.com/library/alpine:latest", "mirror2.example.com:5000/library/alpine:latest", "alpine"},
+		},
+		"digest": {
+			image: "quay.io/tinkerbell/actions@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			want: []string{
+				"quay-mirror.example.com/tinkerbell/actions@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+				"quay.io/tinkerbell/actions@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			},
+		},
+		"no mirror": {
+			image: "harbor.example.com/actions/image2disk:v1",
+			want:  []string{"harbor.example.com/actions/image2disk:v1"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, c.PullRefs(tt.image)); diff != "" {
+				t.Errorf("PullRefs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCredential(t *testing.T) {
+	c := Config{Credentials: []Credential{{Registry: "docker.io", Username: "user", Password: "pass"}}}
+	for _, host := range []string{"docker.io", "registry-1.docker.io", "https://index.docker.io/v1/"} {
+		if _, ok := c.Credential(host); !ok {
+			t.Errorf("expected a credential for %q", host)
+		}
+	}
+	if _, ok := c.Credential("quay.io"); ok {
+		t.Error("expected no credential for quay.io")
+	}
+}
@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"net/http"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+)
+
+// Resolver returns a containerd resolver that authenticates with the configured credentials and
+// tries the mirrors of a registry, in order, before the registry itself.
+func (c Config) Resolver(client *http.Client) remotes.Resolver {
+	authorizer := docker.NewDockerAuthorizer(docker.WithAuthClient(client), docker.WithAuthCreds(func(host string) (string, string, error) {
+		if cred, ok := c.Credential(host); ok {
+			return cred.Username, cred.Password, nil
+		}
+		return "", "", nil
+	}))
+	defaults := docker.ConfigureDefaultRegistries(docker.WithAuthorizer(authorizer), docker.WithClient(client))
+	hosts := func(host string) ([]docker.RegistryHost, error) {
+		origin, err := defaults(host)
+		if err != nil {
+			return nil, err
+		}
+		var hs []docker.RegistryHost
+		for _, m := range c.MirrorsFor(host) {
+			scheme := "https"
+			if m.PlainHTTP {
+				scheme = "http"
+			}
+			hs = append(hs, docker.RegistryHost{
+				Client:       client,
+				Authorizer:   authorizer,
+				Host:         m.Host,
+				Scheme:       scheme,
+				Path:         "/v2",
+				Capabilities: docker.HostCapabilityPull | docker.HostCapabilityResolve,
+			})
+		}
+		return append(hs, origin...), nil
+	}
+
+	return docker.NewResolver(docker.ResolverOptions{Hosts: hosts})
+}
@@ -0,0 +1,57 @@
+// Package securepath resolves paths in a directory, like a container root filesystem or a mounted disk,
+// without following symlinks out of it.
+package securepath
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Join returns the path of unsafePath in root, resolving symlinks as if root was the root
+// filesystem so that the returned path can't be outside of root.
+func Join(root, unsafePath string) (string, error) {
+	resolved := "/"
+	parts := strings.Split(unsafePath, "/")
+	links := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, part)
+		fi, err := os.Lstat(filepath.Join(root, next))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				resolved = next
+				continue
+			}
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		links++
+		if links > 255 {
+			return "", fmt.Errorf("too many symlinks in %q", unsafePath)
+		}
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		parts = append(strings.Split(target, "/"), parts...)
+	}
+
+	return filepath.Join(root, resolved), nil
+}
@@ -0,0 +1,50 @@
+package securepath
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "usr", "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"bin":    "usr/bin",
+		"escape": "/../../etc",
+		"up":     "../../../../etc",
+		"loop":   "loop",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		"plain":            {path: "/usr/bin/sh", want: filepath.Join(root, "usr/bin/sh")},
+		"relative symlink": {path: "/bin/sh", want: filepath.Join(root, "usr/bin/sh")},
+		"absolute symlink": {path: "/escape/passwd", want: filepath.Join(root, "etc/passwd")},
+		"dot dot symlink":  {path: "/up/passwd", want: filepath.Join(root, "etc/passwd")},
+		"dot dot in path":  {path: "/../../etc/passwd", want: filepath.Join(root, "etc/passwd")},
+		"does not exist":   {path: "/data/dir", want: filepath.Join(root, "data/dir")},
+		"symlink loop":     {path: "/loop/file", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Join(root, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Join() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Join() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package volume converts Action volumes into OCI mounts for the runtimes that do not use Docker.
+package volume
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+// DefaultDir is the directory under which named volumes are created.
+const DefaultDir = "/var/lib/tinkerbell/volumes"
+
+// volumeName matches the names Docker allows for named volumes.
+var volumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Mounts converts Action volumes into OCI bind mounts.
+// Volumes use the same syntax as the Docker runtime, {SRC-VOLUME-NAME | SRC-HOST-DIR}:TGT-CONTAINER-DIR[:OPTIONS].
+// Named volumes are directories in volumesDir that are created when they do not exist,
+// so that they can be shared between the Actions of a Workflow.
+func Mounts(volumesDir string, volumes []spec.Volume) ([]specs.Mount, error) {
+	ms := make([]specs.Mount, 0, len(volumes))
+	for _, v := range volumes {
+		m, err := mount(volumesDir, v)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+
+	return ms, nil
+}
+
+func mount(volumesDir string, v spec.Volume) (specs.Mount, error) {
+	parts := strings.Split(string(v), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return specs.Mount{}, fmt.Errorf("invalid volume %q: must be of the form {SRC-VOLUME-NAME | SRC-HOST-DIR}:TGT-CONTAINER-DIR[:OPTIONS]", v)
+	}
+	src, dst := parts[0], parts[1]
+	if !filepath.IsAbs(dst) {
+		return specs.Mount{}, fmt.Errorf("invalid volume %q: target %q must be an absolute path", v, dst)
+	}
+
+	if !filepath.IsAbs(src) {
+		if !volumeName.MatchString(src) {
+			return specs.Mount{}, fmt.Errorf("invalid volume %q: %q is not a valid volume name", v, src)
+		}
+		src = filepath.Join(volumesDir, src)
+		if err := os.MkdirAll(src, 0o755); err != nil { // #nosec G301 -- volumes are shared with Action containers
+			return specs.Mount{}, fmt.Errorf("error creating volume %q: %w", parts[0], err)
+		}
+	}
+
+	opts := []string{"rbind", "rw"}
+	if len(parts) == 3 {
+		for _, o := range strings.Split(parts[2], ",") {
+			switch o {
+			case "ro", "rw":
+				opts[1] = o
+			case "shared", "rshared", "slave", "rslave", "private", "rprivate":
+				opts = append(opts, o)
+			case "z", "Z", "":
+				// SELinux relabeling is not supported, ignore it like Docker does when SELinux is disabled.
+			default:
+				return specs.Mount{}, fmt.Errorf("invalid volume %q: unknown option %q", v, o)
+			}
+		}
+	}
+
+	return specs.Mount{
+		Destination: dst,
+		Type:        "bind",
+		Source:      src,
+		Options:     opts,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+func TestMount(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]struct {
+		volume  spec.Volume
+		want    specs.Mount
+		wantDir string
+		wantErr bool
+	}{
+		"bind mount": {
+			volume: "/dev:/dev",
+			want:   specs.Mount{Destination: "/dev", Type: "bind", Source: "/dev", Options: []string{"rbind", "rw"}},
+		},
+		"read only bind mount": {
+			volume: "/etc/data:/data:ro",
+			want:   specs.Mount{Destination: "/data", Type: "bind", Source: "/etc/data", Options: []string{"rbind", "ro"}},
+		},
+		"bind mount with propagation": {
+			volume: "/mnt:/mnt:ro,rshared",
+			want:   specs.Mount{Destination: "/mnt", Type: "bind", Source: "/mnt", Options: []string{"rbind", "ro", "rshared"}},
+		},
+		"named volume": {
+			volume:  "shared_volume:/data",
+			want:    specs.Mount{Destination: "/data", Type: "bind", Source: filepath.Join(dir, "shared_volume"), Options: []string{"rbind", "rw"}},
+			wantDir: filepath.Join(dir, "shared_volume"),
+		},
+		"missing target": {
+			volume:  "/data",
+			wantErr: true,
+		},
+		"relative target": {
+			volume:  "/data:data",
+			wantErr: true,
+		},
+		"invalid volume name": {
+			volume:  "../escape:/data",
+			wantErr: true,
+		},
+		"unknown option": {
+			volume:  "/data:/data:bogus",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := mount(dir, tt.volume)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mount() mismatch (-want +got):\n%s", diff)
+			}
+			if tt.wantDir != "" {
+				if _, err := os.Stat(tt.wantDir); err != nil {
+					t.Errorf("expected volume directory to be created: %v", err)
+				}
+			}
+		})
+	}
+}
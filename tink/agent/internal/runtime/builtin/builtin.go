@@ -0,0 +1,125 @@
+// Package builtin runs Actions that are compiled into the agent instead of being pulled as container images.
+// An Action uses a builtin when its image is of the form "builtin://<name>", for example "builtin://image2disk".
+// Builtins read the same environment variables as the container images they replace
+// and run in the namespaces of the agent, the volumes and namespaces of the Action are not used.
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/proxy"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+// Scheme is the image prefix that selects a builtin Action.
+const Scheme = "builtin://"
+
+// Func is the implementation of a builtin Action.
+// env holds the environment variables of the Action and the output of the Action is written to output.
+type Func func(ctx context.Context, env map[string]string, output io.Writer) error
+
+// Executor runs an Action.
+type Executor interface {
+	Execute(ctx context.Context, action spec.Action, output io.Writer) error
+}
+
+type Config struct {
+	Log logr.Logger
+	// Runtime executes the Actions that are not builtins.
+	Runtime Executor
+	// Proxy is used by builtins that download content.
+	Proxy proxy.Config
+
+	actions map[string]Func
+}
+
+type Opt func(*Config)
+
+func WithProxy(p proxy.Config) Opt {
+	return func(c *Config) {
+		c.Proxy = p
+	}
+}
+
+// WithAction registers, or replaces, the builtin Action name.
+func WithAction(name string, f Func) Opt {
+	return func(c *Config) {
+		c.actions[name] = f
+	}
+}
+
+// NewConfig returns a Config that runs builtin Actions and passes all other Actions to runtime.
+func NewConfig(log logr.Logger, runtime Executor, opts ...Opt) *Config {
+	c := &Config{Log: log, Runtime: runtime, actions: map[string]Func{}}
+	c.actions["image2disk"] = func(ctx context.Context, env map[string]string, output io.Writer) error {
+		return image2disk(ctx, c.Proxy.HTTPClient(), env, output)
+	}
+	c.actions["writefile"] = writeFile
+	c.actions["kexec"] = kexec
+	c.actions["reboot"] = reboot
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// IsBuiltin reports whether image selects a builtin Action.
+func IsBuiltin(image string) bool {
+	return strings.HasPrefix(image, Scheme)
+}
+
+// Prefetch pulls the image of an Action, that is not a builtin, when the runtime supports it.
+func (c *Config) Prefetch(ctx context.Context, image string) error {
+	p, ok := c.Runtime.(interface {
+		Prefetch(ctx context.Context, image string) error
+	})
+	if IsBuiltin(image) || !ok {
+		return nil
+	}
+
+	return p.Prefetch(ctx, image)
+}
+
+// Reboots reports whether image selects a builtin that reboots the worker, reboot or kexec.
+// These builtins only prepare the reboot, the worker must be rebooted with Reboot once their success is reported.
+func Reboots(image string) bool {
+	name, ok := strings.CutPrefix(image, Scheme)
+	return ok && (name == "reboot" || name == "kexec")
+}
+
+// Reboot restarts the machine, into the kernel loaded by the kexec builtin when there is one.
+func (c *Config) Reboot(_ context.Context) error {
+	return restart()
+}
+
+func (c *Config) Execute(ctx context.Context, a spec.Action, output io.Writer) error {
+	name, ok := strings.CutPrefix(a.Image, Scheme)
+	if !ok {
+		return c.Runtime.Execute(ctx, a, output)
+	}
+	f, ok := c.actions[name]
+	if !ok {
+		names := make([]string, 0, len(c.actions))
+		for n := range c.actions {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown builtin action %q, must be one of %v", name, names)
+	}
+	if output == nil {
+		output = io.Discard
+	}
+	env := map[string]string{}
+	for _, e := range a.Env {
+		env[e.Key] = e.Value
+	}
+	c.Log.Info("running builtin action", "builtin", name, "action", a.Name)
+
+	return f(ctx, env, output)
+}
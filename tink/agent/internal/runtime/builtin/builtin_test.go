@@ -0,0 +1,153 @@
+package builtin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/klauspost/compress/zstd"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"github.com/ulikunitz/xz"
+)
+
+type fakeRuntime struct {
+	executed []string
+}
+
+func (f *fakeRuntime) Execute(_ context.Context, a spec.Action, _ io.Writer) error {
+	f.executed = append(f.executed, a.Image)
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	tests := map[string]struct {
+		image        string
+		wantErr      bool
+		wantBuiltin  map[string]string
+		wantExecuted []string
+	}{
+		"runtime": {
+			image:        "quay.io/tinkerbell/actions/image2disk:latest",
+			wantExecuted: []string{"quay.io/tinkerbell/actions/image2disk:latest"},
+		},
+		"builtin": {
+			image:       "builtin://test",
+			wantBuiltin: map[string]string{"KEY": "value"},
+		},
+		"unknown builtin": {
+			image:   "builtin://unknown",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rt := &fakeRuntime{}
+			var gotEnv map[string]string
+			c := NewConfig(logr.Discard(), rt, WithAction("test", func(_ context.Context, env map[string]string, _ io.Writer) error {
+				gotEnv = env
+				return nil
+			}))
+			a := spec.Action{Image: tt.image, Env: []spec.Env{{Key: "KEY", Value: "value"}}}
+			err := c.Execute(context.Background(), a, io.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(tt.wantBuiltin) > 0 && gotEnv["KEY"] != tt.wantBuiltin["KEY"] {
+				t.Errorf("builtin env = %v, want %v", gotEnv, tt.wantBuiltin)
+			}
+			if len(rt.executed) != len(tt.wantExecuted) {
+				t.Errorf("runtime executed %v, want %v", rt.executed, tt.wantExecuted)
+			}
+		})
+	}
+}
+
+func TestImage2Disk(t *testing.T) {
+	content := bytes.Repeat([]byte("tinkerbell"), 1024)
+	compress := map[string]func(t *testing.T) []byte{
+		"gzip": func(t *testing.T) []byte {
+			var b bytes.Buffer
+			w := gzip.NewWriter(&b)
+			_, _ = w.Write(content)
+			_ = w.Close()
+			return b.Bytes()
+		},
+		"xz": func(t *testing.T) []byte {
+			var b bytes.Buffer
+			w, err := xz.NewWriter(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write(content)
+			_ = w.Close()
+			return b.Bytes()
+		},
+		"zstd": func(t *testing.T) []byte {
+			var b bytes.Buffer
+			w, err := zstd.NewWriter(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write(content)
+			_ = w.Close()
+			return b.Bytes()
+		},
+	}
+
+	tests := map[string]struct {
+		body       func(t *testing.T) []byte
+		compressed string
+		status     int
+		wantErr    bool
+	}{
+		"uncompressed": {body: func(*testing.T) []byte { return content }},
+		"gzip":         {body: compress["gzip"], compressed: "true"},
+		"xz":           {body: compress["xz"], compressed: "true"},
+		"zstd":         {body: compress["zstd"], compressed: "true"},
+		"unknown compression": {
+			body:       func(*testing.T) []byte { return content },
+			compressed: "true",
+			wantErr:    true,
+		},
+		"not found": {body: func(*testing.T) []byte { return nil }, status: http.StatusNotFound, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			body := tt.body(t)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+			disk := filepath.Join(t.TempDir(), "disk")
+			if err := os.WriteFile(disk, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			env := map[string]string{"IMG_URL": srv.URL + "/image", "DEST_DISK": disk, "COMPRESSED": tt.compressed}
+			err := image2disk(context.Background(), srv.Client(), env, io.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("image2disk() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := os.ReadFile(disk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("image2disk() wrote %d bytes, want %d", len(got), len(content))
+			}
+		})
+	}
+}
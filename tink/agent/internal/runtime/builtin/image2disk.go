@@ -0,0 +1,131 @@
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"golang.org/x/sys/unix"
+)
+
+// image2disk streams the image at IMG_URL to the block device DEST_DISK.
+// When COMPRESSED is true the image is decompressed, gzip, xz, bzip2 and zstd are detected from the content.
+func image2disk(ctx context.Context, client *http.Client, env map[string]string, output io.Writer) error {
+	url, disk := env["IMG_URL"], env["DEST_DISK"]
+	if url == "" || disk == "" {
+		return errors.New("IMG_URL and DEST_DISK are required")
+	}
+	compressed, _ := strconv.ParseBool(env["COMPRESSED"])
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error downloading image: unexpected status %s", resp.Status)
+	}
+
+	var r io.Reader = resp.Body
+	if compressed {
+		dr, err := decompress(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error decompressing image: %w", err)
+		}
+		defer dr.Close()
+		r = dr
+	}
+
+	f, err := os.OpenFile(disk, os.O_WRONLY, 0) // #nosec G304 -- the destination disk is set in the Template
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", disk, err)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(output, "writing %s to %s\n", url, disk)
+	start := time.Now()
+	n, err := io.Copy(f, &progress{Reader: r, output: output, last: start})
+	if err != nil {
+		return fmt.Errorf("error writing image to %s: %w", disk, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("error syncing %s: %w", disk, err)
+	}
+	// Have the kernel re-read the partition table that was written, this fails for files which is expected.
+	_ = unix.IoctlSetInt(int(f.Fd()), unix.BLKRRPART, 0) // #nosec G115 -- file descriptors fit in an int
+	fmt.Fprintf(output, "wrote %d bytes to %s in %s\n", n, disk, time.Since(start).Round(time.Second))
+
+	return nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
+
+// decompress returns a reader of the decompressed content of r, the compression is detected from its magic number.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(6)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	noop := func() error { return nil }
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{Reader: xr, close: noop}, nil
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return readCloser{Reader: bzip2.NewReader(br), close: noop}, nil
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{Reader: zr, close: func() error { zr.Close(); return nil }}, nil
+	default:
+		return nil, errors.New("unknown compression, must be one of gzip, xz, bzip2 or zstd")
+	}
+}
+
+// progress writes the number of bytes read to output every 10 seconds.
+type progress struct {
+	io.Reader
+	output io.Writer
+	total  int64
+	last   time.Time
+}
+
+func (p *progress) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.total += int64(n)
+	if time.Since(p.last) >= 10*time.Second {
+		p.last = time.Now()
+		fmt.Fprintf(p.output, "%d bytes written\n", p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,78 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/securepath"
+	"golang.org/x/sys/unix"
+)
+
+// kexec loads the kernel KERNEL_PATH, and the optional initrd INITRD_PATH, from the file system, of type FS_TYPE,
+// on BLOCK_DEVICE with the kernel command line CMD_LINE. The worker boots into it when it is rebooted, with
+// Config.Reboot, once the success of the Action is reported.
+func kexec(_ context.Context, env map[string]string, output io.Writer) error {
+	disk, fsType, kernel := env["BLOCK_DEVICE"], env["FS_TYPE"], env["KERNEL_PATH"]
+	if disk == "" || fsType == "" || kernel == "" {
+		return errors.New("BLOCK_DEVICE, FS_TYPE and KERNEL_PATH are required")
+	}
+	err := withMount(disk, fsType, unix.MS_RDONLY, func(root string) error {
+		// Symlinks on the disk are resolved in it, so a file outside of it can't be loaded.
+		kernelPath, err := securepath.Join(root, kernel)
+		if err != nil {
+			return err
+		}
+		k, err := os.Open(kernelPath)
+		if err != nil {
+			return fmt.Errorf("error opening kernel: %w", err)
+		}
+		defer k.Close()
+
+		initrdFd, flags := -1, unix.KEXEC_FILE_NO_INITRAMFS
+		if initrd := env["INITRD_PATH"]; initrd != "" {
+			initrdPath, err := securepath.Join(root, initrd)
+			if err != nil {
+				return err
+			}
+			i, err := os.Open(initrdPath)
+			if err != nil {
+				return fmt.Errorf("error opening initrd: %w", err)
+			}
+			defer i.Close()
+			initrdFd, flags = int(i.Fd()), 0 // #nosec G115 -- file descriptors fit in an int
+		}
+		if err := unix.KexecFileLoad(int(k.Fd()), initrdFd, env["CMD_LINE"], flags); err != nil { // #nosec G115 -- file descriptors fit in an int
+			return fmt.Errorf("error loading kernel: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "loaded %s from %s, booting into it once the action is reported\n", kernel, disk)
+
+	return nil
+}
+
+// reboot does nothing, the worker is rebooted, with Config.Reboot, once the success of the Action is reported.
+func reboot(_ context.Context, _ map[string]string, output io.Writer) error {
+	fmt.Fprintln(output, "rebooting once the action is reported")
+
+	return nil
+}
+
+// restart reboots the machine into the kernel loaded by the kexec builtin, when there is one, or through the firmware.
+func restart() error {
+	cmd := unix.LINUX_REBOOT_CMD_RESTART
+	if b, err := os.ReadFile("/sys/kernel/kexec_loaded"); err == nil && strings.TrimSpace(string(b)) == "1" {
+		cmd = unix.LINUX_REBOOT_CMD_KEXEC
+	}
+	unix.Sync()
+
+	return unix.Reboot(cmd)
+}
@@ -0,0 +1,101 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/securepath"
+	"golang.org/x/sys/unix"
+)
+
+// writeFile writes CONTENTS to DEST_PATH in the file system, of type FS_TYPE, on DEST_DISK.
+// MODE and DIRMODE are the octal permissions of the file and of the directories that are created, UID and GID its owner.
+func writeFile(_ context.Context, env map[string]string, output io.Writer) error {
+	disk, fsType, dest := env["DEST_DISK"], env["FS_TYPE"], env["DEST_PATH"]
+	if disk == "" || fsType == "" || dest == "" {
+		return errors.New("DEST_DISK, FS_TYPE and DEST_PATH are required")
+	}
+	mode, err := parseMode(env["MODE"], 0o644)
+	if err != nil {
+		return fmt.Errorf("invalid MODE: %w", err)
+	}
+	dirMode, err := parseMode(env["DIRMODE"], 0o755)
+	if err != nil {
+		return fmt.Errorf("invalid DIRMODE: %w", err)
+	}
+	uid, err := parseID(env["UID"])
+	if err != nil {
+		return fmt.Errorf("invalid UID: %w", err)
+	}
+	gid, err := parseID(env["GID"])
+	if err != nil {
+		return fmt.Errorf("invalid GID: %w", err)
+	}
+
+	return withMount(disk, fsType, 0, func(root string) error {
+		// Symlinks on the disk are resolved in it, so a file outside of it can't be written.
+		path, err := securepath.Join(root, dest)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(env["CONTENTS"]), mode); err != nil {
+			return err
+		}
+		// WriteFile does not change the permissions of existing files and is subject to the umask.
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+		if err := os.Chown(path, uid, gid); err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "wrote %s on %s\n", dest, disk)
+		return nil
+	})
+}
+
+// withMount mounts disk in a temporary directory, calls f with the directory and unmounts disk.
+func withMount(disk, fsType string, flags uintptr, f func(root string) error) (err error) {
+	root, err := os.MkdirTemp("", "builtin-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(root)
+	if err := unix.Mount(disk, root, fsType, flags, ""); err != nil {
+		return fmt.Errorf("error mounting %s: %w", disk, err)
+	}
+	defer func() {
+		if uerr := unix.Unmount(root, 0); uerr != nil {
+			err = errors.Join(err, fmt.Errorf("error unmounting %s: %w", disk, uerr))
+		}
+	}()
+
+	return f(root)
+}
+
+func parseMode(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return os.FileMode(m) & os.ModePerm, nil
+}
+
+func parseID(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(s)
+}
@@ -2,18 +2,28 @@ package containerd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"syscall"
+	"time"
 
 	"github.com/containerd/containerd"
 	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/images/archive"
 	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/containerd/oci"
-	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/platforms"
 	"github.com/containers/image/v5/pkg/shortnames"
 	"github.com/containers/image/v5/types"
 	"github.com/go-logr/logr"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/conv"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/imagecache"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/proxy"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/registry"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/volume"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
 )
 
@@ -22,29 +32,26 @@ type Config struct {
 	Client     *containerd.Client
 	Log        logr.Logger
 	SocketPath string
+	// Registry holds the credentials and mirrors used to pull images.
+	Registry registry.Config
+	// Proxy is used to pull images and is added to the environment of Actions.
+	Proxy proxy.Config
+	// VolumesDir is the directory under which named volumes are created. Defaults to volume.DefaultDir.
+	VolumesDir string
+	// Cache is the persistent image cache. Images that are not in the namespace are imported from it before they are
+	// pulled. There is no cache when it is nil.
+	Cache *imagecache.Cache
+	// StopTimeout is how long a task that is stopped is given to exit after SIGTERM before it is sent SIGKILL.
+	// Defaults to 5 seconds.
+	StopTimeout time.Duration
 }
 
-func (c *Config) Execute(ctx context.Context, a spec.Action) error {
-	ctx = namespaces.WithNamespace(ctx, c.Namespace)
-	// Pull the image
-	imageName := a.Image
-	r, err := shortnames.Resolve(&types.SystemContext{PodmanOnlyShortNamesIgnoreRegistriesConfAndForceDockerHub: true}, imageName)
-	if err != nil {
-		c.Log.Info("unable to resolve image fully qualified name", "error", err)
-	}
-	if r != nil && len(r.PullCandidates) > 0 {
-		imageName = r.PullCandidates[0].Value.String()
-	}
+func (c *Config) Execute(ctx context.Context, a spec.Action, output io.Writer) error {
 	// set up a containerd namespace
 	ctx = namespaces.WithNamespace(ctx, c.Namespace)
-	image, err := c.Client.GetImage(ctx, imageName)
+	image, err := c.pullImage(ctx, a.Image)
 	if err != nil {
-		// if the image isn't already in our namespaced context, then pull it
-		image, err = c.Client.Pull(ctx, imageName, containerd.WithPullUnpack, containerd.WithResolver(docker.NewResolver(docker.ResolverOptions{})))
-		if err != nil {
-			return fmt.Errorf("error pulling image: %w", err)
-		}
-		c.Log.Info("image pulled", "image", image.Name())
+		return err
 	}
 
 	// create a container
@@ -52,47 +59,184 @@ func (c *Config) Execute(ctx context.Context, a spec.Action) error {
 	if err != nil {
 		return fmt.Errorf("error creating container: %w", err)
 	}
-	defer func() { _ = tainer.Delete(ctx, containerd.WithSnapshotCleanup) }()
+	// The context passed to Execute may have been cancelled so a context that is not cancelled is used for cleanup.
+	cleanupCtx := context.WithoutCancel(ctx)
+	defer func() { _ = tainer.Delete(cleanupCtx, containerd.WithSnapshotCleanup) }()
 
+	if output == nil {
+		output = io.Discard
+	}
 	// create the task
-	task, err := tainer.NewTask(ctx, cio.NewCreator(cio.WithStdio))
+	task, err := tainer.NewTask(ctx, cio.NewCreator(cio.WithStreams(nil, output, output)))
 	if err != nil {
 		return fmt.Errorf("error creating task: %w", err)
 	}
-	defer func() { _, _ = task.Delete(ctx) }()
 
 	var statusC <-chan containerd.ExitStatus
-	statusC, err = task.Wait(ctx)
+	statusC, err = task.Wait(cleanupCtx)
 	if err != nil {
+		_, _ = task.Delete(cleanupCtx)
 		return fmt.Errorf("error waiting on task: %w", err)
 	}
 
 	// start the task
 	if err := task.Start(ctx); err != nil {
-		_, _ = task.Delete(ctx)
+		_, _ = task.Delete(cleanupCtx)
 		return fmt.Errorf("error starting task: %w", err)
 	}
 
-	exitStatus := <-statusC
-	if exitStatus.ExitCode() != 0 {
-		return fmt.Errorf("task exited with non-zero code: %d, error: %w", exitStatus.ExitCode(), exitStatus.Error())
+	select {
+	case exitStatus := <-statusC:
+		// Deleting the task waits for the remaining output so the end of it is not lost.
+		_, _ = task.Delete(cleanupCtx)
+		if exitStatus.ExitCode() != 0 {
+			return fmt.Errorf("task exited with non-zero code: %d, error: %w", exitStatus.ExitCode(), exitStatus.Error())
+		}
+		return nil
+	case <-ctx.Done():
+		c.stopTask(cleanupCtx, task, statusC)
+		_, _ = task.Delete(cleanupCtx, containerd.WithProcessKill)
+		return fmt.Errorf("context error: %w", ctx.Err())
+	}
+}
+
+// Prefetch pulls an image so that it is available when the Action that uses it runs.
+func (c *Config) Prefetch(ctx context.Context, image string) error {
+	_, err := c.pullImage(namespaces.WithNamespace(ctx, c.Namespace), image)
+	return err
+}
+
+// pullImage returns an image, it is pulled when it isn't already in the namespace.
+func (c *Config) pullImage(ctx context.Context, imageName string) (containerd.Image, error) {
+	r, err := shortnames.Resolve(&types.SystemContext{PodmanOnlyShortNamesIgnoreRegistriesConfAndForceDockerHub: true}, imageName)
+	if err != nil {
+		c.Log.Info("unable to resolve image fully qualified name", "error", err)
+	}
+	if r != nil && len(r.PullCandidates) > 0 {
+		imageName = r.PullCandidates[0].Value.String()
+	}
+	image, err := c.Client.GetImage(ctx, imageName)
+	if err == nil {
+		return image, nil
+	}
+	if image, ok := c.importCachedImage(ctx, imageName); ok {
+		return image, nil
+	}
+	// if the image isn't already in our namespaced context, then pull it
+	image, err = c.Client.Pull(ctx, imageName, containerd.WithPullUnpack, containerd.WithResolver(c.Registry.Resolver(c.Proxy.HTTPClient())))
+	if err != nil {
+		return nil, fmt.Errorf("error pulling image: %w", err)
+	}
+	c.Log.Info("image pulled", "image", image.Name())
+	c.cacheImage(ctx, image)
+
+	return image, nil
+}
+
+// importCachedImage imports, and unpacks, imageName from the cache.
+func (c *Config) importCachedImage(ctx context.Context, imageName string) (containerd.Image, bool) {
+	if c.Cache == nil {
+		return nil, false
+	}
+	rc, err := c.Cache.Get(imageName)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			c.Log.Info("unable to read image from the cache", "image", imageName, "error", err)
+		}
+		return nil, false
+	}
+	defer rc.Close()
+	imgs, err := c.Client.Import(ctx, rc, containerd.WithImportPlatform(platforms.Default()))
+	if err != nil {
+		c.Log.Info("unable to import image from the cache", "image", imageName, "error", err)
+		return nil, false
+	}
+	for _, img := range imgs {
+		if img.Name != imageName {
+			continue
+		}
+		image := containerd.NewImage(c.Client, img)
+		if err := image.Unpack(ctx, ""); err != nil {
+			c.Log.Info("unable to unpack image imported from the cache", "image", imageName, "error", err)
+			return nil, false
+		}
+		c.Log.Info("image imported from the cache", "image", imageName)
+		return image, true
+	}
+
+	return nil, false
+}
+
+// cacheImage exports a pulled image to the cache. Failing to cache an image doesn't fail the pull.
+func (c *Config) cacheImage(ctx context.Context, image containerd.Image) {
+	if c.Cache == nil {
+		return
+	}
+	err := c.Cache.Put(image.Name(), func(w io.Writer) error {
+		return c.Client.Export(ctx, w, archive.WithImage(c.Client.ImageService(), image.Name()), archive.WithPlatform(platforms.Default()))
+	})
+	if err != nil {
+		c.Log.Info("unable to cache image", "image", image.Name(), "error", err)
+		return
+	}
+	c.Log.Info("image cached", "image", image.Name())
+}
+
+// stopTask sends SIGTERM to the task and SIGKILL if it has not exited after a grace period.
+func (c *Config) stopTask(ctx context.Context, task containerd.Task, statusC <-chan containerd.ExitStatus) {
+	if err := task.Kill(ctx, syscall.SIGTERM); err != nil {
+		c.Log.Info("failed to gracefully stop task", "error", err)
+	}
+	select {
+	case <-statusC:
+		return
+	case <-time.After(stopTimeout(c.StopTimeout)):
+	}
+	if err := task.Kill(ctx, syscall.SIGKILL); err != nil {
+		c.Log.Info("failed to kill task", "error", err)
 	}
-	return nil
+	<-statusC
 }
 
 func (c *Config) createContainer(ctx context.Context, image containerd.Image, action spec.Action) (containerd.Container, error) {
 	newOpts := []containerd.NewContainerOpts{}
-	args := []string{action.Cmd}
-	args = append(args, action.Args...)
+	// The Action Cmd is the command launched in the container and replaces the entrypoint of the image.
+	// Args replace the command of the image. This matches the Docker runtime.
+	imageConfig := oci.WithImageConfig(image)
+	if action.Cmd == "" && len(action.Args) > 0 {
+		imageConfig = oci.WithImageConfigArgs(image, action.Args)
+	}
 	specOpts := []oci.SpecOpts{
-		oci.WithImageConfig(image),
+		imageConfig,
 		oci.WithPrivileged,
-		oci.WithEnv(conv.ParseEnv(action.Env)),
-		oci.WithProcessArgs(args...),
+		oci.WithEnv(c.Proxy.Env(conv.ParseEnv(action.Env))),
+	}
+	if action.Cmd != "" {
+		specOpts = append(specOpts, oci.WithProcessArgs(append([]string{action.Cmd}, action.Args...)...))
 	}
 	if action.Namespaces.PID == "host" {
 		specOpts = append(specOpts, oci.WithHostNamespace(specs.PIDNamespace))
 	}
+	// Without CNI, containerd only supports the host network namespace or a new network namespace
+	// with a loopback interface, which is the equivalent of the Docker "none" network mode.
+	switch action.Namespaces.Network {
+	case "host":
+		specOpts = append(specOpts, oci.WithHostNamespace(specs.NetworkNamespace), oci.WithHostHostsFile, oci.WithHostResolvconf)
+	case "", "none":
+	default:
+		return nil, fmt.Errorf("unsupported network namespace %q: must be one of \"host\" or \"none\"", action.Namespaces.Network)
+	}
+	volumesDir := c.VolumesDir
+	if volumesDir == "" {
+		volumesDir = volume.DefaultDir
+	}
+	ms, err := volume.Mounts(volumesDir, action.Volumes)
+	if err != nil {
+		return nil, err
+	}
+	if len(ms) > 0 {
+		specOpts = append(specOpts, oci.WithMounts(ms))
+	}
 	name := conv.ParseName(action.ID, action.Name)
 	newOpts = append(newOpts, containerd.WithNewSnapshot(name, image))
 	newOpts = append(newOpts, containerd.WithNewSpec(specOpts...))
@@ -120,6 +264,36 @@ func WithSocketPath(socketPath string) Opt {
 	}
 }
 
+func WithRegistry(r registry.Config) Opt {
+	return func(c *Config) {
+		c.Registry = r
+	}
+}
+
+func WithProxy(p proxy.Config) Opt {
+	return func(c *Config) {
+		c.Proxy = p
+	}
+}
+
+func WithVolumesDir(dir string) Opt {
+	return func(c *Config) {
+		c.VolumesDir = dir
+	}
+}
+
+func WithCache(cache *imagecache.Cache) Opt {
+	return func(c *Config) {
+		c.Cache = cache
+	}
+}
+
+func WithStopTimeout(d time.Duration) Opt {
+	return func(c *Config) {
+		c.StopTimeout = d
+	}
+}
+
 func NewConfig(log logr.Logger, opts ...Opt) (*Config, error) {
 	c := &Config{Log: log}
 	for _, opt := range opts {
@@ -144,3 +318,11 @@ func NewConfig(log logr.Logger, opts ...Opt) (*Config, error) {
 
 	return c, nil
 }
+
+// stopTimeout returns d, or the default stop timeout when d is not set.
+func stopTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 5 * time.Second
+	}
+	return d
+}
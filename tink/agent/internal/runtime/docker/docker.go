@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"time"
 
 	retry "github.com/avast/retry-go/v4"
@@ -13,60 +15,43 @@ import (
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/go-logr/logr"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/conv"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/imagecache"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/proxy"
+	reg "github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/registry"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
 )
 
 type Config struct {
-	Log          logr.Logger
-	Client       *client.Client
-	RegistryAuth *registry.AuthConfig
+	Log    logr.Logger
+	Client *client.Client
+	// Registry holds the credentials and mirrors used to pull images.
+	// Mirrors are used by rewriting the image reference. Mirrors using plain HTTP must be
+	// configured as insecure registries in the Docker daemon.
+	Registry reg.Config
+	// Proxy is added to the environment of Actions. When it is enabled images are pulled through it by the agent,
+	// and loaded into the Docker daemon, as the daemon doesn't use the proxy of the agent.
+	Proxy proxy.Config
+	// Cache is the persistent image cache. Cached images are loaded into the daemon before they are pulled so that
+	// only layers that changed are downloaded. There is no cache when it is nil.
+	Cache *imagecache.Cache
+	// StopTimeout is how long a container that is stopped is given to exit before it is killed.
+	// It is rounded down to the second. Defaults to 5 seconds.
+	StopTimeout time.Duration
 }
 
-func (c *Config) Execute(ctx context.Context, a spec.Action) error {
-	pullImage := func() error {
-		pullOpts := image.PullOptions{}
-
-		if c.RegistryAuth != nil {
-			encodedJSON, err := json.Marshal(c.RegistryAuth)
-			if err != nil {
-				return fmt.Errorf("unable to encode auth config: %w", err)
-			}
-			pullOpts.RegistryAuth = base64.URLEncoding.EncodeToString(encodedJSON)
-		}
-
-		img, err := c.Client.ImagePull(ctx, a.Image, pullOpts)
-		if err != nil {
-			// If the image is already present, we can ignore the error.
-			// This might be the case where the image is already present in the local cache
-			// and the environment doesn't have access to the registry.
-			// Embedded images in HookOS are a partial example of this.
-			if _, err := c.Client.ImageInspect(ctx, a.Image); err == nil {
-				return nil
-			}
-			return fmt.Errorf("docker: %w", err)
-		}
-		defer img.Close()
-
-		// Docker requires everything to be read from the images ReadCloser for the image to actually
-		// be pulled. We may want to log image pulls in a circular buffer somewhere for debug-ability.
-		if _, err = io.Copy(io.Discard, img); err != nil {
-			return fmt.Errorf("docker: %w", err)
-		}
-
-		return nil
-	}
-
-	err := retry.Do(pullImage, retry.Attempts(5), retry.DelayType(retry.BackOffDelay))
+func (c *Config) Execute(ctx context.Context, a spec.Action, output io.Writer) error {
+	img, err := c.pullImage(ctx, a.Image)
 	if err != nil {
 		return err
 	}
 
 	// TODO: Support all the other things on the action such as volumes.
 	cfg := container.Config{
-		Image: a.Image,
-		Env:   conv.ParseEnv(a.Env),
+		Image: img,
+		Env:   c.Proxy.Env(conv.ParseEnv(a.Env)),
 	}
 
 	hostCfg := container.HostConfig{
@@ -76,6 +61,9 @@ func (c *Config) Execute(ctx context.Context, a spec.Action) error {
 	if a.Namespaces.PID != "" {
 		hostCfg.PidMode = container.PidMode(a.Namespaces.PID)
 	}
+	if a.Namespaces.Network != "" {
+		hostCfg.NetworkMode = container.NetworkMode(a.Namespaces.Network)
+	}
 	for _, v := range a.Volumes {
 		hostCfg.Binds = append(hostCfg.Binds, string(v))
 	}
@@ -92,8 +80,6 @@ func (c *Config) Execute(ctx context.Context, a spec.Action) error {
 		cfg.Cmd = append(cfg.Cmd, a.Args...)
 	}
 
-	// TODO: Figure out container logging. We probably want to save it somewhere for debug-ability.
-
 	create, err := c.Client.ContainerCreate(ctx, &cfg, &hostCfg, nil, nil, containerName)
 	if err != nil {
 		return fmt.Errorf("error creating container: %w", err)
@@ -124,12 +110,20 @@ func (c *Config) Execute(ctx context.Context, a spec.Action) error {
 		return fmt.Errorf("error starting container: %w", err)
 	}
 
+	logsDone := c.copyLogs(ctx, create.ID, output)
+
 	select {
 	case result := <-waitBody:
+		// Wait for the remaining output so the end of it is not lost when the container is removed.
+		select {
+		case <-logsDone:
+		case <-time.After(5 * time.Second):
+			c.Log.Info("timed out waiting for container output", "container_name", containerName)
+		}
 		if result.StatusCode == 0 {
 			return nil
 		}
-		return fmt.Errorf("got non 0 exit status, see the logs for more information")
+		return fmt.Errorf("got non 0 exit status: %d", result.StatusCode)
 
 	case err := <-waitErr:
 		return fmt.Errorf("error while waiting for container: %w", err)
@@ -137,7 +131,7 @@ func (c *Config) Execute(ctx context.Context, a spec.Action) error {
 	case <-ctx.Done():
 		// We can't use the context passed to Run() as its been cancelled.
 		err := c.Client.ContainerStop(context.Background(), create.ID, container.StopOptions{
-			Timeout: toPtr(5),
+			Timeout: toPtr(int(stopTimeout(c.StopTimeout) / time.Second)),
 		})
 		if err != nil {
 			c.Log.Info("Failed to gracefully stop container", "error", err)
@@ -146,6 +140,185 @@ func (c *Config) Execute(ctx context.Context, a spec.Action) error {
 	}
 }
 
+// Prefetch pulls an image so that it is available when the Action that uses it runs.
+func (c *Config) Prefetch(ctx context.Context, image string) error {
+	_, err := c.pullImage(ctx, image)
+	return err
+}
+
+// pullImage pulls an image, from the mirrors of its registry first, and returns the reference that was pulled.
+func (c *Config) pullImage(ctx context.Context, image string) (string, error) {
+	cachedID := c.loadCachedImage(ctx, image)
+	if c.Proxy.Enabled() {
+		var ref string
+		pull := func() error {
+			var err error
+			ref, err = c.pullThroughProxy(ctx, image)
+			return err
+		}
+		if err := retry.Do(pull, retry.Attempts(5), retry.DelayType(retry.BackOffDelay)); err != nil {
+			// The image may already be in the daemon, embedded in the OS for example.
+			if _, ierr := c.Client.ImageInspect(ctx, image); ierr == nil {
+				return image, nil
+			}
+			return "", err
+		}
+		c.cacheImage(ctx, image, ref, cachedID)
+		return ref, nil
+	}
+	var errs error
+	for _, ref := range c.Registry.PullRefs(image) {
+		pull := func() error {
+			return c.doPullImage(ctx, ref)
+		}
+		err := retry.Do(pull, retry.Attempts(5), retry.DelayType(retry.BackOffDelay))
+		if err == nil {
+			c.cacheImage(ctx, image, ref, cachedID)
+			return ref, nil
+		}
+		c.Log.Info("unable to pull image", "image", ref, "error", err)
+		errs = errors.Join(errs, err)
+	}
+
+	return "", errs
+}
+
+func (c *Config) doPullImage(ctx context.Context, ref string) error {
+	pullOpts := image.PullOptions{}
+	if cred, ok := c.Registry.Credential(reg.Host(ref)); ok {
+		encodedJSON, err := json.Marshal(registry.AuthConfig{
+			Username:      cred.Username,
+			Password:      cred.Password,
+			ServerAddress: cred.Registry,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to encode auth config: %w", err)
+		}
+		pullOpts.RegistryAuth = base64.URLEncoding.EncodeToString(encodedJSON)
+	}
+
+	img, err := c.Client.ImagePull(ctx, ref, pullOpts)
+	if err != nil {
+		// If the image is already present, we can ignore the error.
+		// This might be the case where the image is already present in the local cache
+		// and the environment doesn't have access to the registry.
+		// Embedded images in HookOS are a partial example of this.
+		if _, err := c.Client.ImageInspect(ctx, ref); err == nil {
+			return nil
+		}
+		return fmt.Errorf("docker: %w", err)
+	}
+	defer img.Close()
+
+	// Docker requires everything to be read from the images ReadCloser for the image to actually
+	// be pulled. We may want to log image pulls in a circular buffer somewhere for debug-ability.
+	if _, err = io.Copy(io.Discard, img); err != nil {
+		return fmt.Errorf("docker: %w", err)
+	}
+
+	return nil
+}
+
+// loadCachedImage loads image from the cache into the daemon, unless the daemon already has it, and returns its ID.
+// An empty ID is returned when there is no cache or the image is neither in the daemon nor in the cache.
+func (c *Config) loadCachedImage(ctx context.Context, image string) string {
+	if c.Cache == nil {
+		return ""
+	}
+	// The image was loaded from the cache, or pulled and cached, earlier in this boot, or is embedded in the OS.
+	if inspect, err := c.Client.ImageInspect(ctx, image); err == nil {
+		return inspect.ID
+	}
+	archive, err := c.Cache.Get(image)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			c.Log.Info("unable to read image from the cache", "image", image, "error", err)
+		}
+		return ""
+	}
+	defer archive.Close()
+	resp, err := c.Client.ImageLoad(ctx, archive, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		c.Log.Info("unable to load image from the cache", "image", image, "error", err)
+		return ""
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	inspect, err := c.Client.ImageInspect(ctx, image)
+	if err != nil {
+		c.Log.Info("image loaded from the cache not found", "image", image, "error", err)
+		return ""
+	}
+	c.Log.Info("image loaded from the cache", "image", image)
+
+	return inspect.ID
+}
+
+// cacheImage saves the image pulled as ref to the cache, under the name image, unless it is the image that was
+// in the daemon, or loaded from the cache, before the pull. Failing to cache an image doesn't fail the pull.
+func (c *Config) cacheImage(ctx context.Context, image, ref, cachedID string) {
+	if c.Cache == nil {
+		return
+	}
+	inspect, err := c.Client.ImageInspect(ctx, ref)
+	if err != nil || inspect.ID == cachedID {
+		return
+	}
+	names := []string{ref}
+	if ref != image {
+		// The image is tagged with the name it is cached under so that it can be found after it is loaded.
+		if err := c.Client.ImageTag(ctx, ref, image); err != nil {
+			c.Log.Info("unable to cache image", "image", image, "error", err)
+			return
+		}
+		names = append(names, image)
+	}
+	err = c.Cache.Put(image, func(w io.Writer) error {
+		archive, err := c.Client.ImageSave(ctx, names)
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+		_, err = io.Copy(w, archive)
+		return err
+	})
+	if err != nil {
+		c.Log.Info("unable to cache image", "image", image, "error", err)
+		return
+	}
+	c.Log.Info("image cached", "image", image)
+}
+
+// copyLogs follows the stdout and stderr of a container and writes them to output.
+// The returned channel is closed once all output has been copied.
+func (c *Config) copyLogs(ctx context.Context, containerID string, output io.Writer) <-chan struct{} {
+	done := make(chan struct{})
+	logs, err := c.Client.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	if err != nil {
+		c.Log.Info("unable to get container output", "error", err)
+		close(done)
+		return done
+	}
+	go func() {
+		defer close(done)
+		defer logs.Close()
+		// Containers are created without a TTY so stdout and stderr are multiplexed.
+		if _, err := stdcopy.StdCopy(output, output, logs); err != nil && ctx.Err() == nil {
+			c.Log.Info("error copying container output", "error", err)
+		}
+	}()
+
+	return done
+}
+
 func toPtr[T any](v T) *T {
 	return &v
 }
+
+// stopTimeout returns d, or the default stop timeout when d is not set.
+func stopTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 5 * time.Second
+	}
+	return d
+}
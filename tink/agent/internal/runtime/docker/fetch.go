@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/images/archive"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/platforms"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// pullThroughProxy pulls image, for the platform of the agent, through the proxy and loads it into the Docker daemon.
+// The mirrors and credentials of the registry are used like they are by the other runtimes.
+// It returns the reference under which the image is loaded.
+func (c *Config) pullThroughProxy(ctx context.Context, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	named = reference.TagNameOnly(named)
+	ref := named.String()
+	loadRef := ref
+	if d, ok := named.(reference.Digested); ok {
+		// The daemon only names loaded images by tag, so the digest is used as the tag.
+		tagged, err := reference.WithTag(reference.TrimNamed(named), strings.ReplaceAll(d.Digest().String(), ":", "-"))
+		if err != nil {
+			return "", err
+		}
+		loadRef = tagged.String()
+	}
+
+	resolver := c.Registry.Resolver(c.Proxy.HTTPClient())
+	name, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %q: %w", ref, err)
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "tink-agent-pull-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	store, err := local.NewStore(dir)
+	if err != nil {
+		return "", err
+	}
+	platform := platforms.Default()
+	// manifest is the manifest of the image for the platform, only one is fetched.
+	var manifest ocispec.Descriptor
+	recordManifest := images.HandlerFunc(func(_ context.Context, d ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if images.IsManifestType(d.MediaType) {
+			manifest = d
+		}
+		return nil, nil
+	})
+	handlers := images.Handlers(
+		remotes.FetchHandler(store, fetcher),
+		recordManifest,
+		images.LimitManifests(images.FilterPlatforms(images.ChildrenHandler(store), platform), platform, 1),
+	)
+	if err := images.Dispatch(ctx, handlers, nil, desc); err != nil {
+		return "", fmt.Errorf("error fetching %q: %w", ref, err)
+	}
+	if manifest.Digest == "" {
+		return "", fmt.Errorf("no manifest of %q for platform %s", ref, platforms.Format(platforms.DefaultSpec()))
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(archive.Export(ctx, store, pw, archive.WithManifest(manifest, loadRef)))
+	}()
+	resp, err := c.Client.ImageLoad(ctx, pr, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		_ = pr.CloseWithError(err)
+		return "", fmt.Errorf("error loading %q into the docker daemon: %w", ref, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	_ = pr.Close()
+	if _, err := c.Client.ImageInspect(ctx, loadRef); err != nil {
+		return "", fmt.Errorf("image %q loaded into the docker daemon not found: %w", ref, err)
+	}
+	c.Log.Info("image pulled through the proxy", "image", ref)
+
+	return loadRef, nil
+}
@@ -0,0 +1,193 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/securepath"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"golang.org/x/sys/unix"
+)
+
+// runChroot runs an Action in a chroot of rootfs with new UTS, IPC and mount namespaces and,
+// unless the host namespaces are requested, new PID and network namespaces.
+// /dev and /sys of the host, /proc and the volumes are mounted in rootfs before the Action starts
+// and are removed once it exits.
+func (c *Config) runChroot(ctx context.Context, rootfs string, p process, ns spec.Namespaces, mounts []specs.Mount, output io.Writer) (err error) {
+	if err := validateNamespaces(ns); err != nil {
+		return err
+	}
+	ms := []specs.Mount{
+		{Destination: "/proc", Type: "proc", Source: "proc"},
+		{Destination: "/sys", Type: "bind", Source: "/sys", Options: []string{"rbind"}},
+		{Destination: "/dev", Type: "bind", Source: "/dev", Options: []string{"rbind"}},
+	}
+	if ns.Network == "host" {
+		ms = append(ms,
+			specs.Mount{Destination: "/etc/resolv.conf", Type: "bind", Source: "/etc/resolv.conf", Options: []string{"rbind", "ro"}},
+			specs.Mount{Destination: "/etc/hosts", Type: "bind", Source: "/etc/hosts", Options: []string{"rbind", "ro"}},
+		)
+	}
+	ms = append(ms, mounts...)
+
+	var mounted []string
+	defer func() {
+		if uerr := unmountAll(mounted); uerr != nil {
+			err = errors.Join(err, uerr)
+		}
+	}()
+	for _, m := range ms {
+		target, merr := mountInRootfs(rootfs, m)
+		if target != "" {
+			mounted = append(mounted, target)
+		}
+		if merr != nil {
+			return fmt.Errorf("error mounting %s: %w", m.Destination, merr)
+		}
+	}
+
+	path, err := lookPath(rootfs, p.Args[0], p.Env)
+	if err != nil {
+		return err
+	}
+	cloneflags := uintptr(syscall.CLONE_NEWUTS | syscall.CLONE_NEWIPC | syscall.CLONE_NEWNS)
+	if ns.PID != "host" {
+		cloneflags |= syscall.CLONE_NEWPID
+	}
+	if ns.Network != "host" {
+		cloneflags |= syscall.CLONE_NEWNET
+	}
+	cmd := exec.CommandContext(ctx, path) // #nosec G204 -- the command of the Action runs in its chroot
+	cmd.Args = p.Args
+	cmd.Env = p.Env
+	cmd.Dir = p.Cwd
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Chroot:     rootfs,
+		Cloneflags: cloneflags,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	// Stop the Action gracefully when the context is cancelled, it is killed if it has not exited after WaitDelay.
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = stopTimeout(c.StopTimeout)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("context error: %w", ctx.Err())
+		}
+		return fmt.Errorf("error running action: %w", err)
+	}
+
+	return nil
+}
+
+// mountInRootfs mounts m in rootfs and returns the path of the mount target once it is mounted.
+func mountInRootfs(rootfs string, m specs.Mount) (string, error) {
+	target, err := securepath.Join(rootfs, m.Destination)
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(m.Source); err == nil && !fi.IsDir() {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil { // #nosec G301 -- directories in the rootfs of an Action
+			return "", err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_RDONLY, 0o644) // #nosec G302,G304 -- mount target in the rootfs of an Action
+		if err != nil {
+			return "", err
+		}
+		_ = f.Close()
+	} else if err := os.MkdirAll(target, 0o755); err != nil { // #nosec G301 -- directories in the rootfs of an Action
+		return "", err
+	}
+
+	if m.Type != "bind" {
+		if err := unix.Mount(m.Source, target, m.Type, 0, ""); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+	flags := uintptr(unix.MS_BIND)
+	if slices.Contains(m.Options, "rbind") {
+		flags |= unix.MS_REC
+	}
+	if err := unix.Mount(m.Source, target, "", flags, ""); err != nil {
+		return "", err
+	}
+	for _, o := range m.Options {
+		var propagation uintptr
+		switch o {
+		case "shared":
+			propagation = unix.MS_SHARED
+		case "rshared":
+			propagation = unix.MS_SHARED | unix.MS_REC
+		case "slave":
+			propagation = unix.MS_SLAVE
+		case "rslave":
+			propagation = unix.MS_SLAVE | unix.MS_REC
+		case "private":
+			propagation = unix.MS_PRIVATE
+		case "rprivate":
+			propagation = unix.MS_PRIVATE | unix.MS_REC
+		default:
+			continue
+		}
+		if err := unix.Mount("", target, "", propagation, ""); err != nil {
+			return target, err
+		}
+	}
+	if slices.Contains(m.Options, "ro") {
+		if err := unix.Mount("", target, "", flags|unix.MS_REMOUNT|unix.MS_RDONLY, ""); err != nil {
+			return target, err
+		}
+	}
+
+	return target, nil
+}
+
+// unmountAll lazily unmounts targets in reverse order.
+func unmountAll(targets []string) error {
+	var errs error
+	for _, t := range slices.Backward(targets) {
+		if err := unix.Unmount(t, unix.MNT_DETACH); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("error unmounting %s: %w", t, err))
+		}
+	}
+
+	return errs
+}
+
+// lookPath returns the path, in rootfs, of the executable name using the PATH in env.
+func lookPath(rootfs, name string, env []string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	var path string
+	for _, e := range env {
+		if v, ok := strings.CutPrefix(e, "PATH="); ok {
+			path = v
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		p := filepath.Join("/", dir, name)
+		hp, err := securepath.Join(rootfs, p)
+		if err != nil {
+			continue
+		}
+		if fi, err := os.Stat(hp); err == nil && fi.Mode().IsRegular() && fi.Mode()&0o111 != 0 {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("executable %q not found in PATH %q of the image", name, path)
+}
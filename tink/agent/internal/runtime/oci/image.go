@@ -0,0 +1,140 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containerd/containerd/archive"
+	"github.com/containerd/containerd/archive/compression"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/platforms"
+	"github.com/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Prefetch pulls an image into the content store so that it is available when the Action that uses it runs.
+func (c *Config) Prefetch(ctx context.Context, image string) error {
+	_, _, err := c.pull(ctx, image)
+	return err
+}
+
+// pull fetches an image, for the platform of the agent, into the content store and returns its configuration and layers.
+// Blobs that are already in the content store are not fetched again.
+func (c *Config) pull(ctx context.Context, image string) (ocispec.Image, []ocispec.Descriptor, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ocispec.Image{}, nil, fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	ref := reference.TagNameOnly(named).String()
+
+	platform := platforms.Default()
+	handlers := []images.Handler{}
+	resolver := c.Registry.Resolver(c.Proxy.HTTPClient())
+	name, desc, err := resolver.Resolve(ctx, ref)
+	if err == nil {
+		fetcher, err := resolver.Fetcher(ctx, name)
+		if err != nil {
+			return ocispec.Image{}, nil, err
+		}
+		handlers = append(handlers, remotes.FetchHandler(c.store, fetcher))
+	} else {
+		cached, cerr := c.cachedDescriptor(ref)
+		if cerr != nil {
+			return ocispec.Image{}, nil, fmt.Errorf("error resolving %q: %w", ref, err)
+		}
+		c.Log.Info("unable to resolve image, using the cached image", "image", ref, "error", err)
+		desc = cached
+	}
+	if c.Cache != nil {
+		handlers = append(handlers, images.HandlerFunc(func(_ context.Context, d ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			c.Cache.Touch(d.Digest.Encoded())
+			return nil, nil
+		}))
+	}
+	handlers = append(handlers, images.LimitManifests(images.FilterPlatforms(images.ChildrenHandler(c.store), platform), platform, 1))
+	if err := images.Dispatch(ctx, images.Handlers(handlers...), nil, desc); err != nil {
+		return ocispec.Image{}, nil, err
+	}
+	c.Log.Info("image pulled", "image", ref)
+	c.cacheDescriptor(ref, desc)
+
+	manifest, err := images.Manifest(ctx, c.store, desc, platform)
+	if err != nil {
+		return ocispec.Image{}, nil, err
+	}
+	b, err := content.ReadBlob(ctx, c.store, manifest.Config)
+	if err != nil {
+		return ocispec.Image{}, nil, fmt.Errorf("error reading image config: %w", err)
+	}
+	var img ocispec.Image
+	if err := json.Unmarshal(b, &img); err != nil {
+		return ocispec.Image{}, nil, fmt.Errorf("error decoding image config: %w", err)
+	}
+
+	return img, manifest.Layers, nil
+}
+
+// cachedDescriptor returns the descriptor that ref resolved to when it was last pulled.
+func (c *Config) cachedDescriptor(ref string) (ocispec.Descriptor, error) {
+	if c.Cache == nil {
+		return ocispec.Descriptor{}, errors.New("no image cache")
+	}
+	rc, err := c.Cache.Get(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer rc.Close()
+	var desc ocispec.Descriptor
+	if err := json.NewDecoder(rc).Decode(&desc); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	return desc, nil
+}
+
+// cacheDescriptor records the descriptor that ref resolved to, so that the image can be run when the registry can't be
+// reached, and garbage collects the cache. Failing to cache an image doesn't fail the pull.
+func (c *Config) cacheDescriptor(ref string, desc ocispec.Descriptor) {
+	if c.Cache == nil {
+		return
+	}
+	if err := c.Cache.Put(ref, func(w io.Writer) error { return json.NewEncoder(w).Encode(desc) }); err != nil {
+		c.Log.Info("unable to cache image", "image", ref, "error", err)
+	}
+}
+
+// unpack applies the layers of an image, in order, to rootfs.
+func (c *Config) unpack(ctx context.Context, layers []ocispec.Descriptor, rootfs string) error {
+	if err := os.MkdirAll(rootfs, 0o755); err != nil { // #nosec G301 -- the rootfs of an Action container
+		return err
+	}
+	for _, l := range layers {
+		if err := c.applyLayer(ctx, l, rootfs); err != nil {
+			return fmt.Errorf("error applying layer %s: %w", l.Digest, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) applyLayer(ctx context.Context, layer ocispec.Descriptor, rootfs string) error {
+	ra, err := c.store.ReaderAt(ctx, layer)
+	if err != nil {
+		return err
+	}
+	defer ra.Close()
+	r, err := compression.DecompressStream(content.NewReader(ra))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = archive.Apply(ctx, rootfs, r)
+
+	return err
+}
@@ -0,0 +1,299 @@
+// Package oci is a runtime that runs Actions without a container daemon.
+// Images are pulled into a local content store and unpacked into a root filesystem that is run
+// with runc or, in the minimal chroot mode, in a chroot with new Linux namespaces.
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/go-logr/logr"
+	"github.com/moby/sys/mountinfo"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/conv"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/imagecache"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/proxy"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/registry"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/volume"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+// Mode is how the unpacked image of an Action is run.
+type Mode string
+
+const (
+	// ModeRunc runs Actions with the runc binary.
+	ModeRunc Mode = "runc"
+	// ModeChroot runs Actions in a chroot with new UTS, IPC, mount, PID and network namespaces.
+	// It has no dependencies other than the kernel but /proc is the one of the host.
+	ModeChroot Mode = "chroot"
+
+	// DefaultDir is the directory that holds the content store and the Action bundles.
+	DefaultDir = "/var/lib/tinkerbell/oci"
+	// defaultPath is used when neither the image nor the Action set PATH.
+	defaultPath = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+type Config struct {
+	Log logr.Logger
+	// Dir holds the content store and the Action bundles. Defaults to DefaultDir.
+	Dir string
+	// Mode is how Actions are run. Defaults to ModeRunc.
+	Mode Mode
+	// RuncPath is the path to the runc binary. Defaults to "runc" in the PATH.
+	RuncPath string
+	// VolumesDir is the directory under which named volumes are created. Defaults to volume.DefaultDir.
+	VolumesDir string
+	// Registry holds the credentials and mirrors used to pull images.
+	Registry registry.Config
+	// Proxy is used to pull images and is added to the environment of Actions.
+	Proxy proxy.Config
+	// Cache is the persistent image cache. When it is set it is used as the content store, instead of a content store
+	// in Dir, and images are run from it when their registry can't be reached.
+	Cache *imagecache.Cache
+	// StopTimeout is how long an Action that is stopped is given to exit after SIGTERM before it is killed.
+	// Defaults to 5 seconds.
+	StopTimeout time.Duration
+
+	store content.Store
+}
+
+type Opt func(*Config)
+
+func WithDir(dir string) Opt {
+	return func(c *Config) {
+		c.Dir = dir
+	}
+}
+
+func WithMode(m Mode) Opt {
+	return func(c *Config) {
+		c.Mode = m
+	}
+}
+
+func WithRuncPath(path string) Opt {
+	return func(c *Config) {
+		c.RuncPath = path
+	}
+}
+
+func WithVolumesDir(dir string) Opt {
+	return func(c *Config) {
+		c.VolumesDir = dir
+	}
+}
+
+func WithRegistry(r registry.Config) Opt {
+	return func(c *Config) {
+		c.Registry = r
+	}
+}
+
+func WithProxy(p proxy.Config) Opt {
+	return func(c *Config) {
+		c.Proxy = p
+	}
+}
+
+func WithCache(cache *imagecache.Cache) Opt {
+	return func(c *Config) {
+		c.Cache = cache
+	}
+}
+
+func WithStopTimeout(d time.Duration) Opt {
+	return func(c *Config) {
+		c.StopTimeout = d
+	}
+}
+
+func NewConfig(log logr.Logger, opts ...Opt) (*Config, error) {
+	c := &Config{
+		Log:        log,
+		Dir:        DefaultDir,
+		Mode:       ModeRunc,
+		RuncPath:   "runc",
+		VolumesDir: volume.DefaultDir,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	switch c.Mode {
+	case ModeRunc, ModeChroot:
+	default:
+		return nil, fmt.Errorf("invalid mode %q, must be one of [%s, %s]", c.Mode, ModeRunc, ModeChroot)
+	}
+	storeDir := filepath.Join(c.Dir, "content")
+	if c.Cache != nil {
+		storeDir = c.Cache.Dir
+	}
+	store, err := local.NewStore(storeDir)
+	if err != nil {
+		return nil, fmt.Errorf("error creating content store: %w", err)
+	}
+	c.store = store
+
+	return c, nil
+}
+
+func (c *Config) Execute(ctx context.Context, a spec.Action, output io.Writer) error {
+	img, layers, err := c.pull(ctx, a.Image)
+	if err != nil {
+		return fmt.Errorf("error pulling image: %w", err)
+	}
+
+	name := conv.ParseName(a.ID, a.Name)
+	bundle := filepath.Join(c.Dir, "bundles", name)
+	if err := removeBundle(bundle); err != nil {
+		return fmt.Errorf("error removing stale bundle: %w", err)
+	}
+	defer func() {
+		if err := removeBundle(bundle); err != nil {
+			c.Log.Info("unable to remove bundle", "bundle", bundle, "error", err)
+		}
+	}()
+	rootfs := filepath.Join(bundle, "rootfs")
+	if err := c.unpack(ctx, layers, rootfs); err != nil {
+		return fmt.Errorf("error unpacking image: %w", err)
+	}
+
+	p, err := c.process(img, a)
+	if err != nil {
+		return err
+	}
+	mounts, err := volume.Mounts(c.VolumesDir, a.Volumes)
+	if err != nil {
+		return err
+	}
+	if output == nil {
+		output = io.Discard
+	}
+
+	if c.Mode == ModeChroot {
+		return c.runChroot(ctx, rootfs, p, a.Namespaces, mounts, output)
+	}
+	return c.runRunc(ctx, name, bundle, p, a.Namespaces, mounts, output)
+}
+
+// removeBundle removes a bundle once any mounts left in it, by an agent that did not exit cleanly, are removed.
+// Removing a rootfs that still has mounts would remove the content of the mounted host directories.
+func removeBundle(bundle string) error {
+	ms, err := mountinfo.GetMounts(mountinfo.PrefixFilter(bundle))
+	if err != nil {
+		return err
+	}
+	targets := make([]string, 0, len(ms))
+	for _, m := range ms {
+		targets = append(targets, m.Mountpoint)
+	}
+	// Mounts are listed in the order they were mounted, nested mounts are removed first.
+	if err := unmountAll(targets); err != nil {
+		return err
+	}
+	if ms, err := mountinfo.GetMounts(mountinfo.PrefixFilter(bundle)); err != nil || len(ms) > 0 {
+		return fmt.Errorf("bundle still has mounts: %w", err)
+	}
+
+	return os.RemoveAll(bundle)
+}
+
+// process holds the process of an Action.
+type process struct {
+	Args []string
+	Env  []string
+	Cwd  string
+}
+
+// process returns the process to run for an Action from the configuration of its image.
+// The Action Cmd replaces the entrypoint of the image and Args replace the command of the image,
+// this matches the Docker and containerd runtimes.
+func (c *Config) process(img ocispec.Image, a spec.Action) (process, error) {
+	var args []string
+	switch {
+	case a.Cmd != "":
+		args = append([]string{a.Cmd}, a.Args...)
+	case len(a.Args) > 0:
+		args = append(append(args, img.Config.Entrypoint...), a.Args...)
+	default:
+		args = append(append(args, img.Config.Entrypoint...), img.Config.Cmd...)
+	}
+	if len(args) == 0 {
+		return process{}, errors.New("no command specified in the Action or its image")
+	}
+
+	env := mergeEnv(img.Config.Env, conv.ParseEnv(a.Env))
+	if !hasEnv(env, "PATH") {
+		env = append([]string{defaultPath}, env...)
+	}
+	cwd := img.Config.WorkingDir
+	if cwd == "" {
+		cwd = "/"
+	}
+
+	return process{Args: args, Env: c.Proxy.Env(env), Cwd: cwd}, nil
+}
+
+// mergeEnv returns base with the variables in override added, or replaced when they are already set.
+func mergeEnv(base, override []string) []string {
+	env := append([]string{}, base...)
+	for _, o := range override {
+		k, _, _ := strings.Cut(o, "=")
+		replaced := false
+		for i, e := range env {
+			if ek, _, _ := strings.Cut(e, "="); ek == k {
+				env[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			env = append(env, o)
+		}
+	}
+
+	return env
+}
+
+func hasEnv(env []string, key string) bool {
+	for _, e := range env {
+		if k, _, _ := strings.Cut(e, "="); k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateNamespaces returns an error for namespaces that can't be honoured without a container network stack.
+func validateNamespaces(ns spec.Namespaces) error {
+	switch ns.Network {
+	case "", "none", "host":
+	default:
+		return fmt.Errorf("unsupported network namespace %q: must be one of \"host\" or \"none\"", ns.Network)
+	}
+	switch ns.PID {
+	case "", "host":
+	default:
+		return fmt.Errorf("unsupported pid namespace %q: must be \"host\" or empty", ns.PID)
+	}
+
+	return nil
+}
+
+// stopTimeout returns d, or the default stop timeout when d is not set.
+func stopTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 5 * time.Second
+	}
+	return d
+}
@@ -0,0 +1,66 @@
+package oci
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/pkg/proxy"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+func TestProcess(t *testing.T) {
+	img := ocispec.Image{Config: ocispec.ImageConfig{
+		Entrypoint: []string{"/entrypoint.sh"},
+		Cmd:        []string{"default"},
+		Env:        []string{"PATH=/bin", "A=image"},
+		WorkingDir: "/work",
+	}}
+	tests := map[string]struct {
+		img     ocispec.Image
+		action  spec.Action
+		proxy   proxy.Config
+		want    process
+		wantErr bool
+	}{
+		"image defaults": {
+			img:  img,
+			want: process{Args: []string{"/entrypoint.sh", "default"}, Env: []string{"PATH=/bin", "A=image"}, Cwd: "/work"},
+		},
+		"cmd replaces the entrypoint and command": {
+			img:    img,
+			action: spec.Action{Cmd: "/bin/sh", Args: []string{"-c", "true"}},
+			want:   process{Args: []string{"/bin/sh", "-c", "true"}, Env: []string{"PATH=/bin", "A=image"}, Cwd: "/work"},
+		},
+		"args replace the command": {
+			img:    img,
+			action: spec.Action{Args: []string{"other"}},
+			want:   process{Args: []string{"/entrypoint.sh", "other"}, Env: []string{"PATH=/bin", "A=image"}, Cwd: "/work"},
+		},
+		"action env overrides image env": {
+			img:    img,
+			action: spec.Action{Env: []spec.Env{{Key: "A", Value: "action"}, {Key: "B", Value: "b"}}},
+			want:   process{Args: []string{"/entrypoint.sh", "default"}, Env: []string{"PATH=/bin", "A=action", "B=b"}, Cwd: "/work"},
+		},
+		"default path and cwd": {
+			action: spec.Action{Cmd: "sleep"},
+			proxy:  proxy.Config{HTTPProxy: "http://proxy:3128"},
+			want:   process{Args: []string{"sleep"}, Env: []string{defaultPath, "HTTP_PROXY=http://proxy:3128", "http_proxy=http://proxy:3128"}, Cwd: "/"},
+		},
+		"no command": {
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Config{Proxy: tt.proxy}
+			got, err := c.process(tt.img, tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("process() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
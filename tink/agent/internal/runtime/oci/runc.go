@@ -0,0 +1,81 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+// runcNamespace is the namespace used for the cgroups of Action containers.
+const runcNamespace = "tinkerbell"
+
+// runRunc writes the runtime spec of an Action to its bundle and runs it with runc.
+func (c *Config) runRunc(ctx context.Context, id, bundle string, p process, ns spec.Namespaces, mounts []specs.Mount, output io.Writer) error {
+	if err := validateNamespaces(ns); err != nil {
+		return err
+	}
+	specOpts := []oci.SpecOpts{
+		oci.WithRootFSPath("rootfs"),
+		oci.WithProcessArgs(p.Args...),
+		oci.WithEnv(p.Env),
+		oci.WithProcessCwd(p.Cwd),
+		oci.WithPrivileged,
+		oci.WithHostDevices,
+		oci.WithAllDevicesAllowed,
+	}
+	if ns.PID == "host" {
+		specOpts = append(specOpts, oci.WithHostNamespace(specs.PIDNamespace))
+	}
+	if ns.Network == "host" {
+		specOpts = append(specOpts, oci.WithHostNamespace(specs.NetworkNamespace), oci.WithHostHostsFile, oci.WithHostResolvconf)
+	}
+	if len(mounts) > 0 {
+		specOpts = append(specOpts, oci.WithMounts(mounts))
+	}
+	s, err := oci.GenerateSpec(namespaces.WithNamespace(ctx, runcNamespace), nil, &containers.Container{ID: id}, specOpts...)
+	if err != nil {
+		return fmt.Errorf("error generating runtime spec: %w", err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(bundle, "config.json"), b, 0o600); err != nil {
+		return fmt.Errorf("error writing runtime spec: %w", err)
+	}
+
+	// The context passed to Execute may have been cancelled so a context that is not cancelled is used for cleanup.
+	cleanupCtx := context.WithoutCancel(ctx)
+	defer func() {
+		if out, err := exec.CommandContext(cleanupCtx, c.RuncPath, "delete", "--force", id).CombinedOutput(); err != nil { // #nosec G204 -- runc path is set by the operator
+			c.Log.V(1).Info("unable to delete container", "id", id, "error", err, "output", string(out))
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, c.RuncPath, "run", "--bundle", bundle, id) // #nosec G204 -- runc path is set by the operator
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Stop the container gracefully when the context is cancelled, runc is killed if the container has not exited after WaitDelay.
+	cmd.Cancel = func() error {
+		return exec.CommandContext(cleanupCtx, c.RuncPath, "kill", id, "TERM").Run() // #nosec G204 -- runc path is set by the operator
+	}
+	cmd.WaitDelay = stopTimeout(c.StopTimeout)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("context error: %w", ctx.Err())
+		}
+		return fmt.Errorf("error running container: %w", err)
+	}
+
+	return nil
+}
@@ -39,9 +39,20 @@ type Action struct {
 
 	// Namespaces defines the Linux namespaces this container should execute in.
 	// +optional
-	Namespaces     Namespaces `json:"namespaces,omitempty,omitzero" yaml:"namespaces,omitempty,omitzero"`
-	Retries        int        `json:"retries,omitempty,omitzero" yaml:"retries,omitempty,omitzero"`
-	TimeoutSeconds int        `json:"timeoutSeconds,omitempty,omitzero" yaml:"timeoutSeconds,omitempty,omitzero"`
+	Namespaces Namespaces `json:"namespaces,omitempty,omitzero" yaml:"namespaces,omitempty,omitzero"`
+	// Retries is the number of times to retry the action after it fails.
+	// A failed action is run at most Retries + 1 times.
+	Retries int `json:"retries,omitempty,omitzero" yaml:"retries,omitempty,omitzero"`
+	// BackoffSeconds is the number of seconds to wait between retries.
+	BackoffSeconds int `json:"backoffSeconds,omitempty,omitzero" yaml:"backoffSeconds,omitempty,omitzero"`
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty,omitzero" yaml:"timeoutSeconds,omitempty,omitzero"`
+	// UpcomingImages are the images of the Actions that will run after this Action.
+	// They are pulled in the background while this Action runs.
+	UpcomingImages []string `json:"upcomingImages,omitempty,omitzero" yaml:"upcomingImages,omitempty,omitzero"`
+	// ImagePolicy is how Image is verified before the Action runs. The image is not verified when it is nil.
+	ImagePolicy *ImagePolicy `json:"imagePolicy,omitempty,omitzero" yaml:"imagePolicy,omitempty,omitzero"`
+	// Reboot reboots the worker once the Action succeeds and its success is reported.
+	Reboot bool `json:"reboot,omitempty,omitzero" yaml:"reboot,omitempty,omitzero"`
 	// ExecutionStart is the time the action started executing.
 	ExecutionStart time.Time `json:"executionStart,omitzero" yaml:"executionStart,omitzero"`
 	// ExecutionStop is the time the action stopped executing.
@@ -50,6 +61,16 @@ type Action struct {
 	ExecutionDuration string `json:"executionDuration,omitempty,omitzero" yaml:"duration,omitempty,omitzero"`
 }
 
+// ImagePolicy is how the image of an Action is verified before it runs.
+// An image is allowed when it is pinned in Digests and resolves to its pinned digest or, when it is not pinned,
+// when it has a cosign signature made with one of the PublicKeys.
+type ImagePolicy struct {
+	// PublicKeys are PEM encoded ECDSA, RSA or Ed25519 public keys.
+	PublicKeys []string `json:"publicKeys,omitempty,omitzero" yaml:"publicKeys,omitempty,omitzero"`
+	// Digests pins images to a manifest digest, in the form "sha256:<hex>", by image reference.
+	Digests map[string]string `json:"digests,omitempty,omitzero" yaml:"digests,omitempty,omitzero"`
+}
+
 type Env struct {
 	Key   string `json:"key" yaml:"key"`
 	Value string `json:"value" yaml:"value"`
@@ -87,6 +108,18 @@ type Event struct {
 	Action  Action
 	Message string
 	State   State
+	// Attempts holds the result of each execution of the Action when it has retries.
+	Attempts []Attempt
+}
+
+// Attempt is the result of a single execution of an Action.
+type Attempt struct {
+	// Attempt is the attempt number, starting at 1.
+	Attempt        int       `json:"attempt" yaml:"attempt"`
+	State          State     `json:"state" yaml:"state"`
+	ExecutionStart time.Time `json:"executionStart,omitzero" yaml:"executionStart,omitzero"`
+	ExecutionStop  time.Time `json:"executionStop,omitzero" yaml:"executionStop,omitzero"`
+	Message        string    `json:"message,omitempty" yaml:"message,omitempty"`
 }
 
 type State string
@@ -97,6 +130,10 @@ const (
 	StateRunning State = "running"
 	StateTimeout State = "timeout"
 	StateUnknown State = "unknown"
+	// StateVerificationFailed is the state of an Action whose image didn't satisfy its ImagePolicy, the Action is not run.
+	StateVerificationFailed State = "verification_failed"
+	// StateCanceled is the state of an Action that was stopped because its Workflow was canceled.
+	StateCanceled State = "canceled"
 )
 
 func (e Event) String() string {
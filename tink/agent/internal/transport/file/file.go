@@ -1,58 +1,308 @@
+// Package file is a transport that runs the Actions of a workflow file, without a Tink server, for offline use.
+// The result of each Action is written to a results file. The workflow is reloaded, and run from its first Action,
+// when the workflow file changes. When the agent restarts with an unchanged workflow file the workflow resumes after
+// the last successful Action.
 package file
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/go-logr/logr"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
-	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
 )
 
 type Config struct {
-	Log     logr.Logger
-	Actions chan spec.Action
-	FileLoc string
-	cancel  chan bool
+	Log logr.Logger
+	// WorkflowPath is a YAML file holding the list of Actions to run, in order.
+	WorkflowPath string
+	// ResultsPath is the YAML file the results of the Actions are written to.
+	// Defaults to the WorkflowPath with a ".results" suffix before its extension, for example "workflow.results.yaml".
+	ResultsPath string
+
+	mu      sync.Mutex
+	actions []spec.Action
+	results Results
+	// changed is closed, and replaced, when an Action may have become available to Read.
+	changed chan struct{}
+}
+
+// Results are the results of the Actions of a workflow file.
+type Results struct {
+	// WorkflowPath is the workflow file the results are for.
+	WorkflowPath string `json:"workflowPath"`
+	// Digest is the sha256 digest of the workflow file the results are for.
+	Digest string `json:"digest"`
+	// State is the state of the workflow, empty until its first Action runs.
+	State spec.State `json:"state,omitempty"`
+	// Actions holds the result of each Action of the workflow, in order.
+	Actions []Result `json:"actions"`
+}
+
+// Result is the result of an Action.
+type Result struct {
+	ID                string         `json:"id"`
+	Name              string         `json:"name"`
+	State             spec.State     `json:"state,omitempty"`
+	Message           string         `json:"message,omitempty"`
+	ExecutionStart    time.Time      `json:"executionStart,omitzero"`
+	ExecutionStop     time.Time      `json:"executionStop,omitzero"`
+	ExecutionDuration string         `json:"executionDuration,omitempty"`
+	Attempts          []spec.Attempt `json:"attempts,omitempty"`
+	// Reboot is whether the Action reboots the worker, an Action that reboots the worker is complete
+	// when the agent restarts while it is running.
+	Reboot bool `json:"reboot,omitempty"`
 }
 
-// Start reads the file and sends the actions to the Actions channel.
+// Start loads the workflow file and then reloads it when it changes. It blocks until the context is done.
 func (c *Config) Start(ctx context.Context) error {
-	c.Log.Info("file transport starting")
-	c.cancel = make(chan bool)
-	contents, err := os.ReadFile(c.FileLoc)
+	if c.WorkflowPath == "" {
+		return errors.New("the file transport requires a workflow path")
+	}
+	if c.ResultsPath == "" {
+		ext := filepath.Ext(c.WorkflowPath)
+		c.ResultsPath = strings.TrimSuffix(c.WorkflowPath, ext) + ".results" + ext
+	}
+	c.mu.Lock()
+	if c.changed == nil {
+		c.changed = make(chan struct{})
+	}
+	c.mu.Unlock()
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return err
 	}
-	actions := []spec.Action{}
-	if err := yaml.Unmarshal(contents, &actions); err != nil {
+	defer watcher.Close()
+	// The directory is watched, as editors and configuration management often replace the file instead of writing to it.
+	// It is watched before the workflow is loaded so that no change is missed.
+	if err := watcher.Add(filepath.Dir(c.WorkflowPath)); err != nil {
 		return err
 	}
-	for _, action := range actions {
+	if err := c.load(); err != nil {
+		return err
+	}
+	c.Log.Info("file transport started", "workflow", c.WorkflowPath, "results", c.ResultsPath)
+	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-c.cancel:
-			return nil
-		case c.Actions <- action:
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(c.WorkflowPath) || !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			if err := c.load(); err != nil {
+				c.Log.Info("unable to reload the workflow file", "error", err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			c.Log.Info("error watching the workflow file", "error", err)
+		}
+	}
+}
+
+// load reads the workflow file. The results of the workflow are kept when the file is unchanged,
+// they are read from the results file when the agent starts.
+func (c *Config) load() error {
+	contents, err := os.ReadFile(c.WorkflowPath)
+	if err != nil {
+		return err
+	}
+	actions := []spec.Action{}
+	if err := yaml.Unmarshal(contents, &actions); err != nil {
+		return fmt.Errorf("invalid workflow file %s: %w", c.WorkflowPath, err)
+	}
+	sum := sha256.Sum256(contents)
+	digest := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.actions != nil && c.results.Digest == digest {
+		return nil
+	}
+	for i := range actions {
+		if actions[i].ID == "" {
+			actions[i].ID = strconv.Itoa(i)
 		}
+		// The digest identifies the version of the workflow file an Action, and its events, belong to.
+		actions[i].WorkflowID = digest
+	}
+	results := Results{WorkflowPath: c.WorkflowPath, Digest: digest, Actions: make([]Result, len(actions))}
+	for i, a := range actions {
+		results.Actions[i] = Result{ID: a.ID, Name: a.Name, Reboot: a.Reboot}
+	}
+	if c.actions == nil {
+		results = resume(c.Log, c.ResultsPath, results)
+	} else {
+		c.Log.Info("workflow file changed, running the workflow from its first action", "workflow", c.WorkflowPath)
 	}
+	c.actions = actions
+	c.results = results
+	c.notify()
 
-	return nil
+	return c.writeResults()
 }
 
+// resume returns the results in the results file when they are for the same workflow file, so that the
+// workflow continues after its last successful Action. An Action that reboots the worker and was running is complete.
+func resume(log logr.Logger, path string, fresh Results) Results {
+	b, err := os.ReadFile(path) // #nosec G304 -- the results file is configured by the operator
+	if err != nil {
+		return fresh
+	}
+	var previous Results
+	if err := yaml.Unmarshal(b, &previous); err != nil || previous.Digest != fresh.Digest || len(previous.Actions) != len(fresh.Actions) {
+		return fresh
+	}
+	for i, r := range previous.Actions {
+		if r.State == spec.StateRunning && r.Reboot {
+			previous.Actions[i].State = spec.StateSuccess
+			previous.Actions[i].Message = "worker rebooted"
+		}
+	}
+	previous.State = workflowState(previous.Actions)
+	log.Info("resuming the workflow from the results file", "results", path, "state", previous.State)
+
+	return previous
+}
+
+// Read blocks until an Action is available and returns it. Actions are returned in order,
+// none are returned once an Action failed or all Actions succeeded, until the workflow file changes.
 func (c *Config) Read(ctx context.Context) (spec.Action, error) {
-	select {
-	case <-ctx.Done():
-		return spec.Action{}, context.Canceled
-	case v := <-c.Actions:
-		return v, nil
+	for {
+		c.mu.Lock()
+		if c.changed == nil {
+			c.changed = make(chan struct{})
+		}
+		a, ok := c.next()
+		changed := c.changed
+		c.mu.Unlock()
+		if ok {
+			return a, nil
+		}
+		select {
+		case <-ctx.Done():
+			return spec.Action{}, context.Canceled
+		case <-changed:
+		}
+	}
+}
+
+// next returns the first Action that has not succeeded, unless the workflow ended.
+func (c *Config) next() (spec.Action, bool) {
+	if terminal(c.results.State) {
+		return spec.Action{}, false
 	}
+	for i, r := range c.results.Actions {
+		if r.State != spec.StateSuccess {
+			return c.actions[i], true
+		}
+	}
+
+	return spec.Action{}, false
 }
 
+// Write records the result of an Action and writes the results file.
 func (c *Config) Write(_ context.Context, event spec.Event) error {
-	if event.State == spec.StateFailure || event.State == spec.StateTimeout {
-		c.Actions = make(chan spec.Action)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if event.Action.WorkflowID != c.results.Digest {
+		c.Log.Info("ignoring the event of an action of a previous version of the workflow file", "action", event.Action.Name)
+		return nil
+	}
+	for i := range c.results.Actions {
+		r := &c.results.Actions[i]
+		if r.ID != event.Action.ID {
+			continue
+		}
+		r.State = event.State
+		r.Message = event.Message
+		r.ExecutionStart = event.Action.ExecutionStart
+		r.ExecutionStop = event.Action.ExecutionStop
+		r.ExecutionDuration = event.Action.ExecutionDuration
+		r.Attempts = event.Attempts
+		c.results.State = workflowState(c.results.Actions)
+		c.notify()
+		return c.writeResults()
+	}
+
+	return fmt.Errorf("unknown action %q", event.Action.ID)
+}
+
+// notify wakes up Read calls waiting for an Action.
+func (c *Config) notify() {
+	if c.changed != nil {
+		close(c.changed)
+	}
+	c.changed = make(chan struct{})
+}
+
+// writeResults atomically replaces the results file.
+func (c *Config) writeResults() error {
+	b, err := yaml.Marshal(c.results)
+	if err != nil {
+		return err
 	}
-	return nil
+	f, err := os.CreateTemp(filepath.Dir(c.ResultsPath), ".results-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), c.ResultsPath)
+}
+
+// workflowState is the state of the first Action that failed, success when all Actions succeeded,
+// running once any Action ran and empty otherwise.
+func workflowState(results []Result) spec.State {
+	state := spec.State("")
+	succeeded := 0
+	for _, r := range results {
+		switch {
+		case failed(r.State):
+			return r.State
+		case r.State == spec.StateSuccess:
+			succeeded++
+			state = spec.StateRunning
+		case r.State != "":
+			state = spec.StateRunning
+		}
+	}
+	if succeeded == len(results) && len(results) > 0 {
+		return spec.StateSuccess
+	}
+
+	return state
+}
+
+// terminal returns true for the states that end a workflow.
+func terminal(s spec.State) bool {
+	return s == spec.StateSuccess || failed(s)
+}
+
+// failed returns true for the final states of an Action that failed.
+func failed(s spec.State) bool {
+	return s == spec.StateFailure || s == spec.StateTimeout || s == spec.StateVerificationFailed
 }
@@ -0,0 +1,214 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"sigs.k8s.io/yaml"
+)
+
+const workflow = `
+- id: one
+  name: one
+  image: alpine
+- name: two
+  image: alpine
+  reboot: true
+- id: three
+  name: three
+  image: alpine
+`
+
+func newConfig(t *testing.T, contents string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	p := filepath.Join(dir, "workflow.yaml")
+	if err := os.WriteFile(p, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &Config{Log: logr.Discard(), WorkflowPath: p, ResultsPath: filepath.Join(dir, "workflow.results.yaml")}
+}
+
+func read(t *testing.T, c *Config) (spec.Action, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	a, err := c.Read(ctx)
+	return a, err == nil
+}
+
+func readResults(t *testing.T, c *Config) Results {
+	t.Helper()
+	b, err := os.ReadFile(c.ResultsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Results
+	if err := yaml.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func states(r Results) []spec.State {
+	s := make([]spec.State, 0, len(r.Actions))
+	for _, a := range r.Actions {
+		s = append(s, a.State)
+	}
+	return s
+}
+
+func TestReadWrite(t *testing.T) {
+	tests := map[string]struct {
+		// results are the states of the Actions written, in order, to the transport.
+		results    []spec.State
+		wantNext   string
+		wantState  spec.State
+		wantStates []spec.State
+	}{
+		"first action":       {wantNext: "one", wantStates: []spec.State{"", "", ""}},
+		"running is retried": {results: []spec.State{spec.StateRunning}, wantNext: "one", wantState: spec.StateRunning, wantStates: []spec.State{spec.StateRunning, "", ""}},
+		"in order": {
+			results:    []spec.State{spec.StateSuccess},
+			wantNext:   "1",
+			wantState:  spec.StateRunning,
+			wantStates: []spec.State{spec.StateSuccess, "", ""},
+		},
+		"failure ends the workflow": {
+			results:    []spec.State{spec.StateSuccess, spec.StateFailure},
+			wantState:  spec.StateFailure,
+			wantStates: []spec.State{spec.StateSuccess, spec.StateFailure, ""},
+		},
+		"all succeeded": {
+			results:    []spec.State{spec.StateSuccess, spec.StateSuccess, spec.StateSuccess},
+			wantState:  spec.StateSuccess,
+			wantStates: []spec.State{spec.StateSuccess, spec.StateSuccess, spec.StateSuccess},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newConfig(t, workflow)
+			if err := c.load(); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.results {
+				a, ok := read(t, c)
+				if !ok {
+					t.Fatal("Read() returned no action")
+				}
+				if err := c.Write(context.Background(), spec.Event{Action: a, State: s, Message: string(s)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			a, ok := read(t, c)
+			if got := map[bool]string{true: a.ID}[ok]; got != tt.wantNext {
+				t.Errorf("Read() = %q, want %q", got, tt.wantNext)
+			}
+			r := readResults(t, c)
+			if r.State != tt.wantState {
+				t.Errorf("workflow state = %q, want %q", r.State, tt.wantState)
+			}
+			if diff := cmp.Diff(tt.wantStates, states(r)); diff != "" {
+				t.Errorf("unexpected action states (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	tests := map[string]struct {
+		previous   []spec.State
+		changed    bool
+		wantNext   string
+		wantStates []spec.State
+	}{
+		"after the last success":      {previous: []spec.State{spec.StateSuccess, spec.StateSuccess, ""}, wantNext: "three", wantStates: []spec.State{spec.StateSuccess, spec.StateSuccess, ""}},
+		"reboot action completed":     {previous: []spec.State{spec.StateSuccess, spec.StateRunning, ""}, wantNext: "three", wantStates: []spec.State{spec.StateSuccess, spec.StateSuccess, ""}},
+		"running action is run again": {previous: []spec.State{spec.StateRunning, "", ""}, wantNext: "one", wantStates: []spec.State{spec.StateRunning, "", ""}},
+		"workflow file changed":       {previous: []spec.State{spec.StateSuccess, spec.StateSuccess, ""}, changed: true, wantNext: "one", wantStates: []spec.State{"", "", ""}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newConfig(t, workflow)
+			if err := c.load(); err != nil {
+				t.Fatal(err)
+			}
+			for i, s := range tt.previous {
+				if s == "" {
+					continue
+				}
+				if err := c.Write(context.Background(), spec.Event{Action: c.actions[i], State: s}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.changed {
+				if err := os.WriteFile(c.WorkflowPath, []byte(workflow+"\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// A new transport reads the results file, as the agent does when it restarts.
+			c = &Config{Log: logr.Discard(), WorkflowPath: c.WorkflowPath, ResultsPath: c.ResultsPath}
+			if err := c.load(); err != nil {
+				t.Fatal(err)
+			}
+			a, _ := read(t, c)
+			if a.ID != tt.wantNext {
+				t.Errorf("Read() = %q, want %q", a.ID, tt.wantNext)
+			}
+			if diff := cmp.Diff(tt.wantStates, states(readResults(t, c))); diff != "" {
+				t.Errorf("unexpected action states (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	c := newConfig(t, workflow)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.Start(ctx) }()
+
+	rctx, rcancel := context.WithTimeout(ctx, 5*time.Second)
+	defer rcancel()
+	old, err := c.Read(rctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Write(ctx, spec.Event{Action: old, State: spec.StateFailure}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := read(t, c); ok {
+		t.Fatal("Read() returned an action after the workflow failed")
+	}
+
+	if err := os.WriteFile(c.WorkflowPath, []byte("- id: new\n  name: new\n  image: alpine\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.Read(rctx)
+	if err != nil {
+		t.Fatalf("Read() after the workflow file changed: %v", err)
+	}
+	if a.ID != "new" {
+		t.Errorf("Read() = %q, want %q", a.ID, "new")
+	}
+	// Events of Actions of the previous workflow file are not recorded.
+	if err := c.Write(ctx, spec.Event{Action: old, State: spec.StateSuccess}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]spec.State{""}, states(readResults(t, c))); diff != "" {
+		t.Errorf("unexpected action states (-want +got):\n%s", diff)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+}
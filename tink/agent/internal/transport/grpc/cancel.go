@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WatchCancel blocks until the Tink server tells the worker to stop the action, because its Workflow was canceled,
+// and returns the reason. Errors are retried every RetryInterval until the context is done.
+// When the Tink server doesn't support canceling Actions it blocks until the context is done.
+func (c *Config) WatchCancel(ctx context.Context, action spec.Action) (string, error) {
+	req := &proto.ActionCancelRequest{
+		WorkflowId: toPtr(action.WorkflowID),
+		WorkerId:   toPtr(c.WorkerID),
+		TaskId:     toPtr(action.TaskID),
+		ActionId:   toPtr(action.ID),
+	}
+	for !c.cancelUnsupported.Load() {
+		reason, err := c.doWatchCancel(ctx, req)
+		if err == nil {
+			return reason, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if status.Code(err) == codes.Unimplemented {
+			c.Log.Info("Tink server does not support canceling Actions")
+			c.cancelUnsupported.Store(true)
+			break
+		}
+		c.Log.Info("error watching for action cancellation, will retry", "error", err)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(c.RetryInterval):
+		}
+	}
+	<-ctx.Done()
+
+	return "", ctx.Err()
+}
+
+func (c *Config) doWatchCancel(ctx context.Context, req *proto.ActionCancelRequest) (string, error) {
+	stream, err := c.TinkServerClient.WatchActionCancel(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("error watching for action cancellation: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return "", fmt.Errorf("error receiving action cancellation: %w", err)
+	}
+
+	return resp.GetMessage(), nil
+}
@@ -2,20 +2,23 @@ package grpc
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v5"
 	"github.com/go-logr/logr"
 	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"github.com/tinkerbell/tinkerbell/pkg/workertoken"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -27,12 +30,20 @@ type Config struct {
 	Actions          chan spec.Action
 	Attributes       *proto.WorkerAttributes
 	RetryOptions     []backoff.RetryOption
+	// StreamActions enables receiving Actions over the StreamActions RPC instead of polling GetAction.
+	// Falls back to polling when the Tink server doesn't implement StreamActions.
+	StreamActions bool
+	// BootID is the boot ID of the worker. It is sent with Action requests and reports so that the Tink server
+	// knows when the worker rebooted.
+	BootID string
+
+	stream            grpc.ServerStreamingClient[proto.ActionResponse]
+	streamUnsupported bool
+	// cancelUnsupported is set once the Tink server returned Unimplemented for WatchActionCancel.
+	cancelUnsupported atomic.Bool
 }
 
 func (c *Config) Read(ctx context.Context) (spec.Action, error) {
-	operation := func() (spec.Action, error) {
-		return c.doRead(ctx)
-	}
 	opts := c.RetryOptions
 	if len(opts) == 0 {
 		opts = []backoff.RetryOption{
@@ -40,6 +51,28 @@ func (c *Config) Read(ctx context.Context) (spec.Action, error) {
 			backoff.WithBackOff(backoff.NewExponentialBackOff()),
 		}
 	}
+	if c.StreamActions && !c.streamUnsupported {
+		operation := func() (spec.Action, error) {
+			as, err := c.doReadStream(ctx)
+			if status.Code(err) == codes.Unimplemented {
+				return as, backoff.Permanent(err)
+			}
+			return as, err
+		}
+		resp, err := backoff.Retry(ctx, operation, opts...)
+		if err == nil {
+			return resp, nil
+		}
+		if status.Code(err) != codes.Unimplemented {
+			return spec.Action{}, err
+		}
+		c.Log.Info("Tink server does not support streaming Actions, falling back to polling")
+		c.streamUnsupported = true
+	}
+
+	operation := func() (spec.Action, error) {
+		return c.doRead(ctx)
+	}
 	resp, err := backoff.Retry(ctx, operation, opts...)
 	if err != nil {
 		return spec.Action{}, err
@@ -48,11 +81,34 @@ func (c *Config) Read(ctx context.Context) (spec.Action, error) {
 }
 
 func (c *Config) doRead(ctx context.Context) (spec.Action, error) {
-	response, err := c.TinkServerClient.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr(c.WorkerID), WorkerAttributes: c.Attributes})
+	response, err := c.TinkServerClient.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr(c.WorkerID), WorkerAttributes: c.Attributes, BootId: toPtr(c.BootID)})
 	if err != nil {
 		return spec.Action{}, fmt.Errorf("error getting action: %w", err)
 	}
 
+	return toSpecAction(response), nil
+}
+
+// doReadStream receives the next Action pushed by the Tink server.
+// The stream is opened on first use and reopened on the next call after an error.
+func (c *Config) doReadStream(ctx context.Context) (spec.Action, error) {
+	if c.stream == nil {
+		stream, err := c.TinkServerClient.StreamActions(ctx, &proto.ActionRequest{WorkerId: toPtr(c.WorkerID), WorkerAttributes: c.Attributes, BootId: toPtr(c.BootID)})
+		if err != nil {
+			return spec.Action{}, fmt.Errorf("error opening action stream: %w", err)
+		}
+		c.stream = stream
+	}
+	response, err := c.stream.Recv()
+	if err != nil {
+		c.stream = nil
+		return spec.Action{}, fmt.Errorf("error receiving action: %w", err)
+	}
+
+	return toSpecAction(response), nil
+}
+
+func toSpecAction(response *proto.ActionResponse) spec.Action {
 	as := spec.Action{
 		TaskID:         response.GetTaskId(),
 		ID:             response.GetActionId(),
@@ -63,7 +119,8 @@ func (c *Config) doRead(ctx context.Context) (spec.Action, error) {
 		Env:            []spec.Env{},
 		Volumes:        []spec.Volume{},
 		Namespaces:     spec.Namespaces{},
-		Retries:        0,
+		Retries:        int(response.GetRetries()),
+		BackoffSeconds: int(response.GetBackoff()),
 		TimeoutSeconds: int(response.GetTimeout()),
 	}
 	if len(response.GetCommand()) > 0 {
@@ -103,8 +160,14 @@ func (c *Config) doRead(ctx context.Context) (spec.Action, error) {
 		as.Env = append(as.Env, env)
 	}
 	as.Namespaces.PID = response.GetPid()
+	as.Namespaces.Network = response.GetNetwork()
+	as.UpcomingImages = response.GetUpcomingImages()
+	as.Reboot = response.GetReboot()
+	if p := response.GetImagePolicy(); p != nil {
+		as.ImagePolicy = &spec.ImagePolicy{PublicKeys: p.GetPublicKeys(), Digests: p.GetDigests()}
+	}
 
-	return as, nil
+	return as
 }
 
 func (c *Config) Write(ctx context.Context, event spec.Event) error {
@@ -137,6 +200,16 @@ func (c *Config) doWrite(ctx context.Context, event spec.Event) error {
 		ExecutionStop:     timestamppb.New(event.Action.ExecutionStop),
 		ExecutionDuration: toPtr(event.Action.ExecutionDuration),
 		Message:           &proto.ActionMessage{Message: toPtr(event.Message)},
+		BootId:            toPtr(c.BootID),
+	}
+	for _, a := range event.Attempts {
+		ar.Attempts = append(ar.Attempts, &proto.ActionAttempt{
+			Attempt:        toPtr(int64(a.Attempt)),
+			State:          specToProto(a.State),
+			ExecutionStart: timestamppb.New(a.ExecutionStart),
+			ExecutionStop:  timestamppb.New(a.ExecutionStop),
+			Message:        toPtr(a.Message),
+		})
 	}
 	_, err := c.TinkServerClient.ReportActionStatus(ctx, ar)
 	if err != nil {
@@ -146,18 +219,27 @@ func (c *Config) doWrite(ctx context.Context, event spec.Event) error {
 	return nil
 }
 
-func NewClientConn(authority string, tlsEnabled bool, tlsInsecure bool) (*grpc.ClientConn, error) {
+// NewClientConn creates a client connection to the Tink server.
+// When workerToken is not empty it is sent with every request to authenticate the worker.
+func NewClientConn(authority string, t TLS, workerToken string) (*grpc.ClientConn, error) {
 	if authority == "" {
 		return nil, errors.New("the Tinkerbell server address is required, none provided")
 	}
-	var creds grpc.DialOption
-	if tlsEnabled { // #nosec G402
-		creds = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: tlsInsecure}))
-	} else {
-		creds = grpc.WithTransportCredentials(insecure.NewCredentials())
+	creds := grpc.WithTransportCredentials(insecure.NewCredentials())
+	if t.enabled() {
+		tc, err := t.config()
+		if err != nil {
+			return nil, err
+		}
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tc))
+	}
+
+	opts := []grpc.DialOption{creds, grpc.WithStatsHandler(otelgrpc.NewClientHandler())}
+	if workerToken != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: workerToken, requireTLS: t.enabled()}))
 	}
 
-	conn, err := grpc.NewClient(authority, creds, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	conn, err := grpc.NewClient(authority, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("dial tinkerbell server: %w", err)
 	}
@@ -165,6 +247,20 @@ func NewClientConn(authority string, tlsEnabled bool, tlsInsecure bool) (*grpc.C
 	return conn, nil
 }
 
+// tokenCredentials sends a worker token in the metadata of every request.
+type tokenCredentials struct {
+	token      string
+	requireTLS bool
+}
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{workertoken.MetadataKey: t.token}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
+
 func specToProto(inState spec.State) *proto.StateType {
 	switch inState {
 	case spec.StateRunning:
@@ -175,6 +271,10 @@ func specToProto(inState spec.State) *proto.StateType {
 		return toPtr(proto.StateType_FAILED)
 	case spec.StateTimeout:
 		return toPtr(proto.StateType_TIMEOUT)
+	case spec.StateVerificationFailed:
+		return toPtr(proto.StateType_VERIFICATION_FAILED)
+	case spec.StateCanceled:
+		return toPtr(proto.StateType_CANCELED)
 	default:
 		return toPtr(proto.StateType_UNSPECIFIED)
 	}
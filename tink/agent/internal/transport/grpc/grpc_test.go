@@ -4,17 +4,25 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/cenkalti/backoff/v5"
+	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
 	"github.com/tinkerbell/tinkerbell/pkg/proto"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 type mockWorkflowServiceClient struct {
 	GetActionFunc          func(ctx context.Context, req *proto.ActionRequest) (*proto.ActionResponse, error)
 	ReportActionStatusFunc func(ctx context.Context, req *proto.ActionStatusRequest) (*proto.ActionStatusResponse, error)
+	StreamActionLogsFunc   func(ctx context.Context) (grpc.ClientStreamingClient[proto.ActionLogRequest, proto.ActionLogResponse], error)
+	StreamActionsFunc      func(ctx context.Context, req *proto.ActionRequest) (grpc.ServerStreamingClient[proto.ActionResponse], error)
+	WatchActionCancelFunc  func(ctx context.Context, req *proto.ActionCancelRequest) (grpc.ServerStreamingClient[proto.ActionCancelResponse], error)
 }
 
 func (m *mockWorkflowServiceClient) GetAction(ctx context.Context, req *proto.ActionRequest, _ ...grpc.CallOption) (*proto.ActionResponse, error) {
@@ -25,6 +33,49 @@ func (m *mockWorkflowServiceClient) ReportActionStatus(ctx context.Context, req
 	return m.ReportActionStatusFunc(ctx, req)
 }
 
+func (m *mockWorkflowServiceClient) StreamActionLogs(ctx context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[proto.ActionLogRequest, proto.ActionLogResponse], error) {
+	return m.StreamActionLogsFunc(ctx)
+}
+
+func (m *mockWorkflowServiceClient) StreamActions(ctx context.Context, req *proto.ActionRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[proto.ActionResponse], error) {
+	return m.StreamActionsFunc(ctx, req)
+}
+
+func (m *mockWorkflowServiceClient) WatchActionCancel(ctx context.Context, req *proto.ActionCancelRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[proto.ActionCancelResponse], error) {
+	return m.WatchActionCancelFunc(ctx, req)
+}
+
+func (m *mockWorkflowServiceClient) CancelWorkflow(context.Context, *proto.CancelWorkflowRequest, ...grpc.CallOption) (*proto.CancelWorkflowResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "the agent doesn't cancel workflows")
+}
+
+// mockActionStream returns the responses, in order, followed by err.
+type mockActionStream struct {
+	grpc.ClientStream
+	responses []*proto.ActionResponse
+	err       error
+}
+
+func (m *mockActionStream) Recv() (*proto.ActionResponse, error) {
+	if len(m.responses) == 0 {
+		return nil, m.err
+	}
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return resp, nil
+}
+
+// mockCancelStream returns resp, or err when it is set.
+type mockCancelStream struct {
+	grpc.ClientStream
+	resp *proto.ActionCancelResponse
+	err  error
+}
+
+func (m *mockCancelStream) Recv() (*proto.ActionCancelResponse, error) {
+	return m.resp, m.err
+}
+
 var errTest = errors.New("failed to get action")
 
 func TestRead(t *testing.T) {
@@ -55,7 +106,8 @@ func TestRead(t *testing.T) {
 				},
 				Volumes:        []spec.Volume{"/var/lib:/var/lib"},
 				Namespaces:     spec.Namespaces{},
-				Retries:        0,
+				Retries:        2,
+				BackoffSeconds: 5,
 				TimeoutSeconds: 60,
 			},
 			protoResponse: &proto.ActionResponse{
@@ -66,6 +118,8 @@ func TestRead(t *testing.T) {
 				Name:       toPtr("first action"),
 				Image:      toPtr("alpine"),
 				Timeout:    toPtr(int64(60)),
+				Retries:    toPtr(int64(2)),
+				Backoff:    toPtr(int64(5)),
 				Command:    []string{"sleep", "5"},
 				Volumes:    []string{"/var/lib:/var/lib"},
 				Environment: []string{
@@ -116,6 +170,81 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestReadStream(t *testing.T) {
+	tests := map[string]struct {
+		stream        *mockActionStream
+		getAction     *proto.ActionResponse
+		expectedIDs   []string
+		expectedError error
+		wantPolling   bool
+	}{
+		"pushed actions": {
+			stream: &mockActionStream{
+				responses: []*proto.ActionResponse{{ActionId: toPtr("1")}, {ActionId: toPtr("2")}},
+			},
+			expectedIDs: []string{"1", "2"},
+		},
+		"stream error": {
+			stream:        &mockActionStream{err: errTest},
+			expectedError: errTest,
+		},
+		"server without streaming falls back to polling": {
+			stream:      &mockActionStream{err: status.Error(codes.Unimplemented, "method StreamActions not implemented")},
+			getAction:   &proto.ActionResponse{ActionId: toPtr("polled")},
+			expectedIDs: []string{"polled", "polled"},
+			wantPolling: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opened := 0
+			mockClient := &mockWorkflowServiceClient{
+				StreamActionsFunc: func(_ context.Context, req *proto.ActionRequest) (grpc.ServerStreamingClient[proto.ActionResponse], error) {
+					if req.GetWorkerId() != "worker-123" {
+						t.Errorf("expected worker id worker-123, got: %v", req.GetWorkerId())
+					}
+					opened++
+					return test.stream, nil
+				},
+				GetActionFunc: func(_ context.Context, _ *proto.ActionRequest) (*proto.ActionResponse, error) {
+					return test.getAction, nil
+				},
+			}
+			config := &Config{
+				TinkServerClient: mockClient,
+				WorkerID:         "worker-123",
+				StreamActions:    true,
+				RetryOptions: []backoff.RetryOption{
+					backoff.WithMaxTries(1),
+				},
+			}
+
+			if test.expectedError != nil {
+				if _, err := config.Read(context.Background()); !errors.Is(err, test.expectedError) {
+					t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+				}
+				return
+			}
+			for _, want := range test.expectedIDs {
+				got, err := config.Read(context.Background())
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				if got.ID != want {
+					t.Errorf("expected action id: %v, got: %v", want, got.ID)
+				}
+			}
+			if opened != 1 {
+				t.Errorf("expected the stream to be opened once, got: %d", opened)
+			}
+			if config.streamUnsupported != test.wantPolling {
+				t.Errorf("expected polling: %v, got: %v", test.wantPolling, config.streamUnsupported)
+			}
+		})
+	}
+}
+
 func TestWrite(t *testing.T) {
 	tests := map[string]struct {
 		expectedError error
@@ -176,7 +305,7 @@ func TestNewClientConn(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			conn, err := NewClientConn(test.address, false, false)
+			conn, err := NewClientConn(test.address, TLS{}, "")
 			if test.wantErr {
 				if err == nil {
 					t.Fatalf("expected error, got nil")
@@ -192,3 +321,57 @@ func TestNewClientConn(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchCancel(t *testing.T) {
+	tests := map[string]struct {
+		streams    []*mockCancelStream
+		wantReason string
+		wantErr    error
+	}{
+		"canceled": {
+			streams:    []*mockCancelStream{{resp: &proto.ActionCancelResponse{Message: toPtr("workflow canceled")}}},
+			wantReason: "workflow canceled",
+		},
+		"error is retried": {
+			streams:    []*mockCancelStream{{err: errTest}, {resp: &proto.ActionCancelResponse{Message: toPtr("workflow canceled")}}},
+			wantReason: "workflow canceled",
+		},
+		"server without cancellation blocks until the context is done": {
+			streams: []*mockCancelStream{{err: status.Error(codes.Unimplemented, "method WatchActionCancel not implemented")}},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opened := 0
+			mockClient := &mockWorkflowServiceClient{
+				WatchActionCancelFunc: func(_ context.Context, req *proto.ActionCancelRequest) (grpc.ServerStreamingClient[proto.ActionCancelResponse], error) {
+					want := &proto.ActionCancelRequest{WorkflowId: toPtr("default/wf"), WorkerId: toPtr("worker-123"), TaskId: toPtr("task"), ActionId: toPtr("action")}
+					if !protobuf.Equal(want, req) {
+						t.Errorf("expected request %v, got: %v", want, req)
+					}
+					if opened >= len(test.streams) {
+						t.Fatalf("unexpected call %d", opened+1)
+					}
+					opened++
+					return test.streams[opened-1], nil
+				},
+			}
+			config := &Config{TinkServerClient: mockClient, WorkerID: "worker-123", Log: logr.Discard()}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			reason, err := config.WatchCancel(ctx, spec.Action{WorkflowID: "default/wf", TaskID: "task", ID: "action"})
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("expected error: %v, got: %v", test.wantErr, err)
+			}
+			if reason != test.wantReason {
+				t.Errorf("expected reason: %q, got: %q", test.wantReason, reason)
+			}
+			if opened != len(test.streams) {
+				t.Errorf("expected %d calls, got: %d", len(test.streams), opened)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"google.golang.org/grpc"
+)
+
+// maxLogLineLength is the max number of bytes buffered while waiting for a new line.
+// Output without new lines, progress bars for example, is sent once this many bytes are buffered.
+// A character split across writes is kept in the buffer until it is complete.
+const maxLogLineLength = 4096
+
+// LogWriter returns a writer that streams the output of an Action to the Tink server, line by line.
+// The writer must be closed once the Action has completed.
+func (c *Config) LogWriter(ctx context.Context, action spec.Action) (io.WriteCloser, error) {
+	stream, err := c.TinkServerClient.StreamActionLogs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error opening action log stream: %w", err)
+	}
+
+	return &logWriter{
+		log:    c.Log.WithValues("actionID", action.ID),
+		stream: stream,
+		action: action,
+	}, nil
+}
+
+// logWriter sends complete lines of Action output to the Tink server.
+// Errors sending to the server are logged and never returned so that the Action is not affected by them.
+type logWriter struct {
+	log    logr.Logger
+	stream grpc.ClientStreamingClient[proto.ActionLogRequest, proto.ActionLogResponse]
+	action spec.Action
+
+	mu     sync.Mutex
+	buf    []byte
+	failed bool
+}
+
+func (l *logWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	lines := []string{}
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, string(bytes.TrimSuffix(l.buf[:i], []byte("\r"))))
+		l.buf = l.buf[i+1:]
+	}
+	if len(l.buf) > maxLogLineLength {
+		n := incompleteRuneStart(l.buf)
+		lines = append(lines, string(l.buf[:n]))
+		l.buf = append([]byte(nil), l.buf[n:]...)
+	}
+	l.send(lines)
+
+	return len(p), nil
+}
+
+// Close sends any buffered output and closes the stream.
+func (l *logWriter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) > 0 {
+		l.send([]string{string(l.buf)})
+		l.buf = nil
+	}
+	if _, err := l.stream.CloseAndRecv(); err != nil && !l.failed {
+		return fmt.Errorf("error closing action log stream: %w", err)
+	}
+
+	return nil
+}
+
+// send sends lines to the Tink server. Lines are sent as proto strings, which must be valid UTF-8,
+// so invalid UTF-8 in the output of an Action is replaced.
+func (l *logWriter) send(lines []string) {
+	if l.failed || len(lines) == 0 {
+		return
+	}
+	for i, line := range lines {
+		lines[i] = strings.ToValidUTF8(line, string(utf8.RuneError))
+	}
+	err := l.stream.Send(&proto.ActionLogRequest{
+		WorkflowId: toPtr(l.action.WorkflowID),
+		WorkerId:   toPtr(l.action.WorkerID),
+		TaskId:     toPtr(l.action.TaskID),
+		ActionId:   toPtr(l.action.ID),
+		Lines:      lines,
+	})
+	if err != nil {
+		// Once a send fails the stream is unusable, so stop trying.
+		l.failed = true
+		l.log.Info("error sending action output, no further output will be sent", "error", err)
+	}
+}
+
+// incompleteRuneStart returns the index of the incomplete UTF-8 character at the end of b, len(b) when there is none.
+func incompleteRuneStart(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return len(b)
+			}
+			return i
+		}
+	}
+	return len(b)
+}
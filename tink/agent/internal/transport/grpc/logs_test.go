@@ -0,0 +1,135 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"google.golang.org/grpc"
+)
+
+// mockLogStream records the lines of each request it is sent.
+type mockLogStream struct {
+	grpc.ClientStream
+	sent     [][]string
+	reqs     []*proto.ActionLogRequest
+	sendErr  error
+	closeErr error
+}
+
+func (m *mockLogStream) Send(req *proto.ActionLogRequest) error {
+	m.reqs = append(m.reqs, req)
+	if m.sendErr != nil {
+		return m.sendErr
+	}
+	m.sent = append(m.sent, req.GetLines())
+	return nil
+}
+
+func (m *mockLogStream) CloseAndRecv() (*proto.ActionLogResponse, error) {
+	return &proto.ActionLogResponse{}, m.closeErr
+}
+
+func TestLogWriter(t *testing.T) {
+	long := strings.Repeat("x", maxLogLineLength+1)
+	tests := map[string]struct {
+		writes    []string
+		sendErr   error
+		closeErr  error
+		wantSent  [][]string
+		wantSends int
+		wantErr   bool
+	}{
+		"complete lines": {
+			writes:    []string{"line 1\nline 2\r\n"},
+			wantSent:  [][]string{{"line 1", "line 2"}},
+			wantSends: 1,
+		},
+		"lines split across writes": {
+			writes:    []string{"li", "ne 1\nline", " 2\n"},
+			wantSent:  [][]string{{"line 1"}, {"line 2"}},
+			wantSends: 2,
+		},
+		"partial line sent on close": {
+			writes:    []string{"line 1\nline 2"},
+			wantSent:  [][]string{{"line 1"}, {"line 2"}},
+			wantSends: 2,
+		},
+		"long output without new lines": {
+			writes:    []string{long},
+			wantSent:  [][]string{{long}},
+			wantSends: 1,
+		},
+		"long output split in a character": {
+			writes:    []string{strings.Repeat("x", maxLogLineLength) + "\xc3", "\xa9\n"},
+			wantSent:  [][]string{{strings.Repeat("x", maxLogLineLength)}, {"é"}},
+			wantSends: 2,
+		},
+		"invalid UTF-8": {
+			writes:    []string{"line \xff1\n"},
+			wantSent:  [][]string{{"line \uFFFD1"}},
+			wantSends: 1,
+		},
+		"send failure stops sending": {
+			writes:    []string{"line 1\n", "line 2\n", "line 3"},
+			sendErr:   errors.New("stream closed"),
+			wantSends: 1,
+		},
+		"close error": {
+			writes:    []string{"line 1\n"},
+			closeErr:  errors.New("stream closed"),
+			wantSent:  [][]string{{"line 1"}},
+			wantSends: 1,
+			wantErr:   true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			stream := &mockLogStream{sendErr: tt.sendErr, closeErr: tt.closeErr}
+			c := &Config{
+				Log: logr.Discard(),
+				TinkServerClient: &mockWorkflowServiceClient{
+					StreamActionLogsFunc: func(context.Context) (grpc.ClientStreamingClient[proto.ActionLogRequest, proto.ActionLogResponse], error) {
+						return stream, nil
+					},
+				},
+			}
+			action := spec.Action{ID: "action1", TaskID: "task1", WorkflowID: "default/workflow1", WorkerID: "worker1"}
+			w, err := c.LogWriter(context.Background(), action)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				if err != nil {
+					t.Fatalf("unexpected write error: %v", err)
+				}
+				if n != len(s) {
+					t.Fatalf("expected %d bytes written, got: %d", len(s), n)
+				}
+			}
+			if err := w.Close(); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+
+			if diff := cmp.Diff(tt.wantSent, stream.sent); diff != "" {
+				t.Errorf("unexpected lines sent (-want +got):\n%s", diff)
+			}
+			if len(stream.reqs) != tt.wantSends {
+				t.Fatalf("expected %d sends, got: %d", tt.wantSends, len(stream.reqs))
+			}
+			for _, req := range stream.reqs {
+				got := []string{req.GetWorkflowId(), req.GetTaskId(), req.GetActionId(), req.GetWorkerId()}
+				want := []string{action.WorkflowID, action.TaskID, action.ID, action.WorkerID}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("unexpected action of request (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLS configures the connection to the Tink server.
+type TLS struct {
+	// Enabled uses TLS to connect to the Tink server.
+	// TLS is also used when any of CAFile, CA, CertFile or ServerName is set.
+	Enabled bool
+	// Insecure skips verifying the certificate of the Tink server.
+	Insecure bool
+	// CAFile is the path to a PEM encoded CA bundle used to verify the Tink server.
+	// The system roots are used when neither CAFile nor CA is set.
+	CAFile string
+	// CA is a base64 encoded CA bundle used to verify the Tink server. The bundle can be PEM
+	// or concatenated DER certificates. This form is used to pass a CA on the kernel command line.
+	CA string
+	// CertFile and KeyFile are the paths to a PEM encoded client certificate and key presented to the Tink server (mTLS).
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name used to verify the certificate of the Tink server.
+	ServerName string
+}
+
+func (t TLS) enabled() bool {
+	return t.Enabled || t.CAFile != "" || t.CA != "" || t.CertFile != "" || t.ServerName != ""
+}
+
+// config returns the tls.Config for connecting to the Tink server.
+func (t TLS) config() (*tls.Config, error) {
+	tc := &tls.Config{
+		InsecureSkipVerify: t.Insecure, // #nosec G402 -- opt-in for Tink servers with self-signed certificates.
+		ServerName:         t.ServerName,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if t.CAFile != "" || t.CA != "" {
+		pool := x509.NewCertPool()
+		if t.CAFile != "" {
+			b, err := os.ReadFile(t.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("no PEM encoded certificates found in CA file %q", t.CAFile)
+			}
+		}
+		if t.CA != "" {
+			if err := appendEncodedCerts(pool, t.CA); err != nil {
+				return nil, err
+			}
+		}
+		tc.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate and key: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	return tc, nil
+}
+
+// appendEncodedCerts adds the certificates of a base64 encoded PEM or DER CA bundle to a pool.
+func appendEncodedCerts(pool *x509.CertPool, encoded string) error {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("unable to decode CA: %w", err)
+	}
+	if bytes.Contains(b, []byte("-----BEGIN")) {
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.New("no PEM encoded certificates found in CA")
+		}
+		return nil
+	}
+	certs, err := x509.ParseCertificates(b)
+	if err != nil {
+		return fmt.Errorf("unable to parse CA: %w", err)
+	}
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+
+	return nil
+}
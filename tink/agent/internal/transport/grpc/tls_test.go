@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLSConfig(t *testing.T) {
+	der := selfSignedCert(t)
+	pemCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pemCA, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	want, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		tls     TLS
+		wantCA  bool
+		wantErr bool
+	}{
+		"system roots":  {tls: TLS{Enabled: true}},
+		"ca file":       {tls: TLS{CAFile: caFile}, wantCA: true},
+		"base64 pem ca": {tls: TLS{CA: base64.StdEncoding.EncodeToString(pemCA)}, wantCA: true},
+		"base64 der ca": {tls: TLS{CA: base64.StdEncoding.EncodeToString(der)}, wantCA: true},
+		"invalid ca":    {tls: TLS{CA: "not base64!"}, wantErr: true},
+		"missing file":  {tls: TLS{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: true},
+		"missing key":   {tls: TLS{CertFile: caFile}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if !tt.tls.enabled() {
+				t.Fatal("expected TLS to be enabled")
+			}
+			got, err := tt.tls.config()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantCA {
+				if got.RootCAs != nil {
+					t.Fatal("expected system roots")
+				}
+				return
+			}
+			if _, err := want.Verify(x509.VerifyOptions{Roots: got.RootCAs}); err != nil {
+				t.Fatalf("expected CA in root pool: %v", err)
+			}
+		})
+	}
+}
+
+func selfSignedCert(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tinkerbell test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
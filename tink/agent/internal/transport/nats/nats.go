@@ -1,19 +1,40 @@
+// Package nats is a transport that receives Actions from, and publishes events to, a NATS server.
+//
+// When JetStream is enabled Actions are read from a durable consumer of the worker, so Actions published while the
+// agent is offline are delivered when it connects. A message is acknowledged once the results of its Actions are
+// reported, a message that is not acknowledged, for example because the agent crashed, is delivered again and its
+// Actions are run again from the first one. Events are published as versioned JSON, see Event.
+// The agent falls back to a core NATS subscription, and to text events, when the server has no JetStream or no stream
+// named StreamName.
+// The Tink server tells the agent to stop the running Action of a canceled Workflow with a Cancellation published on the
+// core NATS subject "<stream>.<agent ID>.<actions subject>.cancel".
 package nats
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/netip"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/go-logr/logr"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
-	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
 )
 
+// EventVersion is the version of the JSON events published in JetStream mode.
+const EventVersion = "v1"
+
+// DefaultAckWait is how long a message is waited on before it is delivered again, when AckWait is not set.
+// The agent extends it while the Actions of the message run.
+const DefaultAckWait = time.Minute
+
 type Config struct {
 	StreamName     string
 	EventsSubject  string
@@ -22,12 +43,58 @@ type Config struct {
 	Log            logr.Logger
 	AgentID        string
 	Actions        chan spec.Action
-	conn           *nats.Conn
-	cancel         chan bool
+	// JetStream enables reading Actions from a durable JetStream consumer and publishing JSON events.
+	JetStream bool
+	// AckWait is how long JetStream waits for a message to be acknowledged before delivering it again.
+	AckWait time.Duration
+	// BootID is the boot ID of the worker, it is published with events so that the Tink server knows when the worker rebooted.
+	BootID string
+
+	conn *nats.Conn
+	// js is set once Actions are read from JetStream, events are then published as JSON.
+	js jetstream.JetStream
+	mu sync.Mutex
+	// results receives the Actions with a final state that were reported.
+	results chan spec.Event
+}
+
+// Event is the JSON encoded event of an Action published in JetStream mode.
+type Event struct {
+	// Version is the version of the event format, EventVersion.
+	Version           string         `json:"version"`
+	AgentID           string         `json:"agentId"`
+	WorkflowID        string         `json:"workflowId,omitempty"`
+	TaskID            string         `json:"taskId,omitempty"`
+	ActionID          string         `json:"actionId"`
+	ActionName        string         `json:"actionName"`
+	State             spec.State     `json:"state"`
+	Message           string         `json:"message,omitempty"`
+	ExecutionStart    time.Time      `json:"executionStart,omitzero"`
+	ExecutionStop     time.Time      `json:"executionStop,omitzero"`
+	ExecutionDuration string         `json:"executionDuration,omitempty"`
+	Attempts          []spec.Attempt `json:"attempts,omitempty"`
+	// BootID is the boot ID of the worker. An event without an ActionID only announces it, one is published
+	// when the agent starts reading Actions from JetStream.
+	BootID string `json:"bootId,omitempty"`
+}
+
+// Cancellation is the JSON encoded message that tells the agent to stop a running Action.
+type Cancellation struct {
+	WorkflowID string `json:"workflowId,omitempty"`
+	TaskID     string `json:"taskId,omitempty"`
+	ActionID   string `json:"actionId"`
+	Message    string `json:"message,omitempty"`
 }
 
+// errFallback is returned when Actions can't be read from JetStream and a core NATS subscription should be used instead.
+var errFallback = errors.New("falling back to a core NATS subscription")
+
 func (c *Config) Start(ctx context.Context) error {
-	c.cancel = make(chan bool)
+	c.mu.Lock()
+	if c.results == nil {
+		c.results = make(chan spec.Event, 1)
+	}
+	c.mu.Unlock()
 	opts := []nats.Option{
 		nats.Name(c.AgentID),
 		nats.RetryOnFailedConnect(true),
@@ -67,6 +134,14 @@ func (c *Config) Start(ctx context.Context) error {
 
 	base := fmt.Sprintf("%v.%v", c.StreamName, c.AgentID)
 	subj := fmt.Sprintf("%v.%v", base, c.ActionsSubject)
+	if c.JetStream {
+		err := c.consume(ctx, nc, subj)
+		if !errors.Is(err, errFallback) {
+			return err
+		}
+		c.Log.Info("unable to read actions from JetStream, falling back to a core NATS subscription", "stream", c.StreamName, "error", err)
+	}
+
 	sub, err := nc.SubscribeSync(subj)
 	if err != nil {
 		return err
@@ -90,16 +165,115 @@ func (c *Config) Start(ctx context.Context) error {
 		if err := yaml.Unmarshal(msg.Data, &actions); err != nil {
 			continue
 		}
-		for _, action := range actions {
+		c.deliver(ctx, actions, nil)
+	}
+}
+
+// consume reads Actions from the durable JetStream consumer of the worker until the context is done.
+func (c *Config) consume(ctx context.Context, nc *nats.Conn, subj string) error {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFallback, err)
+	}
+	ackWait := c.AckWait
+	if ackWait <= 0 {
+		ackWait = DefaultAckWait
+	}
+	cons, err := js.CreateOrUpdateConsumer(ctx, c.StreamName, jetstream.ConsumerConfig{
+		Durable:       durableName(c.AgentID),
+		Description:   "Actions of the Tink agent " + c.AgentID,
+		FilterSubject: subj,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		// The Actions of a message run before the Actions of the next one.
+		MaxAckPending: 1,
+	})
+	if err != nil {
+		// A server without JetStream doesn't respond to the JetStream API.
+		if errors.Is(err, nats.ErrNoResponders) || errors.Is(err, jetstream.ErrStreamNotFound) ||
+			errors.Is(err, jetstream.ErrJetStreamNotEnabled) || errors.Is(err, jetstream.ErrJetStreamNotEnabledForAccount) {
+			return fmt.Errorf("%w: %w", errFallback, err)
+		}
+		return err
+	}
+	c.mu.Lock()
+	c.js = js
+	c.mu.Unlock()
+	c.Log.Info("reading actions from JetStream", "stream", c.StreamName, "consumer", durableName(c.AgentID))
+	if err := c.announceBoot(ctx, js); err != nil {
+		c.Log.Info("unable to publish the boot ID", "error", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		msg, err := cons.Next(jetstream.FetchMaxWait(5 * time.Second))
+		if err != nil {
+			if !errors.Is(err, nats.ErrTimeout) && !errors.Is(err, jetstream.ErrNoMessages) {
+				c.Log.Info("error reading actions from JetStream", "error", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+			continue
+		}
+		actions := []spec.Action{}
+		if err := yaml.Unmarshal(msg.Data(), &actions); err != nil {
+			c.Log.Info("discarding invalid actions message", "subject", msg.Subject(), "error", err)
+			_ = msg.Term()
+			continue
+		}
+		if md, err := msg.Metadata(); err == nil && md.NumDelivered > 1 {
+			c.Log.Info("actions message delivered again, running its actions from the first one", "deliveries", md.NumDelivered)
+		}
+		if !c.deliver(ctx, actions, msg) {
+			continue
+		}
+		if err := msg.DoubleAck(ctx); err != nil {
+			c.Log.Info("error acknowledging actions message, it will be delivered again", "error", err)
+		}
+	}
+}
+
+// deliver sends actions, in order, to Read until one of them fails or all of them succeed, and returns true once
+// the result of the last Action that ran was reported. An Action is sent again when Read is called before its result
+// was reported. msg, when not nil, is kept from being delivered again while the Actions run.
+func (c *Config) deliver(ctx context.Context, actions []spec.Action, msg jetstream.Msg) bool {
+	ackWait := c.AckWait
+	if ackWait <= 0 {
+		ackWait = DefaultAckWait
+	}
+	ticker := time.NewTicker(ackWait / 2)
+	defer ticker.Stop()
+	for _, action := range actions {
+	wait:
+		for {
 			select {
 			case <-ctx.Done():
-			case <-c.cancel:
+				return false
 			case c.Actions <- action:
-				continue
+			case <-ticker.C:
+				if msg != nil {
+					_ = msg.InProgress()
+				}
+			case e := <-c.results:
+				if e.Action.ID != action.ID {
+					continue
+				}
+				if e.State != spec.StateSuccess {
+					return true
+				}
+				break wait
 			}
-			break
 		}
 	}
+
+	return true
 }
 
 func (c *Config) Read(ctx context.Context) (spec.Action, error) {
@@ -111,13 +285,117 @@ func (c *Config) Read(ctx context.Context) (spec.Action, error) {
 	}
 }
 
-func (c *Config) Write(_ context.Context, event spec.Event) error {
-	if event.State == spec.StateFailure || event.State == spec.StateTimeout {
-		c.Actions = make(chan spec.Action)
-		c.cancel <- true
+func (c *Config) Write(ctx context.Context, event spec.Event) error {
+	subj := fmt.Sprintf("%v.%v.%v", c.StreamName, c.AgentID, c.EventsSubject)
+	c.mu.Lock()
+	js := c.js
+	results := c.results
+	c.mu.Unlock()
+	if js == nil {
+		if err := c.conn.PublishMsg(&nats.Msg{Subject: subj, Data: []byte(event.String())}); err != nil {
+			return err
+		}
+	} else if err := c.publish(ctx, js, subj, event); err != nil {
+		return err
+	}
+
+	if event.State == spec.StateRunning {
+		return nil
 	}
-	return c.conn.PublishMsg(&nats.Msg{
-		Subject: fmt.Sprintf("%v.%v.%v", c.StreamName, c.AgentID, c.EventsSubject),
-		Data:    []byte(event.String()),
+	select {
+	case results <- event:
+	default:
+	}
+
+	return nil
+}
+
+// publish publishes event as JSON, to JetStream when a stream holds subj.
+func (c *Config) publish(ctx context.Context, js jetstream.JetStream, subj string, event spec.Event) error {
+	b, err := json.Marshal(Event{
+		Version:           EventVersion,
+		AgentID:           c.AgentID,
+		WorkflowID:        event.Action.WorkflowID,
+		TaskID:            event.Action.TaskID,
+		ActionID:          event.Action.ID,
+		ActionName:        event.Action.Name,
+		State:             event.State,
+		Message:           event.Message,
+		ExecutionStart:    event.Action.ExecutionStart,
+		ExecutionStop:     event.Action.ExecutionStop,
+		ExecutionDuration: event.Action.ExecutionDuration,
+		Attempts:          event.Attempts,
+		BootID:            c.BootID,
 	})
+	if err != nil {
+		return err
+	}
+	msg := &nats.Msg{Subject: subj, Data: b, Header: nats.Header{}}
+	msg.Header.Set("Content-Type", "application/json")
+	if _, err := js.PublishMsg(ctx, msg); err != nil {
+		if !errors.Is(err, nats.ErrNoResponders) && !errors.Is(err, jetstream.ErrNoStreamResponse) {
+			return err
+		}
+		// No stream holds the events subject, the event is published without persistence.
+		return c.conn.PublishMsg(msg)
+	}
+
+	return nil
+}
+
+// announceBoot publishes an event, without an Action, with the boot ID of the worker. The Tink server completes the
+// running Action that rebooted the worker when the boot ID isn't the one the Action was reported running with.
+func (c *Config) announceBoot(ctx context.Context, js jetstream.JetStream) error {
+	if c.BootID == "" {
+		return nil
+	}
+	b, err := json.Marshal(Event{Version: EventVersion, AgentID: c.AgentID, BootID: c.BootID})
+	if err != nil {
+		return err
+	}
+	msg := &nats.Msg{Subject: fmt.Sprintf("%v.%v.%v", c.StreamName, c.AgentID, c.EventsSubject), Data: b, Header: nats.Header{}}
+	msg.Header.Set("Content-Type", "application/json")
+	_, err = js.PublishMsg(ctx, msg)
+
+	return err
+}
+
+// durableName returns the name of the durable consumer of a worker, names can't hold ".", "*", ">" or whitespace.
+func durableName(agentID string) string {
+	return "tink-agent-" + strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '/', '\\':
+			return '_'
+		}
+		return r
+	}, agentID)
+}
+
+// WatchCancel blocks until a Cancellation of the action is received and returns its message.
+func (c *Config) WatchCancel(ctx context.Context, action spec.Action) (string, error) {
+	if c.conn == nil {
+		return "", errors.New("not connected to NATS")
+	}
+	sub, err := c.conn.SubscribeSync(fmt.Sprintf("%v.%v.%v.cancel", c.StreamName, c.AgentID, c.ActionsSubject))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = sub.Unsubscribe()
+	}()
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		var cl Cancellation
+		if err := json.Unmarshal(msg.Data, &cl); err != nil {
+			c.Log.Info("discarding invalid cancellation message", "error", err)
+			continue
+		}
+		if cl.ActionID == action.ID && (cl.WorkflowID == "" || cl.WorkflowID == action.WorkflowID) {
+			return cl.Message, nil
+		}
+	}
 }
@@ -0,0 +1,279 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+)
+
+const actions = `[{"id": "one", "name": "one", "image": "alpine"}, {"id": "two", "name": "two", "image": "alpine"}]`
+
+// runServer runs a NATS server, with JetStream and a "tinkerbell" stream when jetStream is true.
+func runServer(t *testing.T, jetStream bool) (*server.Server, *nats.Conn) {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: jetStream, StoreDir: t.TempDir(), NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	if jetStream {
+		js, _ := jetstream.New(nc)
+		if _, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "tinkerbell", Subjects: []string{"tinkerbell.>"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, nc
+}
+
+func newConfig(t *testing.T, s *server.Server, jetStream bool) (*Config, context.CancelFunc) {
+	t.Helper()
+	addr := s.Addr().(*net.TCPAddr).AddrPort()
+	c := &Config{
+		StreamName:     "tinkerbell",
+		EventsSubject:  "workflow_status",
+		ActionsSubject: "workflow_actions",
+		IPPort:         addr,
+		Log:            logr.Discard(),
+		AgentID:        "00:00:5e:00:53:01",
+		Actions:        make(chan spec.Action),
+		JetStream:      jetStream,
+		AckWait:        time.Second,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := c.Start(ctx); err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	}()
+	return c, func() { cancel(); <-done }
+}
+
+func read(t *testing.T, c *Config) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return a.ID
+}
+
+func run(t *testing.T, c *Config, state spec.State) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	for _, s := range []spec.State{spec.StateRunning, state} {
+		if err := c.Write(ctx, spec.Event{Action: a, State: s}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	return a.ID
+}
+
+func TestJetStream(t *testing.T) {
+	s, nc := runServer(t, true)
+	events, err := nc.SubscribeSync("tinkerbell.00:00:5e:00:53:01.workflow_status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Actions are published while the agent is offline.
+	if err := nc.Publish("tinkerbell.00:00:5e:00:53:01.workflow_actions", []byte(actions)); err != nil {
+		t.Fatal(err)
+	}
+
+	c, stop := newConfig(t, s, true)
+	defer stop()
+	got := []string{run(t, c, spec.StateSuccess), run(t, c, spec.StateSuccess)}
+	if diff := cmp.Diff([]string{"one", "two"}, got); diff != "" {
+		t.Errorf("unexpected actions (-want +got):\n%s", diff)
+	}
+
+	msg, err := events.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e Event
+	if err := json.Unmarshal(msg.Data, &e); err != nil {
+		t.Fatalf("event is not JSON: %v", err)
+	}
+	want := Event{Version: EventVersion, AgentID: "00:00:5e:00:53:01", ActionID: "one", ActionName: "one", State: spec.StateRunning}
+	if diff := cmp.Diff(want, e); diff != "" {
+		t.Errorf("unexpected event (-want +got):\n%s", diff)
+	}
+
+	// The message is acknowledged once the results of its Actions are reported.
+	js, _ := jetstream.New(nc)
+	cons, err := js.Consumer(context.Background(), "tinkerbell", durableName(c.AgentID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err := cons.Info(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.AckFloor.Consumer == 1 && info.NumAckPending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("actions message was not acknowledged: %+v", info)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestJetStreamRedelivery(t *testing.T) {
+	s, nc := runServer(t, true)
+	if err := nc.Publish("tinkerbell.00:00:5e:00:53:01.workflow_actions", []byte(actions)); err != nil {
+		t.Fatal(err)
+	}
+
+	c, stop := newConfig(t, s, true)
+	if got := run(t, c, spec.StateSuccess); got != "one" {
+		t.Fatalf("Read() = %q, want %q", got, "one")
+	}
+	// The agent stops before the results of all the Actions of the message are reported.
+	stop()
+
+	c, stop = newConfig(t, s, true)
+	defer stop()
+	if got := read(t, c); got != "one" {
+		t.Errorf("Read() after redelivery = %q, want %q", got, "one")
+	}
+}
+
+func TestFailureEndsMessage(t *testing.T) {
+	s, nc := runServer(t, true)
+	c, stop := newConfig(t, s, true)
+	defer stop()
+	for _, a := range []string{actions, `[{"id": "three", "name": "three", "image": "alpine"}]`} {
+		if err := nc.Publish("tinkerbell.00:00:5e:00:53:01.workflow_actions", []byte(a)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := run(t, c, spec.StateFailure); got != "one" {
+		t.Fatalf("Read() = %q, want %q", got, "one")
+	}
+	// Action "two" is skipped as Action "one" of the same message failed.
+	if got := read(t, c); got != "three" {
+		t.Errorf("Read() = %q, want %q", got, "three")
+	}
+}
+
+func TestFallback(t *testing.T) {
+	s, nc := runServer(t, false)
+	events, err := nc.SubscribeSync("tinkerbell.00:00:5e:00:53:01.workflow_status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, stop := newConfig(t, s, true)
+	defer stop()
+
+	// Core NATS doesn't keep messages, they are published until the agent is subscribed.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	got := make(chan spec.Action, 1)
+	go func() {
+		a, _ := c.Read(ctx)
+		got <- a
+	}()
+	for done := false; !done; {
+		if err := nc.Publish("tinkerbell.00:00:5e:00:53:01.workflow_actions", []byte(actions)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case a := <-got:
+			if a.ID != "one" {
+				t.Errorf("Read() = %q, want %q", a.ID, "one")
+			}
+			done = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if err := c.Write(context.Background(), spec.Event{Action: spec.Action{ID: "one", Name: "one"}, State: spec.StateRunning}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := events.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if json.Valid(msg.Data) {
+		t.Errorf("event is JSON, want text: %s", msg.Data)
+	}
+}
+
+func TestWatchCancel(t *testing.T) {
+	s, nc := runServer(t, false)
+	c, stop := newConfig(t, s, false)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	got := make(chan spec.Action, 1)
+	go func() {
+		a, _ := c.Read(ctx)
+		got <- a
+	}()
+	var action spec.Action
+	for action.ID == "" {
+		if err := nc.Publish("tinkerbell.00:00:5e:00:53:01.workflow_actions", []byte(actions)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case action = <-got:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	reason := make(chan string, 1)
+	go func() {
+		r, err := c.WatchCancel(ctx, action)
+		if err != nil {
+			t.Errorf("WatchCancel() error = %v", err)
+		}
+		reason <- r
+	}()
+	// Core NATS doesn't keep messages, they are published until the agent is subscribed.
+	for {
+		for _, m := range []string{`{"actionId": "two", "message": "other action"}`, `invalid`, `{"actionId": "one", "message": "workflow canceled"}`} {
+			if err := nc.Publish("tinkerbell.00:00:5e:00:53:01.workflow_actions.cancel", []byte(m)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		select {
+		case r := <-reason:
+			if r != "workflow canceled" {
+				t.Errorf("WatchCancel() = %q, want %q", r, "workflow canceled")
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
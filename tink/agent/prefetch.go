@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// ImagePrefetcher is implemented by RuntimeExecutors that can pull an image before the Action that uses it runs.
+type ImagePrefetcher interface {
+	// Prefetch pulls image so that a later Execute using it doesn't have to.
+	Prefetch(ctx context.Context, image string) error
+}
+
+// prefetcher pulls images in the background, at most limit at a time.
+// Each image is pulled once, images that fail to pull are pulled again when they are requested again.
+type prefetcher struct {
+	runtime ImagePrefetcher
+	limit   chan struct{}
+
+	mu sync.Mutex
+	// pulls holds a channel for each image that is, or was successfully, pulled. The channel is closed once the pull is done.
+	pulls map[string]chan struct{}
+}
+
+func newPrefetcher(runtime ImagePrefetcher, concurrency int) *prefetcher {
+	return &prefetcher{
+		runtime: runtime,
+		limit:   make(chan struct{}, concurrency),
+		pulls:   map[string]chan struct{}{},
+	}
+}
+
+// prefetch starts pulling the images that are not already pulled or being pulled.
+func (p *prefetcher) prefetch(ctx context.Context, log logr.Logger, images []string) {
+	for _, image := range images {
+		p.mu.Lock()
+		if _, ok := p.pulls[image]; ok {
+			p.mu.Unlock()
+			continue
+		}
+		done := make(chan struct{})
+		p.pulls[image] = done
+		p.mu.Unlock()
+
+		go func() {
+			defer close(done)
+			select {
+			case p.limit <- struct{}{}:
+				defer func() { <-p.limit }()
+			case <-ctx.Done():
+				p.forget(image)
+				return
+			}
+			if err := p.runtime.Prefetch(ctx, image); err != nil {
+				log.Info("unable to prefetch image, it will be pulled when its Action runs", "image", image, "error", err)
+				p.forget(image)
+				return
+			}
+			log.Info("prefetched image", "image", image)
+		}()
+	}
+}
+
+// wait blocks until a pull of image that is in progress is done, so that the image is not pulled twice.
+func (p *prefetcher) wait(ctx context.Context, image string) {
+	p.mu.Lock()
+	done, ok := p.pulls[image]
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (p *prefetcher) forget(image string) {
+	p.mu.Lock()
+	delete(p.pulls, image)
+	p.mu.Unlock()
+}
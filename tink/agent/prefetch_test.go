@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+type fakePrefetcher struct {
+	mu      sync.Mutex
+	pulls   map[string]int
+	fail    map[string]bool
+	running int
+	max     int
+	release chan struct{}
+}
+
+func (f *fakePrefetcher) Prefetch(_ context.Context, image string) error {
+	f.mu.Lock()
+	f.pulls[image]++
+	f.running++
+	f.max = max(f.max, f.running)
+	f.mu.Unlock()
+	<-f.release
+	f.mu.Lock()
+	f.running--
+	f.mu.Unlock()
+	if f.fail[image] {
+		return errors.New("pull failed")
+	}
+	return nil
+}
+
+func TestPrefetch(t *testing.T) {
+	f := &fakePrefetcher{pulls: map[string]int{}, fail: map[string]bool{"broken": true}, release: make(chan struct{})}
+	p := newPrefetcher(f, 2)
+	ctx := context.Background()
+	images := []string{"image2disk", "writefile", "kexec", "broken"}
+	p.prefetch(ctx, logr.Discard(), images)
+	// Images already being pulled are not pulled again.
+	p.prefetch(ctx, logr.Discard(), images)
+	close(f.release)
+	for _, image := range images {
+		p.wait(ctx, image)
+	}
+
+	if f.max > 2 {
+		t.Errorf("got %d concurrent pulls, want at most 2", f.max)
+	}
+	for _, image := range images {
+		if f.pulls[image] != 1 {
+			t.Errorf("image %q pulled %d times, want 1", image, f.pulls[image])
+		}
+	}
+
+	// Images that failed to pull are pulled again, successful pulls are not.
+	p.prefetch(ctx, logr.Discard(), images)
+	p.wait(ctx, "broken")
+	if f.pulls["broken"] != 2 {
+		t.Errorf("failed image pulled %d times, want 2", f.pulls["broken"])
+	}
+	if f.pulls["image2disk"] != 1 {
+		t.Errorf("image pulled %d times, want 1", f.pulls["image2disk"])
+	}
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"github.com/tinkerbell/tinkerbell/pkg/template"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/runtime/builtin"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"sigs.k8s.io/yaml"
+)
+
+// LocalRun renders a Template, with the same functions as the Tink controller, and runs its Actions on the local
+// runtime without a Tink server. It is used to test Templates, for example in CI.
+type LocalRun struct {
+	// TemplatePath is a YAML file holding a Template object or the data of a Template.
+	TemplatePath string
+	// HardwarePath is a YAML file holding the Hardware object the Template is rendered with. Optional.
+	HardwarePath string
+	// HardwareMap holds the values of the hardware map of a Workflow in the form "key=value", for example "device_1=00:00:5e:00:53:01".
+	HardwareMap []string
+	// DryRun prints the resolved Actions, in the format of the workflow file of the file transport, instead of running them.
+	DryRun bool
+	// Output receives the resolved Actions in dry run mode and the output of the Actions otherwise. Defaults to stdout.
+	Output io.Writer
+}
+
+// RunTemplate renders the Template of r and runs its Actions, in order, until one of them doesn't succeed.
+// The Actions of all the Tasks run on the local machine, the on-failure Actions of Tasks are not run, and the reboot
+// and kexec builtins are skipped.
+func (o *Options) RunTemplate(ctx context.Context, log logr.Logger, r LocalRun) error {
+	if r.Output == nil {
+		r.Output = os.Stdout
+	}
+	actions, err := r.actions()
+	if err != nil {
+		return err
+	}
+	if r.DryRun {
+		b, err := yaml.Marshal(actions)
+		if err != nil {
+			return err
+		}
+		_, err = r.Output.Write(b)
+		return err
+	}
+
+	skip := func(name string) builtin.Func {
+		return func(_ context.Context, _ map[string]string, output io.Writer) error {
+			fmt.Fprintf(output, "the %s builtin is skipped in a local run\n", name)
+			return nil
+		}
+	}
+	re, verifier, err := o.configureRuntime(log, builtin.WithAction("reboot", skip("reboot")), builtin.WithAction("kexec", skip("kexec")))
+	if err != nil {
+		return err
+	}
+	c := &Config{RuntimeExecutor: re, TransportWriter: logTransport{log: log}, ImageVerifier: verifier}
+	for _, action := range actions {
+		log.Info("running action", "task", action.TaskID, "action", action.Name, "image", action.Image)
+		e := c.runAction(ctx, log, action, r.Output)
+		log.Info("action completed", "task", action.TaskID, "action", action.Name, "state", e.State, "duration", e.Action.ExecutionDuration)
+		if e.State != spec.StateSuccess {
+			return fmt.Errorf("action %s of task %s ended in state %s: %s", action.Name, action.TaskID, e.State, e.Message)
+		}
+	}
+
+	return nil
+}
+
+// actions renders the Template and returns its Actions as they are sent to the worker by the Tink server.
+func (r LocalRun) actions() ([]spec.Action, error) {
+	contents, err := os.ReadFile(r.TemplatePath)
+	if err != nil {
+		return nil, err
+	}
+	data := string(contents)
+	// Template data isn't valid YAML before it is rendered, so only a file that parses as a Template is one.
+	tpl := v1alpha1.Template{}
+	if err := yaml.Unmarshal(contents, &tpl); err == nil && tpl.Kind == "Template" {
+		if tpl.Spec.Data == nil {
+			return nil, fmt.Errorf("template %s has no data", r.TemplatePath)
+		}
+		data = *tpl.Spec.Data
+	}
+
+	hw := v1alpha1.Hardware{}
+	if r.HardwarePath != "" {
+		contents, err := os.ReadFile(r.HardwarePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(contents, &hw); err != nil {
+			return nil, fmt.Errorf("invalid hardware %s: %w", r.HardwarePath, err)
+		}
+	}
+	hardwareMap := map[string]string{}
+	for _, kv := range r.HardwareMap {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid hardware map entry %q, must be in the form key=value", kv)
+		}
+		hardwareMap[k] = v
+	}
+
+	wf, err := template.Render(r.TemplatePath, data, template.Data(hardwareMap, hw))
+	if err != nil {
+		return nil, err
+	}
+	actions := []spec.Action{}
+	for _, task := range wf.Tasks {
+		for _, a := range task.Actions {
+			actions = append(actions, toSpecAction(wf.Name, task, a))
+		}
+	}
+
+	return actions, nil
+}
+
+// toSpecAction returns the Action of a Task as it is sent to the worker by the Tink server.
+// The environment of the Task is added to the one of the Action and the volumes of the Task come first.
+func toSpecAction(workflow string, task template.Task, a template.Action) spec.Action {
+	env := maps.Clone(task.Environment)
+	if env == nil {
+		env = map[string]string{}
+	}
+	maps.Copy(env, a.Environment)
+	as := spec.Action{
+		WorkerID:       task.WorkerAddr,
+		TaskID:         task.Name,
+		WorkflowID:     workflow,
+		ID:             a.Name,
+		Name:           a.Name,
+		Image:          a.Image,
+		Args:           a.Command,
+		Namespaces:     spec.Namespaces{PID: a.Pid, Network: a.Network},
+		Retries:        int(a.Retries),
+		BackoffSeconds: int(a.Backoff),
+		TimeoutSeconds: int(a.Timeout),
+		Reboot:         a.Reboot,
+	}
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		as.Env = append(as.Env, spec.Env{Key: k, Value: env[k]})
+	}
+	for _, v := range append(slices.Clone(task.Volumes), a.Volumes...) {
+		as.Volumes = append(as.Volumes, spec.Volume(v))
+	}
+
+	return as
+}
+
+// logTransport logs the events of a local run.
+type logTransport struct {
+	log logr.Logger
+}
+
+func (l logTransport) Write(_ context.Context, event spec.Event) error {
+	l.log.Info("action event", "action", event.Action.Name, "state", event.State, "message", event.Message)
+	return nil
+}
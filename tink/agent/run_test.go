@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/tinkerbell/tink/agent/internal/spec"
+	"sigs.k8s.io/yaml"
+)
+
+const templateData = `version: "0.1"
+name: provision
+global_timeout: 600
+tasks:
+  - name: "os installation"
+    worker: "{{.device_1}}"
+    volumes:
+      - /dev:/dev
+    environment:
+      DEST_DISK: {{ index .Hardware.Disks 0 }}
+    actions:
+      - name: "stream"
+        image: quay.io/tinkerbell/actions/image2disk
+        timeout: 600
+        retries: 1
+        environment:
+          DEST_DISK: {{ formatPartition (index .Hardware.Disks 0) 1 }}
+          IMG_URL: http://192.0.2.1/image.raw.gz
+      - name: "reboot"
+        image: builtin://reboot
+        timeout: 60
+        pid: host
+`
+
+const hardware = `apiVersion: tinkerbell.org/v1alpha1
+kind: Hardware
+metadata:
+  name: machine1
+spec:
+  disks:
+    - device: /dev/nvme0n1
+`
+
+// rendered holds the Actions of templateData rendered with hardware.
+var rendered = []spec.Action{
+	{
+		WorkerID: "00:00:5e:00:53:01", TaskID: "os installation", WorkflowID: "provision", ID: "stream", Name: "stream",
+		Image:          "quay.io/tinkerbell/actions/image2disk",
+		Env:            []spec.Env{{Key: "DEST_DISK", Value: "/dev/nvme0n1p1"}, {Key: "IMG_URL", Value: "http://192.0.2.1/image.raw.gz"}},
+		Volumes:        []spec.Volume{"/dev:/dev"},
+		Retries:        1,
+		TimeoutSeconds: 600,
+	},
+	{
+		WorkerID: "00:00:5e:00:53:01", TaskID: "os installation", WorkflowID: "provision", ID: "reboot", Name: "reboot",
+		Image:          "builtin://reboot",
+		Env:            []spec.Env{{Key: "DEST_DISK", Value: "/dev/nvme0n1"}},
+		Volumes:        []spec.Volume{"/dev:/dev"},
+		Namespaces:     spec.Namespaces{PID: "host"},
+		TimeoutSeconds: 60,
+	},
+}
+
+func TestRunTemplateDryRun(t *testing.T) {
+	indented := "    " + strings.ReplaceAll(strings.TrimSuffix(templateData, "\n"), "\n", "\n    ")
+	tests := map[string]struct {
+		template    string
+		hardwareMap []string
+		want        []spec.Action
+		wantErr     bool
+	}{
+		"template data": {
+			template:    templateData,
+			hardwareMap: []string{"device_1=00:00:5e:00:53:01"},
+			want:        rendered,
+		},
+		"template object": {
+			template:    "apiVersion: tinkerbell.org/v1alpha1\nkind: Template\nmetadata:\n  name: provision\nspec:\n  data: |\n" + indented + "\n",
+			hardwareMap: []string{"device_1=00:00:5e:00:53:01"},
+			want:        rendered,
+		},
+		"missing hardware map value": {
+			template: templateData,
+			wantErr:  true,
+		},
+		"invalid hardware map": {
+			template:    templateData,
+			hardwareMap: []string{"device_1"},
+			wantErr:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			r := LocalRun{
+				TemplatePath: filepath.Join(dir, "template.yaml"),
+				HardwarePath: filepath.Join(dir, "hardware.yaml"),
+				HardwareMap:  tt.hardwareMap,
+				DryRun:       true,
+			}
+			if err := os.WriteFile(r.TemplatePath, []byte(tt.template), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(r.HardwarePath, []byte(hardware), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			out := &bytes.Buffer{}
+			r.Output = out
+
+			err := (&Options{}).RunTemplate(context.Background(), logr.Discard(), r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// The output is a workflow file of the file transport.
+			got := []spec.Action{}
+			if err := yaml.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected actions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunTemplateSkipsReboot(t *testing.T) {
+	dir := t.TempDir()
+	r := LocalRun{
+		TemplatePath: filepath.Join(dir, "template.yaml"),
+		HardwareMap:  []string{"device_1=00:00:5e:00:53:01"},
+	}
+	tpl := `version: "0.1"
+name: reboot
+global_timeout: 60
+tasks:
+  - name: "reboot"
+    worker: "{{.device_1}}"
+    actions:
+      - name: "reboot"
+        image: builtin://reboot
+        timeout: 60
+`
+	if err := os.WriteFile(r.TemplatePath, []byte(tpl), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	r.Output = out
+
+	if err := (&Options{RuntimeSelected: DockerRuntimeType}).RunTemplate(context.Background(), logr.Discard(), r); err != nil {
+		t.Fatalf("RunTemplate() error = %v", err)
+	}
+	if want := "the reboot builtin is skipped in a local run\n"; out.String() != want {
+		t.Errorf("got output %q, want %q", out.String(), want)
+	}
+}
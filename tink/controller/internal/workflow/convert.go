@@ -4,27 +4,18 @@ import (
 	"github.com/oklog/ulid/v2"
 	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
 	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"github.com/tinkerbell/tinkerbell/pkg/template"
 )
 
-func YAMLToStatus(wf *Workflow) *v1alpha1.WorkflowStatus {
+func YAMLToStatus(wf *template.Workflow) *v1alpha1.WorkflowStatus {
 	if wf == nil {
 		return nil
 	}
 	tasks := []v1alpha1.Task{}
 	for _, task := range wf.Tasks {
-		actions := []v1alpha1.Action{}
-		for _, action := range task.Actions {
-			actions = append(actions, v1alpha1.Action{
-				ID:          ulid.Make().String(),
-				Name:        action.Name,
-				Image:       action.Image,
-				Timeout:     action.Timeout,
-				Command:     action.Command,
-				Volumes:     action.Volumes,
-				State:       v1alpha1.WorkflowState(proto.StateType_PENDING.String()),
-				Environment: action.Environment,
-				Pid:         action.Pid,
-			})
+		var onFailure []v1alpha1.Action
+		if len(task.OnFailure) > 0 {
+			onFailure = toStatusActions(task.OnFailure)
 		}
 		tasks = append(tasks, v1alpha1.Task{
 			Name:        task.Name,
@@ -32,7 +23,9 @@ func YAMLToStatus(wf *Workflow) *v1alpha1.WorkflowStatus {
 			ID:          ulid.Make().String(),
 			Volumes:     task.Volumes,
 			Environment: task.Environment,
-			Actions:     actions,
+			Actions:     toStatusActions(task.Actions),
+			OnFailure:   onFailure,
+			DependsOn:   task.DependsOn,
 		})
 	}
 	return &v1alpha1.WorkflowStatus{
@@ -40,3 +33,27 @@ func YAMLToStatus(wf *Workflow) *v1alpha1.WorkflowStatus {
 		Tasks:         tasks,
 	}
 }
+
+func toStatusActions(in []template.Action) []v1alpha1.Action {
+	actions := []v1alpha1.Action{}
+	for _, action := range in {
+		actions = append(actions, v1alpha1.Action{
+			ID:          ulid.Make().String(),
+			Name:        action.Name,
+			Image:       action.Image,
+			Timeout:     action.Timeout,
+			Command:     action.Command,
+			Volumes:     action.Volumes,
+			State:       v1alpha1.WorkflowState(proto.StateType_PENDING.String()),
+			Environment: action.Environment,
+			Pid:         action.Pid,
+			Network:     action.Network,
+			Retries:     action.Retries,
+			Backoff:     action.Backoff,
+			Reboot:      action.Reboot,
+			OnTimeout:   action.OnTimeout,
+			OnFailure:   action.OnFailure,
+		})
+	}
+	return actions
+}
@@ -6,12 +6,13 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"github.com/tinkerbell/tinkerbell/pkg/template"
 )
 
 func TestYAMLToStatus(t *testing.T) {
 	cases := []struct {
 		name    string
-		inputWf *Workflow
+		inputWf *template.Workflow
 		want    *v1alpha1.WorkflowStatus
 	}{
 		{
@@ -21,16 +22,16 @@ func TestYAMLToStatus(t *testing.T) {
 		},
 		{
 			"Full crd",
-			&Workflow{
+			&template.Workflow{
 				Version:       "1",
 				Name:          "debian-provision",
 				ID:            "0a90fac9-b509-4aa5-b294-5944128ece81",
 				GlobalTimeout: 600,
-				Tasks: []Task{
+				Tasks: []template.Task{
 					{
 						Name:       "do-or-do-not-there-is-no-try",
 						WorkerAddr: "00:00:53:00:53:F4",
-						Actions: []Action{
+						Actions: []template.Action{
 							{
 								Name:    "stream-image-to-disk",
 								Image:   "quay.io/tinkerbell-actions/image2disk:v1.0.0",
@@ -46,7 +47,8 @@ func TestYAMLToStatus(t *testing.T) {
 									"DEST_DISK":  "/dev/nvme0n1",
 									"IMG_URL":    "http://10.1.1.11:8080/debian-10-openstack-amd64.raw.gz",
 								},
-								Pid: "host",
+								Pid:     "host",
+								Network: "host",
 							},
 						},
 					},
@@ -69,7 +71,8 @@ func TestYAMLToStatus(t *testing.T) {
 									"/lib/firmware:/lib/firmware:ro",
 									"/tmp/debug:/tmp/debug",
 								},
-								Pid: "host",
+								Pid:     "host",
+								Network: "host",
 								Environment: map[string]string{
 									"COMPRESSED": "true",
 									"DEST_DISK":  "/dev/nvme0n1",
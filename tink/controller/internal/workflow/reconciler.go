@@ -9,6 +9,7 @@ import (
 	"github.com/cenkalti/backoff/v5"
 	"github.com/go-logr/logr"
 	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"github.com/tinkerbell/tinkerbell/pkg/template"
 	"github.com/tinkerbell/tinkerbell/tink/controller/internal/workflow/journal"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -19,6 +20,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// cancelTimeout is how long the worker running an Action of a canceled Workflow is given to report it stopped.
+// It covers the time the agent gives the Action to exit, 5 seconds by default, plus the time to report it.
+const cancelTimeout = time.Minute
+
 // Reconciler is a type for managing Workflows.
 type Reconciler struct {
 	client  ctrlclient.Client
@@ -83,6 +88,18 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	wflow := stored.DeepCopy()
 	// r.processRunningWorkflow(wflow)
 
+	if wflow.Spec.Cancel {
+		canceled, wait := cancel(wflow, r.nowFunc())
+		if canceled {
+			journal.Log(ctx, "workflow canceled")
+			return reconcile.Result{}, mergePatchStatus(ctx, r.client, stored, wflow)
+		}
+		if wait > 0 {
+			journal.Log(ctx, "waiting for the worker to stop the action of the canceled workflow")
+			return reconcile.Result{RequeueAfter: wait}, mergePatchStatus(ctx, r.client, stored, wflow)
+		}
+	}
+
 	switch wflow.Status.State {
 	case "":
 		journal.Log(ctx, "new workflow")
@@ -130,7 +147,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		rc, err := s.postActions(ctx)
 
 		return rc, serrors.Join(err, mergePatchStatus(ctx, r.client, stored, wflow))
-	case v1alpha1.WorkflowStatePending, v1alpha1.WorkflowStateTimeout, v1alpha1.WorkflowStateFailed, v1alpha1.WorkflowStateSuccess:
+	case v1alpha1.WorkflowStatePending, v1alpha1.WorkflowStateTimeout, v1alpha1.WorkflowStateFailed, v1alpha1.WorkflowStateVerificationFailed, v1alpha1.WorkflowStateSuccess, v1alpha1.WorkflowStateCanceled:
 		journal.Log(ctx, "controller will not trigger another reconcile", "state", wflow.Status.State)
 		return reconcile.Result{}, nil
 	}
@@ -218,14 +235,8 @@ func (r *Reconciler) processNewWorkflow(ctx context.Context, logger logr.Logger,
 		)
 	}
 
-	data := make(map[string]interface{})
-	for key, val := range stored.Spec.HardwareMap {
-		data[key] = val
-	}
-	contract := toTemplateHardwareData(hardware)
-	data["Hardware"] = contract
-
-	tinkWf, err := renderTemplateHardware(stored.Name, pointerToValue(tpl.Spec.Data), data)
+	data := template.Data(stored.Spec.HardwareMap, hardware)
+	tinkWf, err := template.Render(stored.Name, pointerToValue(tpl.Spec.Data), data)
 	if err != nil {
 		journal.Log(ctx, "error rendering template")
 		stored.Status.TemplateRendering = v1alpha1.TemplateRenderingFailed
@@ -262,51 +273,87 @@ func (r *Reconciler) processNewWorkflow(ctx context.Context, logger logr.Logger,
 	return reconcile.Result{}, nil
 }
 
-// templateHardwareData defines the data exposed for a Hardware instance to a Template.
-type templateHardwareData struct {
-	Disks      []string
-	Interfaces []v1alpha1.Interface
-	UserData   string
-	Metadata   v1alpha1.HardwareMetadata
-	VendorData string
+func pointerToValue[V any](ptr *V) V {
+	if ptr == nil {
+		var zero V
+		return zero
+	}
+	return *ptr
 }
 
-// toTemplateHardwareData converts a Hardware instance of templateHardwareData for use in template
-// rendering.
-func toTemplateHardwareData(hardware v1alpha1.Hardware) templateHardwareData {
-	var contract templateHardwareData
-	for _, disk := range hardware.Spec.Disks {
-		contract.Disks = append(contract.Disks, disk.Device)
+// cancel moves a Workflow that hasn't ended to the CANCELED state and returns true when it did.
+// A Workflow with a running Action is left to the Tink server, it is canceled when the worker reports the Action
+// it stopped, and cancel returns how long to wait for that report. When the worker doesn't report it within
+// cancelTimeout, for example because it is offline, the running Action and the Workflow are canceled.
+// A Workflow in the POST state already ran all of its Actions and is not canceled.
+func cancel(w *v1alpha1.Workflow, now time.Time) (bool, time.Duration) {
+	switch w.Status.State {
+	case "", v1alpha1.WorkflowStatePreparing, v1alpha1.WorkflowStatePending, v1alpha1.WorkflowStateRunning:
+	default:
+		return false, 0
 	}
-	if len(hardware.Spec.Interfaces) > 0 {
-		contract.Interfaces = hardware.Spec.Interfaces
-	}
-	if hardware.Spec.UserData != nil {
-		contract.UserData = pointerToValue(hardware.Spec.UserData)
+	running := []*v1alpha1.Action{}
+	for ti := range w.Status.Tasks {
+		for ai := range w.Status.Tasks[ti].Actions {
+			if w.Status.Tasks[ti].Actions[ai].State == v1alpha1.WorkflowStateRunning {
+				running = append(running, &w.Status.Tasks[ti].Actions[ai])
+			}
+		}
 	}
-	if hardware.Spec.Metadata != nil {
-		contract.Metadata = *hardware.Spec.Metadata
+	if w.Status.Failure != nil {
+		for ai := range w.Status.Failure.Actions {
+			if w.Status.Failure.Actions[ai].State == v1alpha1.WorkflowStateRunning {
+				running = append(running, &w.Status.Failure.Actions[ai])
+			}
+		}
 	}
-	if hardware.Spec.VendorData != nil {
-		contract.VendorData = pointerToValue(hardware.Spec.VendorData)
+	if len(running) > 0 {
+		requested := cancelRequested(w, now)
+		if wait := requested.Add(cancelTimeout).Sub(now); wait > 0 {
+			return false, wait
+		}
+		for _, a := range running {
+			a.State = v1alpha1.WorkflowStateCanceled
+			a.Message = "the worker did not report the action stopped after the workflow was canceled"
+		}
 	}
-	return contract
+	w.Status.State = v1alpha1.WorkflowStateCanceled
+
+	return true, 0
 }
 
-func pointerToValue[V any](ptr *V) V {
-	if ptr == nil {
-		var zero V
-		return zero
+// cancelRequested returns the time the controller first saw the cancellation of a Workflow with a running Action,
+// it is recorded in the CancelRequested condition.
+func cancelRequested(w *v1alpha1.Workflow, now time.Time) time.Time {
+	for _, c := range w.Status.Conditions {
+		if c.Type == v1alpha1.CancelRequested && c.Time != nil {
+			return c.Time.Time
+		}
 	}
-	return *ptr
+	w.Status.SetCondition(v1alpha1.WorkflowCondition{
+		Type:    v1alpha1.CancelRequested,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Canceling",
+		Message: "waiting for the worker to stop the running action",
+		Time:    &metav1.Time{Time: now.UTC()},
+	})
+
+	return now
 }
 
-// startTime returns the start time, for the first action of the first task.
+// startTime returns the earliest start time of the actions in all tasks.
+// Tasks assigned to different workers can start in any order.
 func startTime(w *v1alpha1.Workflow) *metav1.Time {
-	if len(w.Status.Tasks) > 0 {
-		if len(w.Status.Tasks[0].Actions) > 0 {
-			return w.Status.Tasks[0].Actions[0].ExecutionStart
+	var st *metav1.Time
+	for _, task := range w.Status.Tasks {
+		for _, action := range task.Actions {
+			if action.ExecutionStart.IsZero() {
+				continue
+			}
+			if st == nil || action.ExecutionStart.Before(st) {
+				st = action.ExecutionStart
+			}
 		}
 	}
-	return nil
+	return st
 }
@@ -1145,3 +1145,101 @@ func (f *FrozenTime) MetaV1BeforeSec(s int64) *metav1.Time {
 	t := metav1.NewTime(f.BeforeSec(s))
 	return &t
 }
+
+func TestCancel(t *testing.T) {
+	tasks := func(states ...v1alpha1.WorkflowState) []v1alpha1.Task {
+		task := v1alpha1.Task{Name: "os-installation", WorkerAddr: "3c:ec:ef:4c:4f:54"}
+		for _, s := range states {
+			task.Actions = append(task.Actions, v1alpha1.Action{Name: "action", State: s})
+		}
+		return []v1alpha1.Task{task}
+	}
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	requested := func(ago time.Duration) []v1alpha1.WorkflowCondition {
+		return []v1alpha1.WorkflowCondition{{Type: v1alpha1.CancelRequested, Status: metav1.ConditionTrue, Time: &metav1.Time{Time: now.Add(-ago)}}}
+	}
+	cases := map[string]struct {
+		status    v1alpha1.WorkflowStatus
+		want      bool
+		wantWait  time.Duration
+		wantState v1alpha1.WorkflowState
+		// wantActionState is the state of the first Action of the Task.
+		wantActionState v1alpha1.WorkflowState
+	}{
+		"new": {
+			want:      true,
+			wantState: v1alpha1.WorkflowStateCanceled,
+		},
+		"pending": {
+			status:    v1alpha1.WorkflowStatus{State: v1alpha1.WorkflowStatePending, Tasks: tasks(v1alpha1.WorkflowStatePending)},
+			want:      true,
+			wantState: v1alpha1.WorkflowStateCanceled,
+		},
+		"running between actions": {
+			status:    v1alpha1.WorkflowStatus{State: v1alpha1.WorkflowStateRunning, Tasks: tasks(v1alpha1.WorkflowStateSuccess, v1alpha1.WorkflowStatePending)},
+			want:      true,
+			wantState: v1alpha1.WorkflowStateCanceled,
+		},
+		"action running": {
+			status:          v1alpha1.WorkflowStatus{State: v1alpha1.WorkflowStateRunning, Tasks: tasks(v1alpha1.WorkflowStateRunning, v1alpha1.WorkflowStatePending)},
+			wantWait:        cancelTimeout,
+			wantState:       v1alpha1.WorkflowStateRunning,
+			wantActionState: v1alpha1.WorkflowStateRunning,
+		},
+		"action running, waiting for the worker": {
+			status: v1alpha1.WorkflowStatus{
+				State:      v1alpha1.WorkflowStateRunning,
+				Tasks:      tasks(v1alpha1.WorkflowStateRunning),
+				Conditions: requested(10 * time.Second),
+			},
+			wantWait:        cancelTimeout - 10*time.Second,
+			wantState:       v1alpha1.WorkflowStateRunning,
+			wantActionState: v1alpha1.WorkflowStateRunning,
+		},
+		"action running, worker did not report": {
+			status: v1alpha1.WorkflowStatus{
+				State:      v1alpha1.WorkflowStateRunning,
+				Tasks:      tasks(v1alpha1.WorkflowStateRunning, v1alpha1.WorkflowStatePending),
+				Conditions: requested(cancelTimeout),
+			},
+			want:            true,
+			wantState:       v1alpha1.WorkflowStateCanceled,
+			wantActionState: v1alpha1.WorkflowStateCanceled,
+		},
+		"on-failure action running": {
+			status: v1alpha1.WorkflowStatus{
+				State:   v1alpha1.WorkflowStateRunning,
+				Tasks:   tasks(v1alpha1.WorkflowStateFailed),
+				Failure: &v1alpha1.FailureState{State: v1alpha1.WorkflowStateFailed, Actions: []v1alpha1.Action{{Name: "cleanup", State: v1alpha1.WorkflowStateRunning}}},
+			},
+			wantWait:  cancelTimeout,
+			wantState: v1alpha1.WorkflowStateRunning,
+		},
+		"post": {
+			status:    v1alpha1.WorkflowStatus{State: v1alpha1.WorkflowStatePost, Tasks: tasks(v1alpha1.WorkflowStateSuccess)},
+			wantState: v1alpha1.WorkflowStatePost,
+		},
+		"failed": {
+			status:    v1alpha1.WorkflowStatus{State: v1alpha1.WorkflowStateFailed, Tasks: tasks(v1alpha1.WorkflowStateFailed)},
+			wantState: v1alpha1.WorkflowStateFailed,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			wf := &v1alpha1.Workflow{Spec: v1alpha1.WorkflowSpec{Cancel: true}, Status: tc.status}
+			got, wait := cancel(wf, now)
+			if got != tc.want || wait != tc.wantWait {
+				t.Errorf("cancel() = %v, %v, want %v, %v", got, wait, tc.want, tc.wantWait)
+			}
+			if wf.Status.State != tc.wantState {
+				t.Errorf("state = %q, want %q", wf.Status.State, tc.wantState)
+			}
+			if tc.wantActionState != "" && wf.Status.Tasks[0].Actions[0].State != tc.wantActionState {
+				t.Errorf("action state = %q, want %q", wf.Status.Tasks[0].Actions[0].State, tc.wantActionState)
+			}
+			if tc.wantWait > 0 && !wf.Status.HasCondition(v1alpha1.CancelRequested, metav1.ConditionTrue) {
+				t.Error("CancelRequested condition not set")
+			}
+		})
+	}
+}
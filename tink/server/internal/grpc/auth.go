@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"net/url"
+	"slices"
+
+	"github.com/tinkerbell/tinkerbell/pkg/workertoken"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// AdminTokenMetadataKey is the gRPC metadata key in which callers of admin RPCs, like CancelWorkflow, send the admin token.
+const AdminTokenMetadataKey = "x-tinkerbell-admin-token"
+
+// authorizeWorker verifies that the caller holds a credential for the worker ID it claims.
+// A verified client certificate must name the worker ID in its Common Name or a SAN.
+// Without a client certificate the worker token sent in the request metadata must be valid for the worker ID.
+// When neither client certificates nor worker tokens are configured all callers are allowed.
+func (h *Handler) authorizeWorker(ctx context.Context, workerID string) error {
+	if cert := verifiedClientCert(ctx); cert != nil {
+		if !certNamesWorker(cert, workerID) {
+			return status.Errorf(codes.PermissionDenied, "client certificate is not valid for worker %q", workerID)
+		}
+		return nil
+	}
+	if len(h.WorkerTokenSecret) == 0 {
+		return nil
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(workertoken.MetadataKey); len(v) > 0 {
+			token = v[0]
+		}
+	}
+	if token == "" {
+		return status.Errorf(codes.Unauthenticated, "missing worker token")
+	}
+	if !workertoken.Valid(h.WorkerTokenSecret, workerID, token) {
+		return status.Errorf(codes.PermissionDenied, "worker token is not valid for worker %q", workerID)
+	}
+
+	return nil
+}
+
+// authorizeAdmin verifies that the caller sent the admin token of the server in the request metadata.
+// Admin RPCs are denied to all callers when no admin token is configured.
+func (h *Handler) authorizeAdmin(ctx context.Context) error {
+	if h.AdminToken == "" {
+		return status.Errorf(codes.PermissionDenied, "admin RPCs are disabled, the server has no admin token")
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(AdminTokenMetadataKey); len(v) > 0 {
+			token = v[0]
+		}
+	}
+	if token == "" {
+		return status.Errorf(codes.Unauthenticated, "missing admin token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+		return status.Errorf(codes.PermissionDenied, "admin token is not valid")
+	}
+
+	return nil
+}
+
+// verifiedClientCert returns the leaf certificate of a client verified during the TLS handshake, if any.
+func verifiedClientCert(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	ti, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(ti.State.VerifiedChains) == 0 || len(ti.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return ti.State.VerifiedChains[0][0]
+}
+
+// certNamesWorker reports whether a certificate names a worker ID in its Common Name, DNS or URI SANs.
+func certNamesWorker(cert *x509.Certificate, workerID string) bool {
+	if cert.Subject.CommonName == workerID || slices.Contains(cert.DNSNames, workerID) {
+		return true
+	}
+	return slices.ContainsFunc(cert.URIs, func(u *url.URL) bool { return u.String() == workerID })
+}
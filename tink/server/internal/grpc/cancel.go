@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// canceledMessage is the message sent to workers with an Action of a canceled Workflow.
+const canceledMessage = "workflow canceled"
+
+// WorkflowCanceler is implemented by backends that can cancel Workflows.
+type WorkflowCanceler interface {
+	// CancelWorkflow sets the Cancel field of the spec of a Workflow.
+	CancelWorkflow(ctx context.Context, workflowID, namespace string) error
+}
+
+// CancelWorkflow cancels a Workflow that hasn't ended. It is an admin RPC, see authorizeAdmin.
+// No more Actions of the Workflow are served and the worker running one of its Actions is told to stop it.
+func (h *Handler) CancelWorkflow(ctx context.Context, req *proto.CancelWorkflowRequest) (*proto.CancelWorkflowResponse, error) {
+	if err := h.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	namespace, name, found := strings.Cut(req.GetWorkflowId(), "/")
+	if !found || namespace == "" || name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%s: %q, must be in the form namespace/name", errInvalidWorkflowID, req.GetWorkflowId())
+	}
+	c, ok := h.BackendReadWriter.(WorkflowCanceler)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the backend can't cancel workflows")
+	}
+	wf, err := h.BackendReadWriter.Read(ctx, name, namespace)
+	if err != nil {
+		return nil, errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting workflow: %v", err))
+	}
+	if wf.Spec.Cancel {
+		return &proto.CancelWorkflowResponse{}, nil
+	}
+	if terminalState(wf.Status.State) || wf.Status.State == v1alpha1.WorkflowStateSuccess {
+		return nil, status.Errorf(codes.FailedPrecondition, "workflow already ended in state %s", wf.Status.State)
+	}
+	if err := c.CancelWorkflow(ctx, name, namespace); err != nil {
+		return nil, errors.Join(ErrBackendWrite, status.Errorf(codes.Internal, "error canceling workflow: %v", err))
+	}
+	h.Logger.Info("workflow canceled", "workflow", req.GetWorkflowId())
+
+	return &proto.CancelWorkflowResponse{}, nil
+}
+
+// WatchActionCancel sends a single response, and returns, once the Workflow of the running Action of a worker is canceled.
+// The Action must be one of a Task of the worker.
+func (h *Handler) WatchActionCancel(req *proto.ActionCancelRequest, stream grpc.ServerStreamingServer[proto.ActionCancelResponse]) error {
+	if req.GetWorkerId() == "" {
+		return status.Errorf(codes.InvalidArgument, "invalid worker id:")
+	}
+	namespace, name, _ := strings.Cut(req.GetWorkflowId(), "/")
+	if name == "" {
+		return status.Errorf(codes.InvalidArgument, errInvalidWorkflowID)
+	}
+	ctx := stream.Context()
+	if err := h.authorizeWorker(ctx, req.GetWorkerId()); err != nil {
+		return err
+	}
+	log := h.Logger.WithValues("worker", req.GetWorkerId(), "workflow", req.GetWorkflowId())
+	changed, interval := h.watchWorkflows(ctx, log, req.GetWorkerId())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		wf, err := h.BackendReadWriter.Read(ctx, name, namespace)
+		switch {
+		case err != nil:
+			log.Info("unable to read workflow, will retry", "error", err)
+		case !runsAction(wf, req.GetWorkerId(), req.GetTaskId(), req.GetActionId()):
+			return status.Errorf(codes.PermissionDenied, "action %q of task %q is not run by worker %q", req.GetActionId(), req.GetTaskId(), req.GetWorkerId())
+		case wf.Spec.Cancel:
+			log.Info("telling worker to stop the action of the canceled workflow", "action", req.GetActionId())
+			return stream.Send(&proto.ActionCancelResponse{
+				WorkflowId: toPtr(req.GetWorkflowId()),
+				TaskId:     toPtr(req.GetTaskId()),
+				ActionId:   toPtr(req.GetActionId()),
+				Message:    toPtr(canceledMessage),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// CanceledAction returns the Action a worker was served, and must stop, because its Workflow was canceled.
+// nil is returned when the worker has no such Action.
+// It is used by front ends, other than gRPC, that push cancellations to workers.
+func (h *Handler) CanceledAction(ctx context.Context, workerID string) (*proto.ActionCancelResponse, error) {
+	wflows, err := h.BackendReadWriter.ReadAll(ctx, workerID)
+	if err != nil {
+		return nil, errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting workflows: %v", err))
+	}
+	if len(wflows) == 0 || !wflows[0].Spec.Cancel {
+		return nil, nil
+	}
+	wf := wflows[0]
+	taskID, action := servedAction(&wf, workerID)
+	if action == nil {
+		return nil, nil
+	}
+
+	return &proto.ActionCancelResponse{
+		WorkflowId: toPtr(wf.Namespace + "/" + wf.Name),
+		TaskId:     toPtr(taskID),
+		ActionId:   toPtr(action.ID),
+		Message:    toPtr(canceledMessage),
+	}, nil
+}
+
+// runsAction reports whether an Action of a Workflow, including on-failure Actions, is of a Task of the worker.
+func runsAction(wf *v1alpha1.Workflow, workerID, taskID, actionID string) bool {
+	worker, ok := actionWorker(wf, taskID, actionID)
+	return ok && worker == workerID
+}
+
+// servedAction returns the Action, and the ID of its Task, that a worker is running or was served and hasn't started.
+// On-failure Actions are returned while they run. nil is returned when the worker has no such Action.
+func servedAction(wf *v1alpha1.Workflow, workerID string) (string, *v1alpha1.Action) {
+	active := func(a v1alpha1.Action) bool {
+		return a.State == v1alpha1.WorkflowStateRunning || (a.State == v1alpha1.WorkflowStatePending && a.Served)
+	}
+	if f := wf.Status.Failure; f != nil {
+		for i := range f.Actions {
+			if active(f.Actions[i]) && runsAction(wf, workerID, f.TaskID, f.Actions[i].ID) {
+				return f.TaskID, &f.Actions[i]
+			}
+		}
+	}
+	for _, task := range wf.Status.Tasks {
+		if task.WorkerAddr != workerID {
+			continue
+		}
+		for i := range task.Actions {
+			if active(task.Actions[i]) {
+				return task.ID, &task.Actions[i]
+			}
+		}
+	}
+
+	return "", nil
+}
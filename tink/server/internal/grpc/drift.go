@@ -0,0 +1,168 @@
+package grpc
+
+import (
+	"fmt"
+	"slices"
+
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// HardwareEventRecorder is implemented by backends that can record events for Hardware.
+type HardwareEventRecorder interface {
+	RecordHardwareEvent(hw *v1alpha1.Hardware, eventType, reason, message string)
+}
+
+// inventoryDrift returns the differences between a recorded inventory (old) and a newly reported inventory (cur).
+// Values that are expected to change between boots, like NIC link speed or usable memory, are ignored.
+// No differences are returned when there is no recorded inventory.
+func inventoryDrift(old, cur *v1alpha1.Inventory) []string {
+	if old == nil || cur == nil {
+		return nil
+	}
+	var changes []string
+	changed := func(what string, from, to any) {
+		changes = append(changes, fmt.Sprintf("%s changed from %q to %q", what, fmt.Sprint(from), fmt.Sprint(to)))
+	}
+
+	oc, cc := deref(old.CPU), deref(cur.CPU)
+	if oc.TotalCores != cc.TotalCores {
+		changed("cpu total cores", oc.TotalCores, cc.TotalCores)
+	}
+	if oc.TotalThreads != cc.TotalThreads {
+		changed("cpu total threads", oc.TotalThreads, cc.TotalThreads)
+	}
+	if !slices.Equal(processorModels(oc), processorModels(cc)) {
+		changed("cpu models", processorModels(oc), processorModels(cc))
+	}
+	if o, c := quantityString(deref(old.Memory).Total), quantityString(deref(cur.Memory).Total); o != c {
+		changed("memory total", o, c)
+	}
+
+	oldBlock := map[string]v1alpha1.InventoryBlockDevice{}
+	for _, b := range old.BlockDevices {
+		oldBlock[b.Name] = b
+	}
+	curBlock := map[string]v1alpha1.InventoryBlockDevice{}
+	for _, b := range cur.BlockDevices {
+		curBlock[b.Name] = b
+		o, ok := oldBlock[b.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("block device %q added: %s", b.Name, describeBlockDevice(b)))
+			continue
+		}
+		if describeBlockDevice(o) != describeBlockDevice(b) {
+			changes = append(changes, fmt.Sprintf("block device %q changed from %s to %s", b.Name, describeBlockDevice(o), describeBlockDevice(b)))
+		}
+	}
+	for _, b := range old.BlockDevices {
+		if _, ok := curBlock[b.Name]; !ok {
+			changes = append(changes, fmt.Sprintf("block device %q removed: %s", b.Name, describeBlockDevice(b)))
+		}
+	}
+
+	oldNIC := map[string]v1alpha1.InventoryNIC{}
+	for _, n := range old.NICs {
+		oldNIC[n.Name] = n
+	}
+	curNIC := map[string]v1alpha1.InventoryNIC{}
+	for _, n := range cur.NICs {
+		curNIC[n.Name] = n
+		o, ok := oldNIC[n.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("nic %q added: mac %s", n.Name, n.MAC))
+			continue
+		}
+		if o.MAC != n.MAC {
+			changed(fmt.Sprintf("nic %q mac", n.Name), o.MAC, n.MAC)
+		}
+	}
+	for _, n := range old.NICs {
+		if _, ok := curNIC[n.Name]; !ok {
+			changes = append(changes, fmt.Sprintf("nic %q removed: mac %s", n.Name, n.MAC))
+		}
+	}
+
+	changes = append(changes, pciDrift("pci device", old.PCIDevices, cur.PCIDevices)...)
+	changes = append(changes, pciDrift("gpu", old.GPUs, cur.GPUs)...)
+
+	fields := []struct {
+		what     string
+		from, to string
+	}{
+		{"chassis serial", deref(old.Chassis).Serial, deref(cur.Chassis).Serial},
+		{"chassis vendor", deref(old.Chassis).Vendor, deref(cur.Chassis).Vendor},
+		{"bios vendor", deref(old.BIOS).Vendor, deref(cur.BIOS).Vendor},
+		{"bios version", deref(old.BIOS).Version, deref(cur.BIOS).Version},
+		{"bios release date", deref(old.BIOS).ReleaseDate, deref(cur.BIOS).ReleaseDate},
+		{"baseboard vendor", deref(old.Baseboard).Vendor, deref(cur.Baseboard).Vendor},
+		{"baseboard product", deref(old.Baseboard).Product, deref(cur.Baseboard).Product},
+		{"baseboard version", deref(old.Baseboard).Version, deref(cur.Baseboard).Version},
+		{"product name", deref(old.Product).Name, deref(cur.Product).Name},
+		{"product vendor", deref(old.Product).Vendor, deref(cur.Product).Vendor},
+	}
+	for _, f := range fields {
+		if f.from != f.to {
+			changed(f.what, f.from, f.to)
+		}
+	}
+
+	return changes
+}
+
+// pciDrift returns the PCI devices that were added or removed. Devices are compared as a multiset
+// as identical devices, multiple NICs or GPUs of the same model for example, are common.
+func pciDrift(kind string, old, cur []v1alpha1.InventoryPCIDevice) []string {
+	counts := map[v1alpha1.InventoryPCIDevice]int{}
+	for _, d := range old {
+		counts[d]++
+	}
+	for _, d := range cur {
+		counts[d]--
+	}
+	var changes []string
+	// iterate over the sorted devices for a stable order of changes.
+	for _, d := range append(slices.Clone(old), cur...) {
+		n := counts[d]
+		switch {
+		case n > 0:
+			changes = append(changes, fmt.Sprintf("%s removed: %s", kind, describePCIDevice(d)))
+			counts[d]--
+		case n < 0:
+			changes = append(changes, fmt.Sprintf("%s added: %s", kind, describePCIDevice(d)))
+			counts[d]++
+		}
+	}
+	return changes
+}
+
+func describeBlockDevice(b v1alpha1.InventoryBlockDevice) string {
+	return fmt.Sprintf("vendor %q model %q size %s", b.Vendor, b.Model, quantityString(b.Size))
+}
+
+func describePCIDevice(d v1alpha1.InventoryPCIDevice) string {
+	return fmt.Sprintf("vendor %q product %q class %q", d.Vendor, d.Product, d.Class)
+}
+
+func processorModels(c v1alpha1.InventoryCPU) []string {
+	models := make([]string, 0, len(c.Processors))
+	for _, p := range c.Processors {
+		models = append(models, p.Model)
+	}
+	return models
+}
+
+func quantityString(q *resource.Quantity) string {
+	if q == nil {
+		return "0"
+	}
+	return q.String()
+}
+
+func deref[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
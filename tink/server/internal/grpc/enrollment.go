@@ -0,0 +1,179 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DiscoveredLabel is the label added to Hardware, and Workflows, created by auto-enrollment.
+	DiscoveredLabel = "tinkerbell.org/discovered"
+	// discoveredNamePrefix is the prefix of the name of Hardware, and Workflows, created by auto-enrollment.
+	discoveredNamePrefix = "discovered-"
+	// discoveredDevice is the Template device that is mapped to the worker in Workflows created by auto-enrollment.
+	discoveredDevice = "device_1"
+)
+
+// invalidNameChars matches characters that are not allowed in a Kubernetes object name.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// AutoCapabilities are the automatic capabilities of the Tink server.
+type AutoCapabilities struct {
+	Enrollment AutoEnrollment
+}
+
+// AutoEnrollment creates Hardware, from the attributes a worker sends, for workers that are not known.
+type AutoEnrollment struct {
+	// Enabled turns on auto-enrollment.
+	Enabled bool
+	// Namespace is the namespace in which Hardware and Workflows are created.
+	Namespace string
+	// TemplateRef is the name of a Template used to create a Workflow for enrolled Hardware.
+	// No Workflow is created when empty.
+	TemplateRef string
+	// ReadCreator is the backend used to find and create Hardware and Workflows.
+	ReadCreator AutoEnrollmentReadCreator
+}
+
+// AutoEnrollmentReadCreator is implemented by backends that support auto-enrollment.
+type AutoEnrollmentReadCreator interface {
+	// ReadHardwareByMAC returns the Hardware with an Interface using the MAC address.
+	// The returned error must satisfy apierrors.IsNotFound when no Hardware uses the MAC address.
+	ReadHardwareByMAC(ctx context.Context, mac string) (*v1alpha1.Hardware, error)
+	CreateHardware(ctx context.Context, hw *v1alpha1.Hardware) error
+	CreateWorkflow(ctx context.Context, wf *v1alpha1.Workflow) error
+}
+
+// enroll creates Hardware for a worker that has no Hardware with any of its MAC addresses.
+// When a TemplateRef is configured a Workflow is created for the Hardware as well.
+// The Workflow is created before the Hardware so that a failure is retried on the next request,
+// as a worker with Hardware is no longer enrolled.
+func (h *Handler) enroll(ctx context.Context, workerID string, attrs *proto.WorkerAttributes) error {
+	ae := h.AutoCapabilities.Enrollment
+	if ae.ReadCreator == nil {
+		return nil
+	}
+	macs := workerMACs(attrs)
+	if len(macs) == 0 {
+		// Without MAC addresses there is nothing to identify the Hardware by.
+		return nil
+	}
+	for _, mac := range macs {
+		_, err := ae.ReadCreator.ReadHardwareByMAC(ctx, mac)
+		if err == nil {
+			return nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting hardware: %v", err))
+		}
+	}
+
+	hw := discoveredHardware(discoveredName(workerID), ae.Namespace, macs, attrs)
+	log := h.Logger.WithValues("worker", workerID, "hardware", hw.Name, "namespace", hw.Namespace)
+	if ae.TemplateRef != "" {
+		wf := &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      hw.Name,
+				Namespace: hw.Namespace,
+				Labels:    map[string]string{DiscoveredLabel: "true"},
+			},
+			Spec: v1alpha1.WorkflowSpec{
+				TemplateRef: ae.TemplateRef,
+				HardwareRef: hw.Name,
+				HardwareMap: map[string]string{discoveredDevice: workerID},
+			},
+		}
+		if err := ae.ReadCreator.CreateWorkflow(ctx, wf); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Join(ErrBackendWrite, status.Errorf(codes.Internal, "error creating workflow: %v", err))
+		}
+	}
+	if err := ae.ReadCreator.CreateHardware(ctx, hw); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Join(ErrBackendWrite, status.Errorf(codes.Internal, "error creating hardware: %v", err))
+	}
+	log.Info("enrolled worker", "templateRef", ae.TemplateRef)
+
+	return nil
+}
+
+// workerMACs returns the valid, normalized, MAC addresses of a worker's network interfaces.
+func workerMACs(attrs *proto.WorkerAttributes) []string {
+	macs := []string{}
+	for _, n := range attrs.GetNetwork() {
+		mac, err := net.ParseMAC(n.GetMac())
+		if err != nil {
+			continue
+		}
+		macs = append(macs, mac.String())
+	}
+	return macs
+}
+
+// discoveredName returns a valid Kubernetes object name for a worker.
+func discoveredName(workerID string) string {
+	name := discoveredNamePrefix + strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(workerID), "-"), "-.")
+	if len(name) > 253 {
+		// A name must end with an alphanumeric character.
+		name = strings.TrimRight(name[:253], "-.")
+	}
+	return name
+}
+
+// discoveredHardware returns Hardware built from the attributes of a worker.
+func discoveredHardware(name, namespace string, macs []string, attrs *proto.WorkerAttributes) *v1alpha1.Hardware {
+	hw := &v1alpha1.Hardware{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{DiscoveredLabel: "true"},
+		},
+	}
+	names := map[string]string{}
+	for _, n := range attrs.GetNetwork() {
+		if mac, err := net.ParseMAC(n.GetMac()); err == nil {
+			names[mac.String()] = n.GetName()
+		}
+	}
+	for _, mac := range macs {
+		hw.Spec.Interfaces = append(hw.Spec.Interfaces, v1alpha1.Interface{
+			DHCP: &v1alpha1.DHCP{
+				MAC:       mac,
+				IfaceName: names[mac],
+			},
+			Netboot: &v1alpha1.Netboot{
+				AllowPXE:      toPtr(true),
+				AllowWorkflow: toPtr(true),
+			},
+		})
+	}
+	for _, b := range attrs.GetBlock() {
+		if b.GetName() == "" {
+			continue
+		}
+		hw.Spec.Disks = append(hw.Spec.Disks, v1alpha1.Disk{Device: "/dev/" + b.GetName()})
+	}
+	if threads := attrs.GetCpu().GetTotalThreads(); threads > 0 {
+		if hw.Spec.Resources == nil {
+			hw.Spec.Resources = map[string]resource.Quantity{}
+		}
+		hw.Spec.Resources["cpu"] = *resource.NewQuantity(int64(threads), resource.DecimalSI)
+	}
+	if mem := attrs.GetMemory().GetTotal(); mem > 0 {
+		if hw.Spec.Resources == nil {
+			hw.Spec.Resources = map[string]resource.Quantity{}
+		}
+		hw.Spec.Resources["memory"] = *resource.NewQuantity(int64(mem), resource.BinarySI) // #nosec G115 -- memory size fits in an int64.
+	}
+
+	return hw
+}
@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"slices"
+
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newFailureState returns the on-failure or on-timeout Actions to run because Action (a) in task failed or timed out.
+// The Action's own on-failure or on-timeout command runs first, followed by the Task's on-failure Actions.
+// nil is returned when there is nothing to run.
+func newFailureState(task v1alpha1.Task, a v1alpha1.Action) *v1alpha1.FailureState {
+	actions := []v1alpha1.Action{}
+
+	cmd, suffix := a.OnFailure, "on-failure"
+	if a.State == v1alpha1.WorkflowStateTimeout {
+		cmd, suffix = a.OnTimeout, "on-timeout"
+	}
+	if len(cmd) > 0 {
+		// The command runs with the same image and configuration as the Action that failed.
+		actions = append(actions, v1alpha1.Action{
+			ID:          a.ID + "-" + suffix,
+			Name:        a.Name + "-" + suffix,
+			Image:       a.Image,
+			Timeout:     a.Timeout,
+			Command:     cmd,
+			Volumes:     a.Volumes,
+			Pid:         a.Pid,
+			Network:     a.Network,
+			Environment: a.Environment,
+			State:       v1alpha1.WorkflowStatePending,
+		})
+	}
+	for _, of := range task.OnFailure {
+		of := *of.DeepCopy()
+		of.State = v1alpha1.WorkflowStatePending
+		actions = append(actions, of)
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+
+	return &v1alpha1.FailureState{
+		TaskID:   task.ID,
+		ActionID: a.ID,
+		State:    a.State,
+		Actions:  actions,
+	}
+}
+
+// failureAction returns the next on-failure or on-timeout Action to run for a worker.
+func failureAction(wf *v1alpha1.Workflow, workerID string) (v1alpha1.Task, *v1alpha1.Action, error) {
+	var task *v1alpha1.Task
+	for i := range wf.Status.Tasks {
+		if wf.Status.Tasks[i].ID == wf.Status.Failure.TaskID {
+			task = &wf.Status.Tasks[i]
+			break
+		}
+	}
+	if task == nil {
+		return v1alpha1.Task{}, nil, status.Error(codes.NotFound, "failed task not found")
+	}
+	if task.WorkerAddr != workerID {
+		return v1alpha1.Task{}, nil, status.Error(codes.NotFound, "task not assigned to worker")
+	}
+	for i := range wf.Status.Failure.Actions {
+		switch wf.Status.Failure.Actions[i].State {
+		case v1alpha1.WorkflowStatePending:
+			return *task, &wf.Status.Failure.Actions[i], nil
+		case v1alpha1.WorkflowStateRunning:
+			return v1alpha1.Task{}, nil, status.Error(codes.FailedPrecondition, "on-failure action is running")
+		}
+	}
+
+	return v1alpha1.Task{}, nil, status.Error(codes.NotFound, "no on-failure actions remaining")
+}
+
+// actionWorker returns the worker of the Task, of a Workflow, that runs an Action, including on-failure Actions.
+// It returns false when the Workflow has no such Action.
+func actionWorker(wf *v1alpha1.Workflow, taskID, actionID string) (string, bool) {
+	if f := wf.Status.Failure; f != nil && f.TaskID == taskID && slices.ContainsFunc(f.Actions, func(a v1alpha1.Action) bool { return a.ID == actionID }) {
+		for _, task := range wf.Status.Tasks {
+			if task.ID == f.TaskID {
+				return task.WorkerAddr, true
+			}
+		}
+		return "", false
+	}
+	for _, task := range wf.Status.Tasks {
+		if task.ID == taskID && slices.ContainsFunc(task.Actions, func(a v1alpha1.Action) bool { return a.ID == actionID }) {
+			return task.WorkerAddr, true
+		}
+	}
+
+	return "", false
+}
@@ -41,13 +41,45 @@ type Handler struct {
 	Logger            logr.Logger
 	BackendReadWriter BackendReadWriter
 	NowFunc           func() time.Time
-	AutoCapabilities  bool
+	AutoCapabilities  AutoCapabilities
 	RetryOptions      []backoff.RetryOption
+	// InventoryReadWriter, when set, is used to record the attributes workers send into the status of their Hardware.
+	InventoryReadWriter InventoryReadWriter
+	// EventRecorder, when set, is used to record events, like inventory drift, for Hardware.
+	EventRecorder HardwareEventRecorder
+	// BlockOnInventoryDrift prevents Workflows that haven't started from running on Hardware with an
+	// unacknowledged InventoryDrift condition.
+	BlockOnInventoryDrift bool
+	// ActionLogLines is the number of lines of Action output to keep per Action.
+	// The kept lines are added to the status message of a failed or timed out Action.
+	ActionLogLines int
+	// StreamResyncInterval is how often Workflows are read for workers using StreamActions.
+	// Defaults to 5 seconds, or 1 minute when the backend implements WorkflowWatcher.
+	StreamResyncInterval time.Duration
+	// WorkerTokenSecret, when set, requires callers without a verified client certificate to send
+	// a worker token, derived from this secret, that matches the worker ID they claim.
+	WorkerTokenSecret []byte
+	// AdminToken, when set, enables admin RPCs for callers that send it. Admin RPCs are denied when it is empty.
+	AdminToken string
+
+	actionLogs      logTails
+	inventoryWrites inventoryWrites
 
 	proto.UnimplementedWorkflowServiceServer
 }
 
+// now returns the current time from NowFunc, when set.
+func (h *Handler) now() time.Time {
+	if h.NowFunc != nil {
+		return h.NowFunc()
+	}
+	return time.Now()
+}
+
 func (h *Handler) GetAction(ctx context.Context, req *proto.ActionRequest) (*proto.ActionResponse, error) {
+	if err := h.authorizeWorker(ctx, req.GetWorkerId()); err != nil {
+		return nil, err
+	}
 	operation := func() (*proto.ActionResponse, error) {
 		return h.doGetAction(ctx, req)
 	}
@@ -74,86 +106,113 @@ func (h *Handler) doGetAction(ctx context.Context, req *proto.ActionRequest) (*p
 	default:
 	}
 
-	log := h.Logger.WithValues("worker", req.GetWorkerId())
 	if req.GetWorkerId() == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid worker id:")
 	}
 
-	wflows, err := h.BackendReadWriter.ReadAll(ctx, req.GetWorkerId())
+	wf, task, action, err := h.selectAction(ctx, req.GetWorkerId(), req.GetWorkerAttributes(), req.GetBootId())
+	if err != nil {
+		return nil, err
+	}
+
+	return h.serveAction(ctx, req.GetWorkerId(), wf, task, action)
+}
+
+// NextAction returns the next Action of a worker, and records it as the current state of its Workflow, like GetAction.
+// It is used by front ends, other than gRPC, that push Actions to workers. Workers are not authenticated,
+// the front end must make sure workerID is the worker it serves the Action to.
+// nil is returned for an Action that was served before, to this or another front end, unless resend is true.
+func (h *Handler) NextAction(ctx context.Context, workerID string, resend bool) (*proto.ActionResponse, error) {
+	if workerID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid worker id:")
+	}
+	wf, task, action, err := h.selectAction(ctx, workerID, nil, "")
 	if err != nil {
-		// TODO: This is where we handle auto capabilities
-		return nil, errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting workflows: %v", err))
+		return nil, err
+	}
+	if action.Served && !resend {
+		return nil, nil
+	}
+
+	return h.serveAction(ctx, workerID, wf, task, action)
+}
+
+// selectAction returns the next Action to run for a worker along with the Workflow and Task it belongs to.
+// The Workflow is only modified, and written, to complete a running Action that rebooted the worker, which is
+// known from bootID. The inventory of the worker is recorded and workers without Workflows are enrolled when
+// auto-enrollment is enabled.
+func (h *Handler) selectAction(ctx context.Context, workerID string, attrs *proto.WorkerAttributes, bootID string) (*v1alpha1.Workflow, v1alpha1.Task, *v1alpha1.Action, error) {
+	hw := h.recordInventory(ctx, workerID, attrs)
+	wflows, err := h.BackendReadWriter.ReadAll(ctx, workerID)
+	if err != nil {
+		return nil, v1alpha1.Task{}, nil, errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting workflows: %v", err))
 	}
 	if len(wflows) == 0 {
-		return nil, status.Error(codes.NotFound, "no workflows found")
+		if h.AutoCapabilities.Enrollment.Enabled {
+			if err := h.enroll(ctx, workerID, attrs); err != nil {
+				return nil, v1alpha1.Task{}, nil, err
+			}
+		}
+		return nil, v1alpha1.Task{}, nil, status.Error(codes.NotFound, "no workflows found")
 	}
-	wf := wflows[0]
+	wf := &wflows[0]
 	if len(wf.Status.Tasks) == 0 {
-		return nil, status.Error(codes.NotFound, "no tasks found")
+		return nil, v1alpha1.Task{}, nil, status.Error(codes.NotFound, "no tasks found")
 	}
 	// Don't serve Actions when in a v1alpha1.WorkflowStatePreparing state.
 	// This is to prevent the worker from starting Actions before Workflow boot options are performed.
 	if wf.Spec.BootOptions.BootMode != "" && wf.Status.State == v1alpha1.WorkflowStatePreparing {
-		return nil, status.Error(codes.FailedPrecondition, "workflow is in preparing state")
+		return nil, v1alpha1.Task{}, nil, status.Error(codes.FailedPrecondition, "workflow is in preparing state")
 	}
 	if wf.Status.State != v1alpha1.WorkflowStatePending && wf.Status.State != v1alpha1.WorkflowStateRunning {
-		return nil, status.Error(codes.FailedPrecondition, "workflow not in pending or running state")
+		return nil, v1alpha1.Task{}, nil, status.Error(codes.FailedPrecondition, "workflow not in pending or running state")
 	}
-	// only support workflows with a single task for now
-	task := wf.Status.Tasks[0]
-	if len(task.Actions) == 0 {
-		return nil, status.Error(codes.NotFound, "no actions found")
+	if wf.Spec.Cancel {
+		return nil, v1alpha1.Task{}, nil, status.Error(codes.FailedPrecondition, "workflow is canceled")
 	}
-	if task.WorkerAddr != req.GetWorkerId() {
-		return nil, status.Error(codes.NotFound, "task not assigned to worker")
+	if h.BlockOnInventoryDrift && wf.Status.State == v1alpha1.WorkflowStatePending && hw != nil && hw.Status.HasCondition(v1alpha1.InventoryDrift, metav1.ConditionTrue) {
+		return nil, v1alpha1.Task{}, nil, status.Errorf(codes.FailedPrecondition, "hardware %s has unacknowledged inventory drift", hw.Name)
 	}
+	if err := h.completeRebootAction(ctx, wf, workerID, bootID); err != nil {
+		return nil, v1alpha1.Task{}, nil, err
+	}
+	var task v1alpha1.Task
 	var action *v1alpha1.Action
-	// This is the first action handler
-	if wf.Status.CurrentState == nil {
-		if task.Actions[0].State != v1alpha1.WorkflowStatePending {
-			return nil, status.Error(codes.FailedPrecondition, "first action not in pending state")
-		}
-		action = &task.Actions[0]
+	if wf.Status.Failure != nil {
+		// An Action failed or timed out, only its on-failure or on-timeout Actions are served.
+		task, action, err = failureAction(wf, workerID)
 	} else {
-		// This handles Actions after the first one
-		// Get the current Action. If it is not in a success state, return error.
-		if wf.Status.CurrentState.State != v1alpha1.WorkflowStateSuccess {
-			return nil, status.Error(codes.FailedPrecondition, "current action not in success state")
-		}
-		// Get the next Action after the one defined in the current state.
-		for idx, act := range task.Actions {
-			if act.ID == wf.Status.CurrentState.ActionID {
-				// if the action is the last one in the task, return error
-				if idx == len(task.Actions)-1 {
-					return nil, status.Error(codes.NotFound, "last action in task")
-				}
-				action = &task.Actions[idx+1]
-				break
-			}
-		}
-		if action == nil {
-			return nil, status.Error(codes.NotFound, "no action found")
-		}
+		task, action, err = nextAction(wf, workerID)
+	}
+	if err != nil {
+		return nil, v1alpha1.Task{}, nil, err
 	}
 
+	return wf, task, action, nil
+}
+
+// serveAction records the Action as served, and as the current state of the Workflow, and returns it as an ActionResponse.
+func (h *Handler) serveAction(ctx context.Context, workerID string, wf *v1alpha1.Workflow, task v1alpha1.Task, action *v1alpha1.Action) (*proto.ActionResponse, error) {
+	log := h.Logger.WithValues("worker", workerID)
 	// update the current state
 	// populate the current state and then send the action to the client.
 	wf.Status.CurrentState = &v1alpha1.CurrentState{
-		WorkerID:   req.GetWorkerId(),
+		WorkerID:   workerID,
 		TaskID:     task.ID,
 		ActionID:   action.ID,
 		State:      action.State,
 		ActionName: action.Name,
 	}
+	action.Served = true
 
-	if err := h.BackendReadWriter.Write(ctx, &wf); err != nil {
+	if err := h.BackendReadWriter.Write(ctx, wf); err != nil {
 		return nil, errors.Join(ErrBackendWrite, status.Errorf(codes.Internal, "error writing current state: %v", err))
 	}
 
 	ar := &proto.ActionResponse{
 		WorkflowId: toPtr(wf.Namespace + "/" + wf.Name),
 		TaskId:     toPtr(task.ID),
-		WorkerId:   toPtr(req.GetWorkerId()),
+		WorkerId:   toPtr(workerID),
 		ActionId:   toPtr(action.ID),
 		Name:       toPtr(action.Name),
 		Image:      toPtr(action.Image),
@@ -172,14 +231,191 @@ func (h *Handler) doGetAction(ctx context.Context, req *proto.ActionRequest) (*p
 			sort.Strings(resp)
 			return resp
 		}(),
-		Pid: toPtr(action.Pid),
+		Pid:     toPtr(action.Pid),
+		Network: toPtr(action.Network),
+		Retries: toPtr(action.Retries),
+		Backoff: toPtr(action.Backoff),
+		Reboot:  toPtr(action.Reboot),
+	}
+	// The images of the remaining Actions let the worker pull them while this Action runs.
+	ar.UpcomingImages = upcomingImages(wf, workerID, action)
+	if p := wf.Spec.ImagePolicy; p != nil {
+		ar.ImagePolicy = &proto.ImagePolicy{PublicKeys: p.PublicKeys, Digests: p.Digests}
 	}
 
 	log.Info("sending action", "action", ar, "actionID", action.ID)
 	return ar, nil
 }
 
+// nextAction returns the next Action to run for a worker.
+// Tasks assigned to the worker run in the order they are defined in the Workflow.
+// A Task only runs once all the Tasks it depends on have completed successfully.
+// Tasks assigned to different workers, without dependencies between them, run concurrently.
+func nextAction(wf *v1alpha1.Workflow, workerID string) (v1alpha1.Task, *v1alpha1.Action, error) {
+	assigned := false
+	for ti, task := range wf.Status.Tasks {
+		if task.WorkerAddr != workerID {
+			continue
+		}
+		assigned = true
+		if taskComplete(task) {
+			continue
+		}
+		for _, dep := range task.DependsOn {
+			dt := findTask(wf, dep)
+			if dt == nil {
+				return v1alpha1.Task{}, nil, status.Errorf(codes.FailedPrecondition, "task %q depends on unknown task %q", task.Name, dep)
+			}
+			if !taskComplete(*dt) {
+				return v1alpha1.Task{}, nil, status.Errorf(codes.FailedPrecondition, "task %q waiting on task %q", task.Name, dep)
+			}
+		}
+		for ai, action := range task.Actions {
+			switch action.State {
+			case v1alpha1.WorkflowStateSuccess:
+				continue
+			case v1alpha1.WorkflowStatePending:
+				return task, &wf.Status.Tasks[ti].Actions[ai], nil
+			default:
+				return v1alpha1.Task{}, nil, status.Error(codes.FailedPrecondition, "current action not in success state")
+			}
+		}
+	}
+	if !assigned {
+		return v1alpha1.Task{}, nil, status.Error(codes.NotFound, "task not assigned to worker")
+	}
+
+	return v1alpha1.Task{}, nil, status.Error(codes.NotFound, "no actions remaining for worker")
+}
+
+// Rebooted completes the running Action that rebooted a worker, like GetAction does, when bootID isn't the boot ID
+// the Action was reported running with. It is used by front ends, other than gRPC, where workers announce their
+// boot ID instead of asking for Actions.
+func (h *Handler) Rebooted(ctx context.Context, workerID, bootID string) error {
+	if workerID == "" {
+		return status.Errorf(codes.InvalidArgument, "invalid worker id:")
+	}
+	wflows, err := h.BackendReadWriter.ReadAll(ctx, workerID)
+	if err != nil {
+		return errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting workflows: %v", err))
+	}
+	if len(wflows) == 0 {
+		return nil
+	}
+
+	return h.completeRebootAction(ctx, &wflows[0], workerID, bootID)
+}
+
+// completeRebootAction marks a running Action of the worker that reboots the worker as successful, and writes the
+// Workflow, when the worker has rebooted since it reported the Action running. The worker rebooted when bootID, its
+// current boot ID, isn't the one it reported the Action running with. This happens when the worker rebooted before
+// its report of the success of the Action was written. Without this the Workflow would not continue past the Action.
+func (h *Handler) completeRebootAction(ctx context.Context, wf *v1alpha1.Workflow, workerID, bootID string) error {
+	if bootID == "" || wf.Status.State != v1alpha1.WorkflowStateRunning || wf.Status.Failure != nil || !rebootActionDone(wf, workerID, bootID, h.now()) {
+		return nil
+	}
+	h.Logger.Info("worker rebooted, the running action that reboots it is complete", "worker", workerID)
+	if workflowComplete(wf) {
+		wf.Status.State = v1alpha1.WorkflowStatePost
+	}
+	if err := h.BackendReadWriter.Write(ctx, wf); err != nil {
+		return errors.Join(ErrBackendWrite, status.Errorf(codes.Internal, "error writing workflow: %v", err))
+	}
+
+	return nil
+}
+
+// rebootActionDone marks a running Action of the worker that reboots the worker as successful, when it was reported
+// running with another boot ID than bootID, and returns true when it did. now is the time the Action stopped.
+func rebootActionDone(wf *v1alpha1.Workflow, workerID, bootID string, now time.Time) bool {
+	for ti, task := range wf.Status.Tasks {
+		if task.WorkerAddr != workerID {
+			continue
+		}
+		for ai, action := range task.Actions {
+			if action.State != v1alpha1.WorkflowStateRunning || !reboots(action) || action.BootID == "" || action.BootID == bootID {
+				continue
+			}
+			a := &wf.Status.Tasks[ti].Actions[ai]
+			a.State = v1alpha1.WorkflowStateSuccess
+			a.Message = "worker rebooted"
+			if a.ExecutionStart != nil {
+				a.ExecutionStop = &metav1.Time{Time: now.UTC()}
+				a.ExecutionDuration = a.ExecutionStop.Sub(a.ExecutionStart.Time).String()
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// reboots reports whether an Action reboots the worker once it succeeds, because it is marked so or because it
+// uses the reboot or kexec builtin of the agent.
+func reboots(a v1alpha1.Action) bool {
+	return a.Reboot || a.Image == "builtin://reboot" || a.Image == "builtin://kexec"
+}
+
+// upcomingImages returns the images of the pending Actions that will run on a worker after action,
+// in the order they run and without duplicates or the image of action.
+func upcomingImages(wf *v1alpha1.Workflow, workerID string, action *v1alpha1.Action) []string {
+	seen := map[string]bool{action.Image: true}
+	found := false
+	var images []string
+	for _, task := range wf.Status.Tasks {
+		if task.WorkerAddr != workerID {
+			continue
+		}
+		for _, a := range task.Actions {
+			if a.ID == action.ID {
+				found = true
+				continue
+			}
+			if !found || a.State != v1alpha1.WorkflowStatePending || seen[a.Image] {
+				continue
+			}
+			seen[a.Image] = true
+			images = append(images, a.Image)
+		}
+	}
+
+	return images
+}
+
+// taskComplete returns true when all Actions in a Task completed successfully.
+func taskComplete(task v1alpha1.Task) bool {
+	for _, action := range task.Actions {
+		if action.State != v1alpha1.WorkflowStateSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// workflowComplete returns true when all Tasks in a Workflow completed successfully.
+func workflowComplete(wf *v1alpha1.Workflow) bool {
+	for _, task := range wf.Status.Tasks {
+		if !taskComplete(task) {
+			return false
+		}
+	}
+	return true
+}
+
+// findTask returns the Task with the given name.
+func findTask(wf *v1alpha1.Workflow, name string) *v1alpha1.Task {
+	for i := range wf.Status.Tasks {
+		if wf.Status.Tasks[i].Name == name {
+			return &wf.Status.Tasks[i]
+		}
+	}
+	return nil
+}
+
 func (h *Handler) ReportActionStatus(ctx context.Context, req *proto.ActionStatusRequest) (*proto.ActionStatusResponse, error) {
+	if err := h.authorizeWorker(ctx, req.GetWorkerId()); err != nil {
+		return nil, err
+	}
 	operation := func() (*proto.ActionStatusResponse, error) {
 		return h.doReportActionStatus(ctx, req)
 	}
@@ -199,6 +435,31 @@ func (h *Handler) ReportActionStatus(ctx context.Context, req *proto.ActionStatu
 	return resp, nil
 }
 
+// ReportAction updates the Workflow of an Action with its status, like ReportActionStatus.
+// It is used by front ends, other than gRPC. Workers are not authenticated, the front end must make sure
+// the worker ID of req is the worker that reported the status.
+// Errors of requests that can't succeed, like those of unknown Actions, are not retried.
+func (h *Handler) ReportAction(ctx context.Context, req *proto.ActionStatusRequest) error {
+	operation := func() (*proto.ActionStatusResponse, error) {
+		resp, err := h.doReportActionStatus(ctx, req)
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.NotFound:
+			return nil, backoff.Permanent(err)
+		}
+		return resp, err
+	}
+	opts := h.RetryOptions
+	if len(opts) == 0 {
+		opts = []backoff.RetryOption{
+			backoff.WithMaxElapsedTime(time.Minute * 5),
+			backoff.WithBackOff(backoff.NewExponentialBackOff()),
+		}
+	}
+	_, err := backoff.Retry(ctx, operation, opts...)
+
+	return err
+}
+
 func (h *Handler) doReportActionStatus(ctx context.Context, req *proto.ActionStatusRequest) (*proto.ActionStatusResponse, error) {
 	// 1. Validate the request
 	if req.GetWorkflowId() == "" {
@@ -217,37 +478,51 @@ func (h *Handler) doReportActionStatus(ctx context.Context, req *proto.ActionSta
 		return nil, errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting workflow: %v", err))
 	}
 	// 3. Find the Action in the workflow from the request
+	// On-failure Actions are run by the worker of the Task with the Action that failed.
+	if worker, _ := actionWorker(wf, req.GetTaskId(), req.GetActionId()); wf.Status.Failure != nil && worker == req.GetWorkerId() {
+		for ai, action := range wf.Status.Failure.Actions {
+			if action.ID == req.GetActionId() && wf.Status.Failure.TaskID == req.GetTaskId() {
+				a := &wf.Status.Failure.Actions[ai]
+				h.setActionStatus(a, req)
+				// 4. Write the updated workflow
+				// The Workflow ends in the state of the Action that failed once the last on-failure Action has run,
+				// regardless of the state of the on-failure Actions, unless one of them was canceled.
+				switch {
+				case req.GetActionState() == proto.StateType_CANCELED:
+					wf.Status.State = v1alpha1.WorkflowStateCanceled
+				case len(wf.Status.Failure.Actions) == ai+1 && req.GetActionState() != proto.StateType_RUNNING:
+					wf.Status.State = wf.Status.Failure.State
+				}
+				return h.writeActionStatus(ctx, wf, req, a)
+			}
+		}
+	}
 	for ti, task := range wf.Status.Tasks {
 		for ai, action := range task.Actions {
 			// action IDs match or this is the first action in a task
 			if action.ID == req.GetActionId() && task.WorkerAddr == req.GetWorkerId() {
-				wf.Status.Tasks[ti].Actions[ai].State = v1alpha1.WorkflowState(req.GetActionState().String())
-				wf.Status.Tasks[ti].Actions[ai].ExecutionStart = &metav1.Time{Time: req.GetExecutionStart().AsTime()}
-				wf.Status.Tasks[ti].Actions[ai].ExecutionStop = &metav1.Time{Time: req.GetExecutionStop().AsTime()}
-				wf.Status.Tasks[ti].Actions[ai].ExecutionDuration = req.GetExecutionDuration()
-				wf.Status.Tasks[ti].Actions[ai].Message = req.GetMessage().GetMessage()
+				a := &wf.Status.Tasks[ti].Actions[ai]
+				h.setActionStatus(a, req)
 
 				// 4. Write the updated workflow
-				if req.GetActionState() != proto.StateType_SUCCESS {
-					wf.Status.State = wf.Status.Tasks[ti].Actions[ai].State
+				// A Workflow that already failed or timed out, from an Action in another Task, stays in that state.
+				if req.GetActionState() != proto.StateType_SUCCESS && !terminalState(wf.Status.State) {
+					wf.Status.State = a.State
+				}
+				if (req.GetActionState() == proto.StateType_FAILED || req.GetActionState() == proto.StateType_TIMEOUT) && wf.Status.Failure == nil {
+					// Run any on-failure or on-timeout Actions before the Workflow ends.
+					// An Action whose image failed verification doesn't, its image and so its on-failure command are not trusted.
+					if f := newFailureState(task, *a); f != nil {
+						wf.Status.Failure = f
+						wf.Status.State = v1alpha1.WorkflowStateRunning
+					}
 				}
-				if len(wf.Status.Tasks) == ti+1 && len(task.Actions) == ai+1 && req.GetActionState() == proto.StateType_SUCCESS {
-					// This is the last action in the last task
+				if req.GetActionState() == proto.StateType_SUCCESS && !terminalState(wf.Status.State) && workflowComplete(wf) {
+					// This is the last action of all tasks
 					wf.Status.State = v1alpha1.WorkflowStatePost
 				}
 
-				// update the status current state
-				wf.Status.CurrentState = &v1alpha1.CurrentState{
-					WorkerID:   req.GetWorkerId(),
-					TaskID:     req.GetTaskId(),
-					ActionID:   req.GetActionId(),
-					State:      wf.Status.Tasks[ti].Actions[ai].State,
-					ActionName: req.GetActionName(),
-				}
-				if err := h.BackendReadWriter.Write(ctx, wf); err != nil {
-					return nil, status.Errorf(codes.Internal, "error writing report status: %v", err)
-				}
-				return &proto.ActionStatusResponse{}, nil
+				return h.writeActionStatus(ctx, wf, req, a)
 			}
 		}
 	}
@@ -255,6 +530,70 @@ func (h *Handler) doReportActionStatus(ctx context.Context, req *proto.ActionSta
 	return &proto.ActionStatusResponse{}, status.Error(codes.NotFound, "action not found")
 }
 
+// setActionStatus updates an Action with the status from a request.
+func (h *Handler) setActionStatus(a *v1alpha1.Action, req *proto.ActionStatusRequest) {
+	a.State = v1alpha1.WorkflowState(req.GetActionState().String())
+	a.ExecutionStart = &metav1.Time{Time: req.GetExecutionStart().AsTime()}
+	a.ExecutionStop = &metav1.Time{Time: req.GetExecutionStop().AsTime()}
+	a.ExecutionDuration = req.GetExecutionDuration()
+	a.Message = req.GetMessage().GetMessage()
+	if len(req.GetAttempts()) > 0 {
+		a.Attempts = toAttempts(req.GetAttempts())
+	}
+	if req.GetBootId() != "" {
+		a.BootID = req.GetBootId()
+	}
+	if req.GetActionState() == proto.StateType_FAILED || req.GetActionState() == proto.StateType_TIMEOUT || req.GetActionState() == proto.StateType_VERIFICATION_FAILED {
+		if tail := h.actionLogs.get(actionKey(req.GetWorkflowId(), req.GetTaskId(), req.GetActionId())); tail != "" {
+			a.Message = fmt.Sprintf("%s\n%s", req.GetMessage().GetMessage(), tail)
+		}
+	}
+}
+
+// writeActionStatus updates the current state of the Workflow to the Action (a) and writes the Workflow to the backend.
+func (h *Handler) writeActionStatus(ctx context.Context, wf *v1alpha1.Workflow, req *proto.ActionStatusRequest, a *v1alpha1.Action) (*proto.ActionStatusResponse, error) {
+	// update the status current state
+	wf.Status.CurrentState = &v1alpha1.CurrentState{
+		WorkerID:   req.GetWorkerId(),
+		TaskID:     req.GetTaskId(),
+		ActionID:   req.GetActionId(),
+		State:      a.State,
+		ActionName: req.GetActionName(),
+	}
+	if err := h.BackendReadWriter.Write(ctx, wf); err != nil {
+		return nil, status.Errorf(codes.Internal, "error writing report status: %v", err)
+	}
+	if req.GetActionState() != proto.StateType_RUNNING {
+		h.actionLogs.delete(actionKey(req.GetWorkflowId(), req.GetTaskId(), req.GetActionId()))
+	}
+	return &proto.ActionStatusResponse{}, nil
+}
+
+// terminalState returns true for Workflow states that are not changed by Action status reports.
+func terminalState(s v1alpha1.WorkflowState) bool {
+	return s == v1alpha1.WorkflowStateFailed || s == v1alpha1.WorkflowStateTimeout || s == v1alpha1.WorkflowStateVerificationFailed ||
+		s == v1alpha1.WorkflowStateCanceled
+}
+
+func toAttempts(in []*proto.ActionAttempt) []v1alpha1.ActionAttempt {
+	attempts := make([]v1alpha1.ActionAttempt, 0, len(in))
+	for _, a := range in {
+		attempt := v1alpha1.ActionAttempt{
+			Attempt: a.GetAttempt(),
+			State:   v1alpha1.WorkflowState(a.GetState().String()),
+			Message: a.GetMessage(),
+		}
+		if a.GetExecutionStart() != nil {
+			attempt.ExecutionStart = &metav1.Time{Time: a.GetExecutionStart().AsTime()}
+		}
+		if a.GetExecutionStop() != nil {
+			attempt.ExecutionStop = &metav1.Time{Time: a.GetExecutionStop().AsTime()}
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts
+}
+
 func toPtr[T any](v T) *T {
 	return &v
 }
@@ -2,9 +2,16 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"io"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,10 +21,17 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
 	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"github.com/tinkerbell/tinkerbell/pkg/workertoken"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -84,6 +98,10 @@ func TestGetAction(t *testing.T) {
 				Timeout:     toPtr(int64(5)),
 				Environment: []string{},
 				Pid:         new(string),
+				Network:     new(string),
+				Reboot:      new(bool),
+				Retries:     new(int64),
+				Backoff:     new(int64),
 			},
 			wantErr: nil,
 		},
@@ -101,6 +119,10 @@ func TestGetAction(t *testing.T) {
 				Timeout:     toPtr(int64(300)),
 				Environment: []string{},
 				Pid:         new(string),
+				Network:     new(string),
+				Reboot:      new(bool),
+				Retries:     new(int64),
+				Backoff:     new(int64),
 			},
 			workflow: &v1alpha1.Workflow{
 				ObjectMeta: metav1.ObjectMeta{
@@ -286,6 +308,48 @@ func TestReportActionStatus(t *testing.T) {
 			writeErr:    errors.New("write error"),
 			expectedErr: status.Errorf(codes.Internal, "error writing report status: write error"),
 		},
+		"on-failure action of another worker": {
+			request: &proto.ActionStatusRequest{
+				WorkflowId:  toPtr("default/workflow1"),
+				WorkerId:    toPtr("worker2"),
+				TaskId:      toPtr("task1"),
+				ActionId:    toPtr("cleanup"),
+				ActionState: toPtr(proto.StateType_SUCCESS),
+			},
+			workflow: &v1alpha1.Workflow{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "workflow1",
+					Namespace: "default",
+				},
+				Status: v1alpha1.WorkflowStatus{
+					State: v1alpha1.WorkflowStateRunning,
+					Tasks: []v1alpha1.Task{
+						{
+							ID:         "task1",
+							WorkerAddr: "worker1",
+							Actions: []v1alpha1.Action{
+								{
+									ID:    "action1",
+									State: v1alpha1.WorkflowStateFailed,
+								},
+							},
+						},
+					},
+					Failure: &v1alpha1.FailureState{
+						TaskID:   "task1",
+						ActionID: "action1",
+						State:    v1alpha1.WorkflowStateFailed,
+						Actions: []v1alpha1.Action{
+							{
+								ID:    "cleanup",
+								State: v1alpha1.WorkflowStatePending,
+							},
+						},
+					},
+				},
+			},
+			expectedErr: status.Error(codes.NotFound, "action not found"),
+		},
 	}
 
 	for name, tc := range tests {
@@ -314,3 +378,1700 @@ func TestReportActionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestReportActionStatusWithOutput(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "workflow1",
+			Namespace: "default",
+		},
+		Status: v1alpha1.WorkflowStatus{
+			Tasks: []v1alpha1.Task{
+				{
+					ID: "task1",
+					Actions: []v1alpha1.Action{
+						{
+							ID:    "action1",
+							State: v1alpha1.WorkflowStateRunning,
+						},
+					},
+				},
+			},
+		},
+	}
+	handler := &Handler{
+		BackendReadWriter: &mockBackendReadWriterForReport{workflow: wf},
+		RetryOptions:      []backoff.RetryOption{backoff.WithMaxTries(1)},
+		ActionLogLines:    2,
+	}
+	key := actionKey("default/workflow1", "task1", "action1")
+	handler.actionLogs.append(key, handler.actionLogLines(), time.Now(), "line 1", "line 2", "line 3")
+
+	_, err := handler.ReportActionStatus(context.Background(), &proto.ActionStatusRequest{
+		WorkflowId:  toPtr("default/workflow1"),
+		TaskId:      toPtr("task1"),
+		ActionId:    toPtr("action1"),
+		ActionState: toPtr(proto.StateType_FAILED),
+		Message:     &proto.ActionMessage{Message: toPtr("got non 0 exit status: 1")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "got non 0 exit status: 1\nline 2\nline 3"
+	if diff := cmp.Diff(want, wf.Status.Tasks[0].Actions[0].Message); diff != "" {
+		t.Errorf("unexpected message (-want +got):\n%s", diff)
+	}
+	if got := handler.actionLogs.get(key); got != "" {
+		t.Errorf("expected output to be removed after a final state, got: %q", got)
+	}
+}
+
+type mockLogStream struct {
+	grpc.ServerStream
+	reqs []*proto.ActionLogRequest
+}
+
+func (m *mockLogStream) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockLogStream) Recv() (*proto.ActionLogRequest, error) {
+	if len(m.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := m.reqs[0]
+	m.reqs = m.reqs[1:]
+	return req, nil
+}
+
+func (m *mockLogStream) SendAndClose(_ *proto.ActionLogResponse) error {
+	return nil
+}
+
+func TestStreamActionLogs(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "workflow1", Namespace: "default"},
+		Status: v1alpha1.WorkflowStatus{
+			Tasks: []v1alpha1.Task{
+				{ID: "task1", WorkerAddr: "worker1", Actions: []v1alpha1.Action{{ID: "action1"}}},
+				{ID: "task2", WorkerAddr: "worker2", Actions: []v1alpha1.Action{{ID: "action2"}}},
+			},
+			Failure: &v1alpha1.FailureState{TaskID: "task1", ActionID: "action1", Actions: []v1alpha1.Action{{ID: "cleanup"}}},
+		},
+	}
+	req := func(worker, task, action string, lines ...string) *proto.ActionLogRequest {
+		return &proto.ActionLogRequest{
+			WorkflowId: toPtr("default/workflow1"),
+			WorkerId:   toPtr(worker),
+			TaskId:     toPtr(task),
+			ActionId:   toPtr(action),
+			Lines:      lines,
+		}
+	}
+	tests := map[string]struct {
+		reqs     []*proto.ActionLogRequest
+		wantCode codes.Code
+		wantKey  string
+		wantTail string
+	}{
+		"action of the worker": {
+			reqs:     []*proto.ActionLogRequest{req("worker1", "task1", "action1", "line 1"), req("worker1", "task1", "action1", "line 2")},
+			wantCode: codes.OK,
+			wantKey:  actionKey("default/workflow1", "task1", "action1"),
+			wantTail: "line 1\nline 2",
+		},
+		"on-failure action of the worker": {
+			reqs:     []*proto.ActionLogRequest{req("worker1", "task1", "cleanup", "line 1")},
+			wantCode: codes.OK,
+			wantKey:  actionKey("default/workflow1", "task1", "cleanup"),
+			wantTail: "line 1",
+		},
+		"action of another worker": {
+			reqs:     []*proto.ActionLogRequest{req("worker1", "task2", "action2", "line 1")},
+			wantCode: codes.PermissionDenied,
+			wantKey:  actionKey("default/workflow1", "task2", "action2"),
+		},
+		"unknown action": {
+			reqs:     []*proto.ActionLogRequest{req("worker1", "task1", "action3", "line 1")},
+			wantCode: codes.PermissionDenied,
+			wantKey:  actionKey("default/workflow1", "task1", "action3"),
+		},
+		"another action on the same stream": {
+			reqs:     []*proto.ActionLogRequest{req("worker1", "task1", "action1", "line 1"), req("worker2", "task2", "action2", "line 2")},
+			wantCode: codes.InvalidArgument,
+			wantKey:  actionKey("default/workflow1", "task2", "action2"),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := &Handler{BackendReadWriter: &mockBackendReadWriterForReport{workflow: wf}}
+			err := handler.StreamActionLogs(&mockLogStream{reqs: tt.reqs})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("StreamActionLogs() code = %v, want %v: %v", got, tt.wantCode, err)
+			}
+			if got := handler.actionLogs.get(tt.wantKey); got != tt.wantTail {
+				t.Errorf("got tail %q, want %q", got, tt.wantTail)
+			}
+		})
+	}
+}
+
+type mockBackendStore struct {
+	mu       sync.Mutex
+	workflow *v1alpha1.Workflow
+	// changed, when not nil, is notified on every Write.
+	changed chan struct{}
+}
+
+func (m *mockBackendStore) Read(_ context.Context, _, _ string) (*v1alpha1.Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.workflow.DeepCopy(), nil
+}
+
+func (m *mockBackendStore) ReadAll(_ context.Context, _ string) ([]v1alpha1.Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return []v1alpha1.Workflow{*m.workflow.DeepCopy()}, nil
+}
+
+func (m *mockBackendStore) Write(_ context.Context, wf *v1alpha1.Workflow) error {
+	m.mu.Lock()
+	m.workflow = wf.DeepCopy()
+	m.mu.Unlock()
+	if m.changed != nil {
+		select {
+		case m.changed <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+type mockWatchingBackendStore struct {
+	*mockBackendStore
+}
+
+func (m *mockWatchingBackendStore) WatchWorkflows(_ context.Context, _ string) (<-chan struct{}, error) {
+	return m.changed, nil
+}
+
+type mockActionStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *proto.ActionResponse
+}
+
+func (m *mockActionStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockActionStream) Send(resp *proto.ActionResponse) error {
+	m.sent <- resp
+	return nil
+}
+
+func TestLogTailsEviction(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		fill      int
+		update    string
+		appendAt  time.Duration
+		wantGone  []string
+		wantKept  []string
+		wantTails int
+	}{
+		"expired tails are removed": {
+			fill:      2,
+			appendAt:  2 * maxActionLogAge,
+			wantGone:  []string{"0", "1"},
+			wantTails: 1,
+		},
+		"least recently updated tail is removed at capacity": {
+			fill:      maxActionLogTails,
+			appendAt:  maxActionLogTails * time.Second,
+			wantGone:  []string{"0"},
+			wantKept:  []string{"1"},
+			wantTails: maxActionLogTails,
+		},
+		"updated tail is kept at capacity": {
+			fill:      maxActionLogTails,
+			update:    "0",
+			appendAt:  (maxActionLogTails + 1) * time.Second,
+			wantGone:  []string{"1"},
+			wantKept:  []string{"0"},
+			wantTails: maxActionLogTails,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var l logTails
+			for i := range tt.fill {
+				l.append(strconv.Itoa(i), 1, start.Add(time.Duration(i)*time.Second), "line")
+			}
+			if tt.update != "" {
+				l.append(tt.update, 1, start.Add(time.Duration(tt.fill)*time.Second), "line")
+			}
+			l.append("new", 1, start.Add(tt.appendAt), "line")
+
+			for _, k := range tt.wantGone {
+				if got := l.get(k); got != "" {
+					t.Errorf("expected tail %q to be removed, got: %q", k, got)
+				}
+			}
+			for _, k := range append(tt.wantKept, "new") {
+				if got := l.get(k); got != "line" {
+					t.Errorf("expected tail %q to be kept, got: %q", k, got)
+				}
+			}
+			if got := len(l.tails); got != tt.wantTails {
+				t.Errorf("expected %d tails, got: %d", tt.wantTails, got)
+			}
+		})
+	}
+}
+
+func TestLogTailsLongLines(t *testing.T) {
+	tests := map[string]struct {
+		line string
+		want string
+	}{
+		"short line": {
+			line: "line",
+			want: "line",
+		},
+		"long line": {
+			line: strings.Repeat("x", maxActionLogLineLength+1),
+			want: strings.Repeat("x", maxActionLogLineLength),
+		},
+		"long line cut in a character": {
+			line: strings.Repeat("x", maxActionLogLineLength-1) + "é",
+			want: strings.Repeat("x", maxActionLogLineLength-1),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var l logTails
+			l.append("key", 1, time.Now(), tt.line)
+			if got := l.get("key"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnFailureActions(t *testing.T) {
+	store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Status: v1alpha1.WorkflowStatus{
+			State: v1alpha1.WorkflowStateRunning,
+			Tasks: []v1alpha1.Task{
+				{
+					ID:         "provision",
+					Name:       "provision",
+					WorkerAddr: "machine-mac-1",
+					Actions: []v1alpha1.Action{
+						{ID: "stream", Name: "stream", Image: "image2disk", State: v1alpha1.WorkflowStatePending, OnFailure: []string{"wipefs", "-a", "/dev/sda"}},
+					},
+					OnFailure: []v1alpha1.Action{
+						{ID: "poweroff", Name: "poweroff", Image: "poweroff", State: v1alpha1.WorkflowStatePending},
+					},
+				},
+			},
+		},
+	}}
+	h := &Handler{
+		Logger:            logr.Discard(),
+		BackendReadWriter: store,
+		RetryOptions:      []backoff.RetryOption{backoff.WithMaxTries(1)},
+	}
+	ctx := context.Background()
+	report := func(actionID string, state proto.StateType) {
+		t.Helper()
+		_, err := h.ReportActionStatus(ctx, &proto.ActionStatusRequest{
+			WorkflowId:  toPtr("default/machine1"),
+			WorkerId:    toPtr("machine-mac-1"),
+			TaskId:      toPtr("provision"),
+			ActionId:    toPtr(actionID),
+			ActionState: toPtr(state),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error reporting %s: %v", actionID, err)
+		}
+	}
+	next := func() *proto.ActionResponse {
+		t.Helper()
+		resp, err := h.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr("machine-mac-1")})
+		if err != nil {
+			t.Fatalf("unexpected error getting action: %v", err)
+		}
+		return resp
+	}
+
+	if got := next().GetActionId(); got != "stream" {
+		t.Fatalf("got action %q, want stream", got)
+	}
+	report("stream", proto.StateType_FAILED)
+	if store.workflow.Status.State != v1alpha1.WorkflowStateRunning {
+		t.Fatalf("got workflow state %v, want %v", store.workflow.Status.State, v1alpha1.WorkflowStateRunning)
+	}
+
+	resp := next()
+	if diff := cmp.Diff([]string{"stream-on-failure", "image2disk"}, []string{resp.GetActionId(), resp.GetImage()}); diff != "" {
+		t.Fatalf("unexpected on-failure action (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"wipefs", "-a", "/dev/sda"}, resp.GetCommand()); diff != "" {
+		t.Fatalf("unexpected on-failure command (-want +got):\n%s", diff)
+	}
+	report("stream-on-failure", proto.StateType_FAILED)
+
+	if got := next().GetActionId(); got != "poweroff" {
+		t.Fatalf("got action %q, want poweroff", got)
+	}
+	report("poweroff", proto.StateType_SUCCESS)
+	if store.workflow.Status.State != v1alpha1.WorkflowStateFailed {
+		t.Fatalf("got workflow state %v, want %v", store.workflow.Status.State, v1alpha1.WorkflowStateFailed)
+	}
+}
+
+func TestImageVerificationFailed(t *testing.T) {
+	policy := &v1alpha1.ImagePolicy{
+		PublicKeys: []string{"-----BEGIN PUBLIC KEY-----"},
+		Digests:    map[string]string{"image2disk": "sha256:1234"},
+	}
+	store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Spec:       v1alpha1.WorkflowSpec{ImagePolicy: policy},
+		Status: v1alpha1.WorkflowStatus{
+			State: v1alpha1.WorkflowStateRunning,
+			Tasks: []v1alpha1.Task{
+				{
+					ID:         "provision",
+					Name:       "provision",
+					WorkerAddr: "machine-mac-1",
+					Actions: []v1alpha1.Action{
+						{ID: "stream", Name: "stream", Image: "image2disk", State: v1alpha1.WorkflowStatePending, OnFailure: []string{"wipefs", "-a", "/dev/sda"}},
+					},
+				},
+			},
+		},
+	}}
+	h := &Handler{
+		Logger:            logr.Discard(),
+		BackendReadWriter: store,
+		RetryOptions:      []backoff.RetryOption{backoff.WithMaxTries(1)},
+	}
+	ctx := context.Background()
+
+	resp, err := h.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr("machine-mac-1")})
+	if err != nil {
+		t.Fatalf("unexpected error getting action: %v", err)
+	}
+	if diff := cmp.Diff(&proto.ImagePolicy{PublicKeys: policy.PublicKeys, Digests: policy.Digests}, resp.GetImagePolicy(), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected image policy (-want +got):\n%s", diff)
+	}
+	_, err = h.ReportActionStatus(ctx, &proto.ActionStatusRequest{
+		WorkflowId:  toPtr("default/machine1"),
+		WorkerId:    toPtr("machine-mac-1"),
+		TaskId:      toPtr("provision"),
+		ActionId:    toPtr("stream"),
+		ActionState: toPtr(proto.StateType_VERIFICATION_FAILED),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error reporting status: %v", err)
+	}
+	// The on-failure command of an Action whose image is not trusted doesn't run.
+	if store.workflow.Status.Failure != nil {
+		t.Fatalf("got failure state %+v, want none", store.workflow.Status.Failure)
+	}
+	if store.workflow.Status.State != v1alpha1.WorkflowStateVerificationFailed {
+		t.Fatalf("got workflow state %v, want %v", store.workflow.Status.State, v1alpha1.WorkflowStateVerificationFailed)
+	}
+}
+
+func TestRebootAction(t *testing.T) {
+	tests := map[string]struct {
+		actions       []v1alpha1.Action
+		bootID        string
+		wantAction    string
+		wantErr       codes.Code
+		wantState     v1alpha1.WorkflowState
+		wantFirstDone bool
+	}{
+		"worker rebooted": {
+			actions: []v1alpha1.Action{
+				{ID: "firmware", Name: "firmware", Image: "firmware", State: v1alpha1.WorkflowStateRunning, Reboot: true, BootID: "boot-1"},
+				{ID: "disk", Name: "disk", Image: "disk", State: v1alpha1.WorkflowStatePending},
+			},
+			bootID:        "boot-2",
+			wantAction:    "disk",
+			wantState:     v1alpha1.WorkflowStateRunning,
+			wantFirstDone: true,
+		},
+		"worker rebooted after the last action": {
+			actions: []v1alpha1.Action{
+				{ID: "firmware", Name: "firmware", Image: "firmware", State: v1alpha1.WorkflowStateRunning, Reboot: true, BootID: "boot-1"},
+			},
+			bootID:        "boot-2",
+			wantErr:       codes.NotFound,
+			wantState:     v1alpha1.WorkflowStatePost,
+			wantFirstDone: true,
+		},
+		"worker rebooted by a builtin": {
+			actions: []v1alpha1.Action{
+				{ID: "kexec", Name: "kexec", Image: "builtin://kexec", State: v1alpha1.WorkflowStateRunning, BootID: "boot-1"},
+				{ID: "disk", Name: "disk", Image: "disk", State: v1alpha1.WorkflowStatePending},
+			},
+			bootID:        "boot-2",
+			wantAction:    "disk",
+			wantState:     v1alpha1.WorkflowStateRunning,
+			wantFirstDone: true,
+		},
+		"worker did not reboot": {
+			actions: []v1alpha1.Action{
+				{ID: "firmware", Name: "firmware", Image: "firmware", State: v1alpha1.WorkflowStateRunning, Reboot: true, BootID: "boot-1"},
+				{ID: "disk", Name: "disk", Image: "disk", State: v1alpha1.WorkflowStatePending},
+			},
+			bootID:    "boot-1",
+			wantErr:   codes.FailedPrecondition,
+			wantState: v1alpha1.WorkflowStateRunning,
+		},
+		"worker without a boot ID": {
+			actions: []v1alpha1.Action{
+				{ID: "firmware", Name: "firmware", Image: "firmware", State: v1alpha1.WorkflowStateRunning, Reboot: true, BootID: "boot-1"},
+				{ID: "disk", Name: "disk", Image: "disk", State: v1alpha1.WorkflowStatePending},
+			},
+			wantErr:   codes.FailedPrecondition,
+			wantState: v1alpha1.WorkflowStateRunning,
+		},
+		"running action without reboot": {
+			actions: []v1alpha1.Action{
+				{ID: "firmware", Name: "firmware", Image: "firmware", State: v1alpha1.WorkflowStateRunning, BootID: "boot-1"},
+				{ID: "disk", Name: "disk", Image: "disk", State: v1alpha1.WorkflowStatePending},
+			},
+			bootID:    "boot-2",
+			wantErr:   codes.FailedPrecondition,
+			wantState: v1alpha1.WorkflowStateRunning,
+		},
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.actions[0].ExecutionStart = &metav1.Time{Time: start}
+			store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+				Status: v1alpha1.WorkflowStatus{
+					State: v1alpha1.WorkflowStateRunning,
+					Tasks: []v1alpha1.Task{{ID: "provision", Name: "provision", WorkerAddr: "machine-mac-1", Actions: tt.actions}},
+				},
+			}}
+			h := &Handler{
+				Logger:            logr.Discard(),
+				BackendReadWriter: store,
+				RetryOptions:      []backoff.RetryOption{backoff.WithMaxTries(1)},
+				NowFunc:           func() time.Time { return start.Add(time.Minute) },
+			}
+
+			resp, err := h.GetAction(context.Background(), &proto.ActionRequest{WorkerId: toPtr("machine-mac-1"), BootId: toPtr(tt.bootID)})
+			if status.Code(err) != tt.wantErr {
+				t.Fatalf("got error %v, want code %v", err, tt.wantErr)
+			}
+			if got := resp.GetActionId(); got != tt.wantAction {
+				t.Errorf("got action %q, want %q", got, tt.wantAction)
+			}
+			if store.workflow.Status.State != tt.wantState {
+				t.Errorf("got workflow state %v, want %v", store.workflow.Status.State, tt.wantState)
+			}
+			first := store.workflow.Status.Tasks[0].Actions[0]
+			if done := first.State == v1alpha1.WorkflowStateSuccess; done != tt.wantFirstDone {
+				t.Errorf("first action done = %v, want %v", done, tt.wantFirstDone)
+			}
+			if tt.wantFirstDone && first.ExecutionDuration != time.Minute.String() {
+				t.Errorf("got first action duration %q, want %q", first.ExecutionDuration, time.Minute.String())
+			}
+		})
+	}
+}
+
+func TestNextAction(t *testing.T) {
+	store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Status: v1alpha1.WorkflowStatus{
+			State: v1alpha1.WorkflowStatePending,
+			Tasks: []v1alpha1.Task{{ID: "provision", Name: "provision", WorkerAddr: "machine-mac-1", Actions: []v1alpha1.Action{
+				{ID: "stream", Name: "stream", Image: "stream", State: v1alpha1.WorkflowStatePending},
+				{ID: "kexec", Name: "kexec", Image: "kexec", State: v1alpha1.WorkflowStatePending},
+			}}},
+		},
+	}}
+	h := &Handler{
+		Logger:            logr.Discard(),
+		BackendReadWriter: store,
+		RetryOptions:      []backoff.RetryOption{backoff.WithMaxTries(1)},
+	}
+	ctx := context.Background()
+	next := func(resend bool) string {
+		t.Helper()
+		resp, err := h.NextAction(ctx, "machine-mac-1", resend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.GetActionId()
+	}
+
+	if got := next(false); got != "stream" {
+		t.Fatalf("got action %q, want %q", got, "stream")
+	}
+	// The Action is the current state of the Workflow, it is only returned again when it is resent.
+	if got := next(false); got != "" {
+		t.Errorf("got action %q for an action that was served, want none", got)
+	}
+	if got := next(true); got != "stream" {
+		t.Errorf("got action %q when resending, want %q", got, "stream")
+	}
+
+	report := func(state proto.StateType) {
+		t.Helper()
+		err := h.ReportAction(ctx, &proto.ActionStatusRequest{
+			WorkflowId:  toPtr("default/machine1"),
+			WorkerId:    toPtr("machine-mac-1"),
+			TaskId:      toPtr("provision"),
+			ActionId:    toPtr("stream"),
+			ActionName:  toPtr("stream"),
+			ActionState: toPtr(state),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	report(proto.StateType_RUNNING)
+	if _, err := h.NextAction(ctx, "machine-mac-1", false); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("got error %v while an action runs, want code %v", err, codes.FailedPrecondition)
+	}
+	report(proto.StateType_SUCCESS)
+	if got := next(false); got != "kexec" {
+		t.Errorf("got action %q, want %q", got, "kexec")
+	}
+
+	err := h.ReportAction(ctx, &proto.ActionStatusRequest{
+		WorkflowId:  toPtr("default/machine1"),
+		WorkerId:    toPtr("machine-mac-1"),
+		TaskId:      toPtr("provision"),
+		ActionId:    toPtr("unknown"),
+		ActionState: toPtr(proto.StateType_SUCCESS),
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("got error %v for an unknown action, want code %v", err, codes.NotFound)
+	}
+}
+
+func TestMultiTaskWorkflow(t *testing.T) {
+	store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+		Status: v1alpha1.WorkflowStatus{
+			State: v1alpha1.WorkflowStatePending,
+			Tasks: []v1alpha1.Task{
+				{
+					ID:         "a",
+					Name:       "control-plane",
+					WorkerAddr: "machine-a",
+					Actions:    []v1alpha1.Action{{ID: "a1", Name: "a1", State: v1alpha1.WorkflowStatePending}},
+				},
+				{
+					ID:         "b",
+					Name:       "worker",
+					WorkerAddr: "machine-b",
+					DependsOn:  []string{"control-plane"},
+					Actions:    []v1alpha1.Action{{ID: "b1", Name: "b1", State: v1alpha1.WorkflowStatePending}},
+				},
+			},
+		},
+	}}
+	h := &Handler{
+		Logger:            logr.Discard(),
+		BackendReadWriter: store,
+		RetryOptions:      []backoff.RetryOption{backoff.WithMaxTries(1)},
+	}
+	ctx := context.Background()
+	report := func(worker, task, action string, state proto.StateType) {
+		t.Helper()
+		_, err := h.ReportActionStatus(ctx, &proto.ActionStatusRequest{
+			WorkflowId:  toPtr("default/cluster"),
+			WorkerId:    toPtr(worker),
+			TaskId:      toPtr(task),
+			ActionId:    toPtr(action),
+			ActionState: toPtr(state),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error reporting %s: %v", action, err)
+		}
+	}
+
+	// machine-b must wait for the control-plane task.
+	_, err := h.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr("machine-b")})
+	compareErrors(t, err, status.Errorf(codes.FailedPrecondition, "task %q waiting on task %q", "worker", "control-plane"))
+
+	resp, err := h.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr("machine-a")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GetActionId() != "a1" {
+		t.Fatalf("got action %q, want a1", resp.GetActionId())
+	}
+	report("machine-a", "a", "a1", proto.StateType_RUNNING)
+	report("machine-a", "a", "a1", proto.StateType_SUCCESS)
+	if store.workflow.Status.State == v1alpha1.WorkflowStatePost {
+		t.Fatalf("workflow must not be complete while tasks remain")
+	}
+
+	resp, err = h.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr("machine-b")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GetActionId() != "b1" {
+		t.Fatalf("got action %q, want b1", resp.GetActionId())
+	}
+	report("machine-b", "b", "b1", proto.StateType_SUCCESS)
+	if store.workflow.Status.State != v1alpha1.WorkflowStatePost {
+		t.Fatalf("got workflow state %v, want %v", store.workflow.Status.State, v1alpha1.WorkflowStatePost)
+	}
+}
+
+func TestUpcomingImages(t *testing.T) {
+	wf := &v1alpha1.Workflow{Status: v1alpha1.WorkflowStatus{Tasks: []v1alpha1.Task{
+		{
+			WorkerAddr: "machine-a",
+			Actions: []v1alpha1.Action{
+				{ID: "a1", Image: "image2disk", State: v1alpha1.WorkflowStateSuccess},
+				{ID: "a2", Image: "writefile", State: v1alpha1.WorkflowStatePending},
+				{ID: "a3", Image: "writefile", State: v1alpha1.WorkflowStatePending},
+				{ID: "a4", Image: "cexec", State: v1alpha1.WorkflowStatePending},
+			},
+		},
+		{
+			WorkerAddr: "machine-b",
+			Actions:    []v1alpha1.Action{{ID: "b1", Image: "other", State: v1alpha1.WorkflowStatePending}},
+		},
+		{
+			WorkerAddr: "machine-a",
+			Actions: []v1alpha1.Action{
+				{ID: "c1", Image: "image2disk", State: v1alpha1.WorkflowStatePending},
+				{ID: "c2", Image: "kexec", State: v1alpha1.WorkflowStatePending},
+			},
+		},
+	}}}
+	tests := map[string]struct {
+		worker string
+		action *v1alpha1.Action
+		want   []string
+	}{
+		"first pending action": {
+			worker: "machine-a",
+			action: &wf.Status.Tasks[0].Actions[1],
+			want:   []string{"cexec", "image2disk", "kexec"},
+		},
+		"last action of a task": {
+			worker: "machine-a",
+			action: &wf.Status.Tasks[0].Actions[3],
+			want:   []string{"image2disk", "kexec"},
+		},
+		"last action": {
+			worker: "machine-a",
+			action: &wf.Status.Tasks[2].Actions[1],
+		},
+		"other worker": {
+			worker: "machine-b",
+			action: &wf.Status.Tasks[1].Actions[0],
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := upcomingImages(wf, tt.worker, tt.action)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("upcomingImages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStreamActions(t *testing.T) {
+	store := &mockBackendStore{
+		changed: make(chan struct{}, 1),
+		workflow: &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+			Status: v1alpha1.WorkflowStatus{
+				State: v1alpha1.WorkflowStatePending,
+				Tasks: []v1alpha1.Task{
+					{
+						ID:         "provision",
+						Name:       "provision",
+						WorkerAddr: "machine-mac-1",
+						Actions: []v1alpha1.Action{
+							{ID: "a1", Name: "a1", State: v1alpha1.WorkflowStatePending},
+							{ID: "a2", Name: "a2", State: v1alpha1.WorkflowStatePending},
+						},
+					},
+				},
+			},
+		},
+	}
+	h := &Handler{
+		Logger:               logr.Discard(),
+		BackendReadWriter:    &mockWatchingBackendStore{mockBackendStore: store},
+		RetryOptions:         []backoff.RetryOption{backoff.WithMaxTries(1)},
+		StreamResyncInterval: time.Hour,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &mockActionStream{ctx: ctx, sent: make(chan *proto.ActionResponse)}
+	done := make(chan error)
+	go func() {
+		done <- h.StreamActions(&proto.ActionRequest{WorkerId: toPtr("machine-mac-1")}, stream)
+	}()
+
+	receive := func(want string) {
+		t.Helper()
+		select {
+		case resp := <-stream.sent:
+			if resp.GetActionId() != want {
+				t.Fatalf("got action %q, want %q", resp.GetActionId(), want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for action %q", want)
+		}
+	}
+	receive("a1")
+	// Writing the current state notifies the stream, the same Action must not be sent again.
+	select {
+	case resp := <-stream.sent:
+		t.Fatalf("unexpected action sent: %q", resp.GetActionId())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err := h.ReportActionStatus(ctx, &proto.ActionStatusRequest{
+		WorkflowId:  toPtr("default/machine1"),
+		WorkerId:    toPtr("machine-mac-1"),
+		TaskId:      toPtr("provision"),
+		ActionId:    toPtr("a1"),
+		ActionState: toPtr(proto.StateType_SUCCESS),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	receive("a2")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStreamActionsInvalidWorker(t *testing.T) {
+	h := &Handler{Logger: logr.Discard(), BackendReadWriter: &mockBackendStore{}}
+	err := h.StreamActions(&proto.ActionRequest{}, &mockActionStream{ctx: context.Background()})
+	compareErrors(t, err, status.Errorf(codes.InvalidArgument, "invalid worker id:"))
+}
+
+type mockEnrollmentReadCreator struct {
+	hardware  []*v1alpha1.Hardware
+	workflows []*v1alpha1.Workflow
+}
+
+func (m *mockEnrollmentReadCreator) ReadHardwareByMAC(_ context.Context, mac string) (*v1alpha1.Hardware, error) {
+	for _, hw := range m.hardware {
+		for _, iface := range hw.Spec.Interfaces {
+			if iface.DHCP != nil && iface.DHCP.MAC == mac {
+				return hw, nil
+			}
+		}
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.GroupVersion.WithResource("hardware").GroupResource(), mac)
+}
+
+func (m *mockEnrollmentReadCreator) CreateHardware(_ context.Context, hw *v1alpha1.Hardware) error {
+	m.hardware = append(m.hardware, hw)
+	return nil
+}
+
+func (m *mockEnrollmentReadCreator) CreateWorkflow(_ context.Context, wf *v1alpha1.Workflow) error {
+	m.workflows = append(m.workflows, wf)
+	return nil
+}
+
+var quantityComparer = cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })
+
+func TestAutoEnrollment(t *testing.T) {
+	attrs := &proto.WorkerAttributes{
+		Cpu:    &proto.CPU{TotalThreads: toPtr(uint32(8))},
+		Memory: &proto.Memory{Total: toPtr(uint64(17179869184))},
+		Block:  []*proto.Block{{Name: toPtr("sda")}},
+		Network: []*proto.Network{
+			{Name: toPtr("eth0"), Mac: toPtr("DE:AD:BE:EF:00:01")},
+			{Name: toPtr("lo"), Mac: toPtr("")},
+		},
+	}
+	tests := map[string]struct {
+		attrs             *proto.WorkerAttributes
+		templateRef       string
+		existing          []*v1alpha1.Hardware
+		expectedHardware  []*v1alpha1.Hardware
+		expectedWorkflows []*v1alpha1.Workflow
+	}{
+		"unknown worker": {
+			attrs:       attrs,
+			templateRef: "provision",
+			expectedHardware: []*v1alpha1.Hardware{{
+				ObjectMeta: metav1.ObjectMeta{Name: "discovered-de-ad-be-ef-00-01", Namespace: "tink", Labels: map[string]string{DiscoveredLabel: "true"}},
+				Spec: v1alpha1.HardwareSpec{
+					Interfaces: []v1alpha1.Interface{{
+						DHCP:    &v1alpha1.DHCP{MAC: "de:ad:be:ef:00:01", IfaceName: "eth0"},
+						Netboot: &v1alpha1.Netboot{AllowPXE: toPtr(true), AllowWorkflow: toPtr(true)},
+					}},
+					Disks: []v1alpha1.Disk{{Device: "/dev/sda"}},
+					Resources: map[string]resource.Quantity{
+						"cpu":    resource.MustParse("8"),
+						"memory": resource.MustParse("16Gi"),
+					},
+				},
+			}},
+			expectedWorkflows: []*v1alpha1.Workflow{{
+				ObjectMeta: metav1.ObjectMeta{Name: "discovered-de-ad-be-ef-00-01", Namespace: "tink", Labels: map[string]string{DiscoveredLabel: "true"}},
+				Spec: v1alpha1.WorkflowSpec{
+					TemplateRef: "provision",
+					HardwareRef: "discovered-de-ad-be-ef-00-01",
+					HardwareMap: map[string]string{"device_1": "de:ad:be:ef:00:01"},
+				},
+			}},
+		},
+		"known worker": {
+			attrs: attrs,
+			existing: []*v1alpha1.Hardware{{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine1"},
+				Spec:       v1alpha1.HardwareSpec{Interfaces: []v1alpha1.Interface{{DHCP: &v1alpha1.DHCP{MAC: "de:ad:be:ef:00:01"}}}},
+			}},
+		},
+		"no attributes": {},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &mockEnrollmentReadCreator{hardware: tt.existing}
+			h := &Handler{
+				Logger:            logr.Discard(),
+				BackendReadWriter: &mockBackendReadWriter{},
+				RetryOptions:      []backoff.RetryOption{backoff.WithMaxTries(1)},
+				AutoCapabilities: AutoCapabilities{Enrollment: AutoEnrollment{
+					Enabled:     true,
+					Namespace:   "tink",
+					TemplateRef: tt.templateRef,
+					ReadCreator: rc,
+				}},
+			}
+			_, err := h.GetAction(context.Background(), &proto.ActionRequest{WorkerId: toPtr("de:ad:be:ef:00:01"), WorkerAttributes: tt.attrs})
+			compareErrors(t, err, status.Error(codes.NotFound, "no workflows found"))
+			if diff := cmp.Diff(tt.expectedHardware, rc.hardware[len(tt.existing):], cmpopts.EquateEmpty(), quantityComparer); diff != "" {
+				t.Errorf("unexpected hardware (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.expectedWorkflows, rc.workflows, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("unexpected workflows (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiscoveredName(t *testing.T) {
+	tests := map[string]struct {
+		workerID string
+		want     string
+	}{
+		"mac address": {
+			workerID: "DE:AD:BE:EF:00:01",
+			want:     "discovered-de-ad-be-ef-00-01",
+		},
+		"leading and trailing separators": {
+			workerID: ":worker.",
+			want:     "discovered-worker",
+		},
+		"truncated before a separator": {
+			workerID: strings.Repeat("a", 241) + ".-b",
+			want:     "discovered-" + strings.Repeat("a", 241),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := discoveredName(tt.workerID); got != tt.want {
+				t.Errorf("discoveredName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockInventoryReadWriter struct {
+	hardware *v1alpha1.Hardware
+	writes   int
+	events   []string
+}
+
+func (m *mockInventoryReadWriter) ReadHardwareByMAC(_ context.Context, mac string) (*v1alpha1.Hardware, error) {
+	for _, iface := range m.hardware.Spec.Interfaces {
+		if iface.DHCP != nil && iface.DHCP.MAC == mac {
+			return m.hardware.DeepCopy(), nil
+		}
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.GroupVersion.WithResource("hardware").GroupResource(), mac)
+}
+
+func (m *mockInventoryReadWriter) WriteHardware(_ context.Context, hw *v1alpha1.Hardware) error {
+	m.hardware.ObjectMeta = *hw.ObjectMeta.DeepCopy()
+	return nil
+}
+
+func (m *mockInventoryReadWriter) RecordHardwareEvent(_ *v1alpha1.Hardware, eventType, reason, message string) {
+	m.events = append(m.events, eventType+" "+reason+" "+message)
+}
+
+func (m *mockInventoryReadWriter) WriteHardwareStatus(_ context.Context, hw *v1alpha1.Hardware) error {
+	m.hardware = hw.DeepCopy()
+	m.writes++
+	return nil
+}
+
+func TestRecordInventory(t *testing.T) {
+	attrs := &proto.WorkerAttributes{
+		Memory: &proto.Memory{Total: toPtr(uint64(8589934592))},
+		Block: []*proto.Block{
+			{Name: toPtr("sdb"), Size: toPtr(uint64(1073741824)), Model: toPtr(" SSD ")},
+			{Name: toPtr("sda")},
+		},
+		Network: []*proto.Network{{Name: toPtr("eth0"), Mac: toPtr("DE:AD:BE:EF:00:01"), Speed: toPtr("10Gb/s")}},
+		Gpu: []*proto.GPU{
+			{Vendor: toPtr("nvidia"), Product: toPtr("b")},
+			{Vendor: toPtr("nvidia"), Product: toPtr("a")},
+		},
+		Chassis: &proto.Chassis{Serial: toPtr("ABC123")},
+	}
+	wantInventory := &v1alpha1.Inventory{
+		Memory: &v1alpha1.InventoryMemory{Total: toPtr(resource.MustParse("8Gi"))},
+		BlockDevices: []v1alpha1.InventoryBlockDevice{
+			{Name: "sda"},
+			{Name: "sdb", Size: toPtr(resource.MustParse("1Gi")), Model: "SSD"},
+		},
+		NICs: []v1alpha1.InventoryNIC{{Name: "eth0", MAC: "de:ad:be:ef:00:01", Speed: "10Gb/s"}},
+		GPUs: []v1alpha1.InventoryPCIDevice{
+			{Vendor: "nvidia", Product: "a"},
+			{Vendor: "nvidia", Product: "b"},
+		},
+		Chassis: &v1alpha1.InventoryChassis{Serial: "ABC123"},
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	changed := &proto.WorkerAttributes{
+		Memory:  &proto.Memory{Total: toPtr(uint64(17179869184))},
+		Network: attrs.GetNetwork(),
+	}
+
+	newHardware := func(inv *v1alpha1.Inventory) *v1alpha1.Hardware {
+		hw := &v1alpha1.Hardware{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+			Spec:       v1alpha1.HardwareSpec{Interfaces: []v1alpha1.Interface{{DHCP: &v1alpha1.DHCP{MAC: "de:ad:be:ef:00:01"}}}},
+		}
+		if inv != nil {
+			hw.Status.Inventory = inv.DeepCopy()
+			hw.Status.LastSeen = &metav1.Time{Time: start}
+		}
+		return hw
+	}
+
+	tests := map[string]struct {
+		hardware      *v1alpha1.Hardware
+		writing       bool
+		attrs         *proto.WorkerAttributes
+		after         time.Duration
+		wantWrites    int
+		wantSeen      time.Duration
+		wantInventory *v1alpha1.Inventory
+		wantDrift     metav1.ConditionStatus
+	}{
+		"first report": {
+			hardware:      newHardware(nil),
+			attrs:         attrs,
+			wantWrites:    1,
+			wantInventory: wantInventory,
+		},
+		"unchanged": {
+			hardware:      newHardware(wantInventory),
+			attrs:         attrs,
+			after:         10 * time.Second,
+			wantInventory: wantInventory,
+		},
+		"unchanged after last seen interval": {
+			hardware:      newHardware(wantInventory),
+			attrs:         attrs,
+			after:         2 * time.Minute,
+			wantWrites:    1,
+			wantSeen:      2 * time.Minute,
+			wantInventory: wantInventory,
+		},
+		"changed": {
+			hardware:   newHardware(wantInventory),
+			attrs:      changed,
+			after:      10 * time.Second,
+			wantWrites: 1,
+			wantSeen:   10 * time.Second,
+			wantInventory: &v1alpha1.Inventory{
+				Memory: &v1alpha1.InventoryMemory{Total: toPtr(resource.MustParse("16Gi"))},
+				NICs:   wantInventory.NICs,
+			},
+		},
+		"changed while being written": {
+			hardware:      newHardware(wantInventory),
+			writing:       true,
+			attrs:         changed,
+			after:         10 * time.Second,
+			wantInventory: wantInventory,
+		},
+		"no attributes": {
+			hardware:      newHardware(wantInventory),
+			after:         10 * time.Minute,
+			wantInventory: wantInventory,
+		},
+		"no attributes with acknowledged drift": {
+			hardware: func() *v1alpha1.Hardware {
+				hw := newHardware(wantInventory)
+				hw.Annotations = map[string]string{v1alpha1.InventoryDriftAcknowledgedAnnotation: "true"}
+				hw.Status.Conditions = []v1alpha1.HardwareCondition{{Type: v1alpha1.InventoryDrift, Status: metav1.ConditionTrue}}
+				return hw
+			}(),
+			after:         10 * time.Minute,
+			wantWrites:    1,
+			wantInventory: wantInventory,
+			wantDrift:     metav1.ConditionFalse,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			irw := &mockInventoryReadWriter{hardware: tt.hardware}
+			h := &Handler{
+				Logger:              logr.Discard(),
+				BackendReadWriter:   &mockBackendReadWriter{},
+				RetryOptions:        []backoff.RetryOption{backoff.WithMaxTries(1)},
+				InventoryReadWriter: irw,
+				NowFunc:             func() time.Time { return start.Add(tt.after) },
+			}
+			if tt.writing {
+				h.inventoryWrites.pending = map[string]*v1alpha1.Hardware{"default/machine1": tt.hardware.DeepCopy()}
+			}
+			_, _ = h.GetAction(context.Background(), &proto.ActionRequest{WorkerId: toPtr("de:ad:be:ef:00:01"), WorkerAttributes: tt.attrs})
+			h.inventoryWrites.wait()
+
+			if irw.writes != tt.wantWrites {
+				t.Fatalf("got %d writes, want %d", irw.writes, tt.wantWrites)
+			}
+			if got := irw.hardware.Status.LastSeen.Time; !got.Equal(start.Add(tt.wantSeen)) {
+				t.Fatalf("got last seen %v, want %v", got, start.Add(tt.wantSeen))
+			}
+			if diff := cmp.Diff(tt.wantInventory, irw.hardware.Status.Inventory, quantityComparer); diff != "" {
+				t.Fatalf("unexpected inventory (-want +got):\n%s", diff)
+			}
+			if tt.wantDrift != "" && !irw.hardware.Status.HasCondition(v1alpha1.InventoryDrift, tt.wantDrift) {
+				t.Fatalf("expected %s condition to be %s, got: %+v", v1alpha1.InventoryDrift, tt.wantDrift, irw.hardware.Status.Conditions)
+			}
+			if _, ok := irw.hardware.Annotations[v1alpha1.InventoryDriftAcknowledgedAnnotation]; ok {
+				t.Fatalf("expected the acknowledgement annotation to be removed")
+			}
+		})
+	}
+}
+
+func TestInventoryDrift(t *testing.T) {
+	base := &v1alpha1.Inventory{
+		CPU:    &v1alpha1.InventoryCPU{TotalCores: 8, TotalThreads: 16},
+		Memory: &v1alpha1.InventoryMemory{Total: toPtr(resource.MustParse("32Gi")), Usable: toPtr(resource.MustParse("31Gi"))},
+		BlockDevices: []v1alpha1.InventoryBlockDevice{
+			{Name: "sda", Model: "SSD", Size: toPtr(resource.MustParse("1Ti"))},
+			{Name: "sdb", Model: "HDD"},
+		},
+		NICs:    []v1alpha1.InventoryNIC{{Name: "eth0", MAC: "de:ad:be:ef:00:01", Speed: "10Gb/s"}},
+		GPUs:    []v1alpha1.InventoryPCIDevice{{Vendor: "nvidia", Product: "a"}, {Vendor: "nvidia", Product: "a"}},
+		BIOS:    &v1alpha1.InventoryBIOS{Vendor: "acme", Version: "1.0"},
+		Chassis: &v1alpha1.InventoryChassis{Serial: "ABC123"},
+	}
+	tests := map[string]struct {
+		old    *v1alpha1.Inventory
+		modify func(*v1alpha1.Inventory)
+		want   []string
+	}{
+		"no recorded inventory": {
+			modify: func(i *v1alpha1.Inventory) { i.CPU.TotalCores = 4 },
+		},
+		"no changes": {
+			old:    base,
+			modify: func(*v1alpha1.Inventory) {},
+		},
+		"ignored changes": {
+			old: base,
+			modify: func(i *v1alpha1.Inventory) {
+				i.NICs[0].Speed = "1Gb/s"
+				i.Memory.Usable = toPtr(resource.MustParse("30Gi"))
+			},
+		},
+		"memory removed": {
+			old:    base,
+			modify: func(i *v1alpha1.Inventory) { i.Memory.Total = toPtr(resource.MustParse("16Gi")) },
+			want:   []string{`memory total changed from "32Gi" to "16Gi"`},
+		},
+		"disk swapped and removed": {
+			old: base,
+			modify: func(i *v1alpha1.Inventory) {
+				i.BlockDevices = []v1alpha1.InventoryBlockDevice{{Name: "sda", Model: "NVMe", Size: toPtr(resource.MustParse("1Ti"))}}
+			},
+			want: []string{
+				`block device "sda" changed from vendor "" model "SSD" size 1Ti to vendor "" model "NVMe" size 1Ti`,
+				`block device "sdb" removed: vendor "" model "HDD" size 0`,
+			},
+		},
+		"bios re-flashed": {
+			old:    base,
+			modify: func(i *v1alpha1.Inventory) { i.BIOS.Version = "2.0" },
+			want:   []string{`bios version changed from "1.0" to "2.0"`},
+		},
+		"gpu removed": {
+			old:    base,
+			modify: func(i *v1alpha1.Inventory) { i.GPUs = i.GPUs[:1] },
+			want:   []string{`gpu removed: vendor "nvidia" product "a" class ""`},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cur := base.DeepCopy()
+			tt.modify(cur)
+			got := inventoryDrift(tt.old, cur)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected changes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBlockOnInventoryDrift(t *testing.T) {
+	irw := &mockInventoryReadWriter{hardware: &v1alpha1.Hardware{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Spec:       v1alpha1.HardwareSpec{Interfaces: []v1alpha1.Interface{{DHCP: &v1alpha1.DHCP{MAC: "de:ad:be:ef:00:01"}}}},
+		Status: v1alpha1.HardwareStatus{Inventory: &v1alpha1.Inventory{
+			Memory: &v1alpha1.InventoryMemory{Total: toPtr(resource.MustParse("16Gi"))},
+			NICs:   []v1alpha1.InventoryNIC{{Name: "eth0", MAC: "de:ad:be:ef:00:01"}},
+		}},
+	}}
+	store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Status: v1alpha1.WorkflowStatus{
+			State: v1alpha1.WorkflowStatePending,
+			Tasks: []v1alpha1.Task{{
+				ID:         "provision",
+				Name:       "provision",
+				WorkerAddr: "de:ad:be:ef:00:01",
+				Actions:    []v1alpha1.Action{{ID: "a1", Name: "a1", State: v1alpha1.WorkflowStatePending}},
+			}},
+		},
+	}}
+	h := &Handler{
+		Logger:                logr.Discard(),
+		BackendReadWriter:     store,
+		RetryOptions:          []backoff.RetryOption{backoff.WithMaxTries(1)},
+		InventoryReadWriter:   irw,
+		EventRecorder:         irw,
+		BlockOnInventoryDrift: true,
+	}
+	req := &proto.ActionRequest{
+		WorkerId: toPtr("de:ad:be:ef:00:01"),
+		WorkerAttributes: &proto.WorkerAttributes{
+			Memory:  &proto.Memory{Total: toPtr(uint64(8589934592))},
+			Network: []*proto.Network{{Name: toPtr("eth0"), Mac: toPtr("de:ad:be:ef:00:01")}},
+		},
+	}
+
+	_, err := h.GetAction(context.Background(), req)
+	h.inventoryWrites.wait()
+	compareErrors(t, err, status.Errorf(codes.FailedPrecondition, "hardware %s has unacknowledged inventory drift", "machine1"))
+	if !irw.hardware.Status.HasCondition(v1alpha1.InventoryDrift, metav1.ConditionTrue) {
+		t.Fatalf("expected %s condition to be true, got: %+v", v1alpha1.InventoryDrift, irw.hardware.Status.Conditions)
+	}
+	want := []string{`Warning InventoryDrift memory total changed from "16Gi" to "8Gi"`}
+	if diff := cmp.Diff(want, irw.events); diff != "" {
+		t.Fatalf("unexpected events (-want +got):\n%s", diff)
+	}
+
+	// The new inventory is recorded, the drift is not reported again.
+	_, err = h.GetAction(context.Background(), req)
+	h.inventoryWrites.wait()
+	compareErrors(t, err, status.Errorf(codes.FailedPrecondition, "hardware %s has unacknowledged inventory drift", "machine1"))
+	if len(irw.events) != 1 {
+		t.Fatalf("expected 1 event, got: %v", irw.events)
+	}
+
+	irw.hardware.Annotations = map[string]string{v1alpha1.InventoryDriftAcknowledgedAnnotation: "true"}
+	resp, err := h.GetAction(context.Background(), req)
+	h.inventoryWrites.wait()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GetActionId() != "a1" {
+		t.Fatalf("got action %q, want a1", resp.GetActionId())
+	}
+	if !irw.hardware.Status.HasCondition(v1alpha1.InventoryDrift, metav1.ConditionFalse) {
+		t.Fatalf("expected %s condition to be false, got: %+v", v1alpha1.InventoryDrift, irw.hardware.Status.Conditions)
+	}
+	if _, ok := irw.hardware.Annotations[v1alpha1.InventoryDriftAcknowledgedAnnotation]; ok {
+		t.Fatalf("expected the acknowledgement annotation to be removed")
+	}
+}
+
+func TestNextActionBlockOnInventoryDrift(t *testing.T) {
+	irw := &mockInventoryReadWriter{hardware: &v1alpha1.Hardware{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Spec:       v1alpha1.HardwareSpec{Interfaces: []v1alpha1.Interface{{DHCP: &v1alpha1.DHCP{MAC: "de:ad:be:ef:00:01"}}}},
+		Status: v1alpha1.HardwareStatus{Conditions: []v1alpha1.HardwareCondition{
+			{Type: v1alpha1.InventoryDrift, Status: metav1.ConditionTrue, Reason: "InventoryChanged"},
+		}},
+	}}
+	store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Status: v1alpha1.WorkflowStatus{
+			State: v1alpha1.WorkflowStatePending,
+			Tasks: []v1alpha1.Task{{
+				ID:         "provision",
+				Name:       "provision",
+				WorkerAddr: "de:ad:be:ef:00:01",
+				Actions:    []v1alpha1.Action{{ID: "a1", Name: "a1", State: v1alpha1.WorkflowStatePending}},
+			}},
+		},
+	}}
+	h := &Handler{
+		Logger:                logr.Discard(),
+		BackendReadWriter:     store,
+		RetryOptions:          []backoff.RetryOption{backoff.WithMaxTries(1)},
+		InventoryReadWriter:   irw,
+		BlockOnInventoryDrift: true,
+	}
+
+	// Workers served by NextAction send no attributes, their Hardware is found by their ID.
+	_, err := h.NextAction(context.Background(), "de:ad:be:ef:00:01", false)
+	h.inventoryWrites.wait()
+	compareErrors(t, err, status.Errorf(codes.FailedPrecondition, "hardware %s has unacknowledged inventory drift", "machine1"))
+	if irw.writes != 0 {
+		t.Fatalf("expected no inventory writes, got: %d", irw.writes)
+	}
+
+	// The worker is unblocked once the drift is acknowledged.
+	irw.hardware.Annotations = map[string]string{v1alpha1.InventoryDriftAcknowledgedAnnotation: "true"}
+	resp, err := h.NextAction(context.Background(), "de:ad:be:ef:00:01", false)
+	h.inventoryWrites.wait()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GetActionId() != "a1" {
+		t.Fatalf("got action %q, want a1", resp.GetActionId())
+	}
+	if !irw.hardware.Status.HasCondition(v1alpha1.InventoryDrift, metav1.ConditionFalse) {
+		t.Fatalf("expected %s condition to be false, got: %+v", v1alpha1.InventoryDrift, irw.hardware.Status.Conditions)
+	}
+}
+
+func TestAuthorizeWorker(t *testing.T) {
+	secret := []byte("secret")
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(workertoken.MetadataKey, token))
+	}
+	withCert := func(cert *x509.Certificate) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+		}})
+	}
+	tests := map[string]struct {
+		ctx     context.Context
+		secret  []byte
+		wantErr error
+	}{
+		"no authentication configured": {
+			ctx: context.Background(),
+		},
+		"valid token": {
+			ctx:    withToken(workertoken.New(secret, "machine-mac-1")),
+			secret: secret,
+		},
+		"missing token": {
+			ctx:     context.Background(),
+			secret:  secret,
+			wantErr: status.Errorf(codes.Unauthenticated, "missing worker token"),
+		},
+		"token for another worker": {
+			ctx:     withToken(workertoken.New(secret, "machine-mac-2")),
+			secret:  secret,
+			wantErr: status.Errorf(codes.PermissionDenied, "worker token is not valid for worker %q", "machine-mac-1"),
+		},
+		"certificate common name": {
+			ctx:    withCert(&x509.Certificate{Subject: pkix.Name{CommonName: "machine-mac-1"}}),
+			secret: secret,
+		},
+		"certificate dns san": {
+			ctx: withCert(&x509.Certificate{Subject: pkix.Name{CommonName: "other"}, DNSNames: []string{"machine-mac-1"}}),
+		},
+		"certificate for another worker": {
+			ctx:     withCert(&x509.Certificate{Subject: pkix.Name{CommonName: "machine-mac-2"}}),
+			wantErr: status.Errorf(codes.PermissionDenied, "client certificate is not valid for worker %q", "machine-mac-1"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Handler{WorkerTokenSecret: tt.secret}
+			compareErrors(t, h.authorizeWorker(tt.ctx, "machine-mac-1"), tt.wantErr)
+		})
+	}
+}
+
+type mockCancelingBackendStore struct {
+	*mockBackendStore
+}
+
+func (m *mockCancelingBackendStore) CancelWorkflow(_ context.Context, _, _ string) error {
+	m.mu.Lock()
+	m.workflow.Spec.Cancel = true
+	m.mu.Unlock()
+	if m.changed != nil {
+		select {
+		case m.changed <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *mockCancelingBackendStore) WatchWorkflows(_ context.Context, _ string) (<-chan struct{}, error) {
+	return m.changed, nil
+}
+
+type mockCancelStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *proto.ActionCancelResponse
+}
+
+func (m *mockCancelStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockCancelStream) Send(resp *proto.ActionCancelResponse) error {
+	m.sent <- resp
+	return nil
+}
+
+func TestCancelWorkflow(t *testing.T) {
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(AdminTokenMetadataKey, token))
+	}
+	tests := map[string]struct {
+		ctx        context.Context
+		adminToken string
+		workflowID string
+		state      v1alpha1.WorkflowState
+		noCanceler bool
+		wantErr    error
+		wantCancel bool
+	}{
+		"canceled": {
+			ctx:        withToken("admin"),
+			adminToken: "admin",
+			workflowID: "default/machine1",
+			state:      v1alpha1.WorkflowStateRunning,
+			wantCancel: true,
+		},
+		"admin RPCs disabled": {
+			ctx:        withToken("admin"),
+			workflowID: "default/machine1",
+			state:      v1alpha1.WorkflowStateRunning,
+			wantErr:    status.Errorf(codes.PermissionDenied, "admin RPCs are disabled, the server has no admin token"),
+		},
+		"missing token": {
+			ctx:        context.Background(),
+			adminToken: "admin",
+			workflowID: "default/machine1",
+			state:      v1alpha1.WorkflowStateRunning,
+			wantErr:    status.Errorf(codes.Unauthenticated, "missing admin token"),
+		},
+		"invalid token": {
+			ctx:        withToken("worker"),
+			adminToken: "admin",
+			workflowID: "default/machine1",
+			state:      v1alpha1.WorkflowStateRunning,
+			wantErr:    status.Errorf(codes.PermissionDenied, "admin token is not valid"),
+		},
+		"invalid workflow id": {
+			ctx:        withToken("admin"),
+			adminToken: "admin",
+			workflowID: "machine1",
+			state:      v1alpha1.WorkflowStateRunning,
+			wantErr:    status.Errorf(codes.InvalidArgument, "%s: %q, must be in the form namespace/name", errInvalidWorkflowID, "machine1"),
+		},
+		"backend can't cancel": {
+			ctx:        withToken("admin"),
+			adminToken: "admin",
+			workflowID: "default/machine1",
+			state:      v1alpha1.WorkflowStateRunning,
+			noCanceler: true,
+			wantErr:    status.Error(codes.Unimplemented, "the backend can't cancel workflows"),
+		},
+		"workflow ended": {
+			ctx:        withToken("admin"),
+			adminToken: "admin",
+			workflowID: "default/machine1",
+			state:      v1alpha1.WorkflowStateSuccess,
+			wantErr:    status.Errorf(codes.FailedPrecondition, "workflow already ended in state %s", v1alpha1.WorkflowStateSuccess),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := &mockBackendStore{workflow: &v1alpha1.Workflow{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+				Status:     v1alpha1.WorkflowStatus{State: tt.state},
+			}}
+			h := &Handler{Logger: logr.Discard(), BackendReadWriter: &mockCancelingBackendStore{mockBackendStore: store}, AdminToken: tt.adminToken}
+			if tt.noCanceler {
+				h.BackendReadWriter = store
+			}
+			_, err := h.CancelWorkflow(tt.ctx, &proto.CancelWorkflowRequest{WorkflowId: toPtr(tt.workflowID)})
+			compareErrors(t, err, tt.wantErr)
+			if store.workflow.Spec.Cancel != tt.wantCancel {
+				t.Errorf("spec.cancel = %v, want %v", store.workflow.Spec.Cancel, tt.wantCancel)
+			}
+		})
+	}
+}
+
+func TestCancelRunningAction(t *testing.T) {
+	store := &mockBackendStore{
+		changed: make(chan struct{}, 1),
+		workflow: &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+			Status: v1alpha1.WorkflowStatus{
+				State: v1alpha1.WorkflowStatePending,
+				Tasks: []v1alpha1.Task{
+					{
+						ID:         "provision",
+						Name:       "provision",
+						WorkerAddr: "machine-mac-1",
+						Actions: []v1alpha1.Action{
+							{ID: "a1", Name: "a1", State: v1alpha1.WorkflowStatePending},
+							{ID: "a2", Name: "a2", State: v1alpha1.WorkflowStatePending},
+						},
+					},
+				},
+			},
+		},
+	}
+	h := &Handler{
+		Logger:               logr.Discard(),
+		BackendReadWriter:    &mockCancelingBackendStore{mockBackendStore: store},
+		RetryOptions:         []backoff.RetryOption{backoff.WithMaxTries(1)},
+		StreamResyncInterval: time.Hour,
+		AdminToken:           "admin",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := h.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr("machine-mac-1")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report := func(state proto.StateType) {
+		t.Helper()
+		_, err := h.ReportActionStatus(ctx, &proto.ActionStatusRequest{
+			WorkflowId:  toPtr("default/machine1"),
+			WorkerId:    toPtr("machine-mac-1"),
+			TaskId:      toPtr("provision"),
+			ActionId:    toPtr("a1"),
+			ActionName:  toPtr("a1"),
+			ActionState: toPtr(state),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	report(proto.StateType_RUNNING)
+	if got, err := h.CanceledAction(ctx, "machine-mac-1"); err != nil || got != nil {
+		t.Fatalf("CanceledAction() = %v, %v, want nil before the workflow is canceled", got, err)
+	}
+
+	stream := &mockCancelStream{ctx: ctx, sent: make(chan *proto.ActionCancelResponse, 1)}
+	done := make(chan error)
+	go func() {
+		done <- h.WatchActionCancel(&proto.ActionCancelRequest{
+			WorkflowId: toPtr("default/machine1"),
+			WorkerId:   toPtr("machine-mac-1"),
+			TaskId:     toPtr("provision"),
+			ActionId:   toPtr("a1"),
+		}, stream)
+	}()
+	select {
+	case resp := <-stream.sent:
+		t.Fatalf("unexpected cancellation sent: %v", resp)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	adminCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(AdminTokenMetadataKey, "admin"))
+	if _, err := h.CancelWorkflow(adminCtx, &proto.CancelWorkflowRequest{WorkflowId: toPtr("default/machine1")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &proto.ActionCancelResponse{
+		WorkflowId: toPtr("default/machine1"),
+		TaskId:     toPtr("provision"),
+		ActionId:   toPtr("a1"),
+		Message:    toPtr("workflow canceled"),
+	}
+	select {
+	case resp := <-stream.sent:
+		if diff := cmp.Diff(want, resp, protocmp.Transform()); diff != "" {
+			t.Errorf("unexpected cancellation (-want +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the cancellation")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := h.CanceledAction(ctx, "machine-mac-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected canceled action (-want +got):\n%s", diff)
+	}
+	// No more Actions of a canceled Workflow are served.
+	_, err = h.GetAction(ctx, &proto.ActionRequest{WorkerId: toPtr("machine-mac-1")})
+	compareErrors(t, err, status.Error(codes.FailedPrecondition, "workflow is canceled"))
+
+	report(proto.StateType_CANCELED)
+	if store.workflow.Status.State != v1alpha1.WorkflowStateCanceled {
+		t.Errorf("workflow state = %q, want %q", store.workflow.Status.State, v1alpha1.WorkflowStateCanceled)
+	}
+	if a := store.workflow.Status.Tasks[0].Actions[0]; a.State != v1alpha1.WorkflowStateCanceled {
+		t.Errorf("action state = %q, want %q", a.State, v1alpha1.WorkflowStateCanceled)
+	}
+}
+
+func TestCanceledAction(t *testing.T) {
+	tasks := func(a, b v1alpha1.Action) []v1alpha1.Task {
+		return []v1alpha1.Task{
+			{ID: "a", Name: "a", WorkerAddr: "machine-mac-1", Actions: []v1alpha1.Action{a}},
+			{ID: "b", Name: "b", WorkerAddr: "machine-mac-2", Actions: []v1alpha1.Action{b}},
+		}
+	}
+	tests := map[string]struct {
+		tasks   []v1alpha1.Task
+		failure *v1alpha1.FailureState
+		worker  string
+		want    *proto.ActionCancelResponse
+	}{
+		"running action of the first worker": {
+			tasks: tasks(
+				v1alpha1.Action{ID: "a1", State: v1alpha1.WorkflowStateRunning},
+				v1alpha1.Action{ID: "b1", State: v1alpha1.WorkflowStateRunning},
+			),
+			worker: "machine-mac-1",
+			want:   &proto.ActionCancelResponse{TaskId: toPtr("a"), ActionId: toPtr("a1")},
+		},
+		"running action of the second worker": {
+			tasks: tasks(
+				v1alpha1.Action{ID: "a1", State: v1alpha1.WorkflowStateRunning},
+				v1alpha1.Action{ID: "b1", State: v1alpha1.WorkflowStateRunning},
+			),
+			worker: "machine-mac-2",
+			want:   &proto.ActionCancelResponse{TaskId: toPtr("b"), ActionId: toPtr("b1")},
+		},
+		"served action that hasn't started": {
+			tasks: tasks(
+				v1alpha1.Action{ID: "a1", State: v1alpha1.WorkflowStateRunning},
+				v1alpha1.Action{ID: "b1", State: v1alpha1.WorkflowStatePending, Served: true},
+			),
+			worker: "machine-mac-2",
+			want:   &proto.ActionCancelResponse{TaskId: toPtr("b"), ActionId: toPtr("b1")},
+		},
+		"action that wasn't served": {
+			tasks: tasks(
+				v1alpha1.Action{ID: "a1", State: v1alpha1.WorkflowStateRunning},
+				v1alpha1.Action{ID: "b1", State: v1alpha1.WorkflowStatePending},
+			),
+			worker: "machine-mac-2",
+		},
+		"running on-failure action": {
+			tasks: tasks(
+				v1alpha1.Action{ID: "a1", State: v1alpha1.WorkflowStateFailed},
+				v1alpha1.Action{ID: "b1", State: v1alpha1.WorkflowStateSuccess},
+			),
+			failure: &v1alpha1.FailureState{TaskID: "a", ActionID: "a1", State: v1alpha1.WorkflowStateFailed, Actions: []v1alpha1.Action{
+				{ID: "a1-on-failure", State: v1alpha1.WorkflowStateRunning},
+			}},
+			worker: "machine-mac-1",
+			want:   &proto.ActionCancelResponse{TaskId: toPtr("a"), ActionId: toPtr("a1-on-failure")},
+		},
+		"on-failure action of another worker": {
+			tasks: tasks(
+				v1alpha1.Action{ID: "a1", State: v1alpha1.WorkflowStateFailed},
+				v1alpha1.Action{ID: "b1", State: v1alpha1.WorkflowStateSuccess},
+			),
+			failure: &v1alpha1.FailureState{TaskID: "a", ActionID: "a1", State: v1alpha1.WorkflowStateFailed, Actions: []v1alpha1.Action{
+				{ID: "a1-on-failure", State: v1alpha1.WorkflowStateRunning},
+			}},
+			worker: "machine-mac-2",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Handler{
+				Logger: logr.Discard(),
+				BackendReadWriter: &mockBackendStore{workflow: &v1alpha1.Workflow{
+					ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+					Spec:       v1alpha1.WorkflowSpec{Cancel: true},
+					Status:     v1alpha1.WorkflowStatus{State: v1alpha1.WorkflowStateRunning, Tasks: tt.tasks, Failure: tt.failure},
+				}},
+			}
+			if tt.want != nil {
+				tt.want.WorkflowId = toPtr("default/machine1")
+				tt.want.Message = toPtr(canceledMessage)
+			}
+			got, err := h.CanceledAction(context.Background(), tt.worker)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected canceled action (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWatchActionCancelOtherWorker(t *testing.T) {
+	h := &Handler{
+		Logger: logr.Discard(),
+		BackendReadWriter: &mockBackendStore{workflow: &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+			Spec:       v1alpha1.WorkflowSpec{Cancel: true},
+			Status: v1alpha1.WorkflowStatus{
+				State: v1alpha1.WorkflowStateRunning,
+				Tasks: []v1alpha1.Task{{ID: "provision", Name: "provision", WorkerAddr: "machine-mac-1", Actions: []v1alpha1.Action{
+					{ID: "a1", Name: "a1", State: v1alpha1.WorkflowStateRunning},
+				}}},
+			},
+		}},
+		StreamResyncInterval: time.Hour,
+	}
+	stream := &mockCancelStream{ctx: context.Background(), sent: make(chan *proto.ActionCancelResponse, 1)}
+	err := h.WatchActionCancel(&proto.ActionCancelRequest{
+		WorkflowId: toPtr("default/machine1"),
+		WorkerId:   toPtr("machine-mac-2"),
+		TaskId:     toPtr("provision"),
+		ActionId:   toPtr("a1"),
+	}, stream)
+	compareErrors(t, err, status.Errorf(codes.PermissionDenied, "action %q of task %q is not run by worker %q", "a1", "provision", "machine-mac-2"))
+	if len(stream.sent) != 0 {
+		t.Errorf("unexpected cancellation sent: %v", <-stream.sent)
+	}
+}
@@ -0,0 +1,313 @@
+package grpc
+
+import (
+	"cmp"
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// inventoryLastSeenInterval is how often the last seen time of Hardware is updated when its inventory hasn't changed.
+// This bounds the number of writes to the backend from workers that request Actions frequently.
+const inventoryLastSeenInterval = time.Minute
+
+// inventoryWriteTimeout bounds the time spent writing the inventory of Hardware in the background.
+const inventoryWriteTimeout = 30 * time.Second
+
+// InventoryReadWriter is implemented by backends that can record the inventory of Hardware.
+type InventoryReadWriter interface {
+	// ReadHardwareByMAC returns the Hardware with an Interface using the MAC address.
+	// The returned error must satisfy apierrors.IsNotFound when no Hardware uses the MAC address.
+	ReadHardwareByMAC(ctx context.Context, mac string) (*v1alpha1.Hardware, error)
+	// WriteHardware updates the metadata and spec of Hardware.
+	WriteHardware(ctx context.Context, hw *v1alpha1.Hardware) error
+	WriteHardwareStatus(ctx context.Context, hw *v1alpha1.Hardware) error
+}
+
+// recordInventory records the attributes of a worker, as an inventory, in the status of its Hardware.
+// The status is only written when the inventory changed or the last seen time is older than inventoryLastSeenInterval.
+// Differences with the recorded inventory set the InventoryDrift condition and are recorded as an event.
+// The Hardware of the worker is returned, with its updated status, nil when it's not found.
+// The Hardware is written in the background so that serving Actions doesn't wait on the backend. While it's
+// being written the Hardware being written is returned and the attributes are not recorded again.
+// Workers that don't send attributes, like the ones served by NextAction, have no inventory recorded. Their Hardware,
+// found when their ID is a MAC address, is still returned, with an acknowledged drift cleared, so that its
+// InventoryDrift condition applies to them.
+// Errors are logged and not returned as recording the inventory must not prevent serving Actions.
+func (h *Handler) recordInventory(ctx context.Context, workerID string, attrs *proto.WorkerAttributes) *v1alpha1.Hardware {
+	if h.InventoryReadWriter == nil {
+		return nil
+	}
+	log := h.Logger.WithValues("worker", workerID)
+	hw, err := h.workerHardware(ctx, workerID, attrs)
+	if err != nil {
+		log.Info("unable to get hardware for inventory", "error", err)
+		return nil
+	}
+	if hw == nil {
+		return nil
+	}
+	if pending := h.inventoryWrites.get(hw); pending != nil {
+		return pending
+	}
+	log = log.WithValues("hardware", hw.Name, "namespace", hw.Namespace)
+
+	now := h.now()
+	updated := hw.DeepCopy()
+	conditionChanged := false
+	_, acknowledged := updated.Annotations[v1alpha1.InventoryDriftAcknowledgedAnnotation]
+	if acknowledged {
+		delete(updated.Annotations, v1alpha1.InventoryDriftAcknowledgedAnnotation)
+		if updated.Status.HasCondition(v1alpha1.InventoryDrift, metav1.ConditionTrue) {
+			updated.Status.SetCondition(v1alpha1.HardwareCondition{
+				Type:    v1alpha1.InventoryDrift,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Acknowledged",
+				Message: "inventory drift acknowledged",
+				Time:    &metav1.Time{Time: now},
+			})
+			conditionChanged = true
+		}
+	}
+	if attrs == nil {
+		if acknowledged {
+			h.writeHardware(ctx, log, updated, true)
+		}
+		return updated
+	}
+
+	inv := toInventory(attrs)
+	if changes := inventoryDrift(updated.Status.Inventory, inv); len(changes) > 0 {
+		msg := strings.Join(changes, "; ")
+		updated.Status.SetCondition(v1alpha1.HardwareCondition{
+			Type:    v1alpha1.InventoryDrift,
+			Status:  metav1.ConditionTrue,
+			Reason:  "InventoryChanged",
+			Message: msg,
+			Time:    &metav1.Time{Time: now},
+		})
+		conditionChanged = true
+		log.Info("hardware inventory drift detected", "changes", changes)
+		if h.EventRecorder != nil {
+			h.EventRecorder.RecordHardwareEvent(updated, corev1.EventTypeWarning, string(v1alpha1.InventoryDrift), msg)
+		}
+	}
+	if !acknowledged && !conditionChanged && apiequality.Semantic.DeepEqual(updated.Status.Inventory, inv) && updated.Status.LastSeen != nil && now.Sub(updated.Status.LastSeen.Time) < inventoryLastSeenInterval {
+		return updated
+	}
+	updated.Status.Inventory = inv
+	updated.Status.LastSeen = &metav1.Time{Time: now}
+	h.writeHardware(ctx, log, updated, acknowledged)
+
+	return updated
+}
+
+// writeHardware writes the status of hw in the background and, when acknowledged, its metadata first to remove
+// the acknowledgement annotation.
+func (h *Handler) writeHardware(ctx context.Context, log logr.Logger, hw *v1alpha1.Hardware, acknowledged bool) {
+	h.inventoryWrites.start(hw, func(hw *v1alpha1.Hardware) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), inventoryWriteTimeout)
+		defer cancel()
+		if acknowledged {
+			// Writing the metadata updates the resource version that the status is written with.
+			meta := hw.DeepCopy()
+			if err := h.InventoryReadWriter.WriteHardware(ctx, meta); err != nil {
+				log.Info("unable to acknowledge inventory drift", "error", err)
+				return
+			}
+			hw.ResourceVersion = meta.ResourceVersion
+		}
+		if err := h.InventoryReadWriter.WriteHardwareStatus(ctx, hw); err != nil {
+			log.Info("unable to write hardware inventory", "error", err)
+		}
+	})
+}
+
+// inventoryWrites runs the writes of Hardware inventories, at most one at a time per Hardware.
+type inventoryWrites struct {
+	mu      sync.Mutex
+	pending map[string]*v1alpha1.Hardware
+	wg      sync.WaitGroup
+}
+
+// start runs write with a copy of hw in a new goroutine, unless a write of hw is already running.
+func (w *inventoryWrites) start(hw *v1alpha1.Hardware, write func(*v1alpha1.Hardware)) {
+	key := hw.Namespace + "/" + hw.Name
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.pending[key]; ok {
+		return
+	}
+	if w.pending == nil {
+		w.pending = make(map[string]*v1alpha1.Hardware)
+	}
+	w.pending[key] = hw.DeepCopy()
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		write(hw.DeepCopy())
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.pending, key)
+	}()
+}
+
+// get returns a copy of the Hardware being written for hw, nil when it isn't being written.
+func (w *inventoryWrites) get(hw *v1alpha1.Hardware) *v1alpha1.Hardware {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if p, ok := w.pending[hw.Namespace+"/"+hw.Name]; ok {
+		return p.DeepCopy()
+	}
+	return nil
+}
+
+// wait returns once all started writes are done.
+func (w *inventoryWrites) wait() {
+	w.wg.Wait()
+}
+
+// workerHardware returns the Hardware of a worker by its ID, when the ID is a MAC address, or the MAC addresses of its network interfaces.
+// nil is returned, without an error, when there is no Hardware for the worker.
+func (h *Handler) workerHardware(ctx context.Context, workerID string, attrs *proto.WorkerAttributes) (*v1alpha1.Hardware, error) {
+	macs := workerMACs(attrs)
+	if mac, err := net.ParseMAC(workerID); err == nil {
+		macs = append([]string{mac.String()}, macs...)
+	}
+	for _, mac := range macs {
+		hw, err := h.InventoryReadWriter.ReadHardwareByMAC(ctx, mac)
+		if err == nil {
+			return hw, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// toInventory returns the normalised inventory of a worker's attributes.
+// Strings are trimmed, MAC addresses are lower case, and lists are sorted so that
+// the same hardware always results in the same inventory.
+func toInventory(attrs *proto.WorkerAttributes) *v1alpha1.Inventory {
+	inv := &v1alpha1.Inventory{}
+	if c := attrs.GetCpu(); c != nil {
+		inv.CPU = &v1alpha1.InventoryCPU{
+			TotalCores:   int64(c.GetTotalCores()),
+			TotalThreads: int64(c.GetTotalThreads()),
+		}
+		for _, p := range c.GetProcessors() {
+			inv.CPU.Processors = append(inv.CPU.Processors, v1alpha1.InventoryProcessor{
+				ID:      int64(p.GetId()),
+				Cores:   int64(p.GetCores()),
+				Threads: int64(p.GetThreads()),
+				Vendor:  strings.TrimSpace(p.GetVendor()),
+				Model:   strings.TrimSpace(p.GetModel()),
+			})
+		}
+		slices.SortFunc(inv.CPU.Processors, func(a, b v1alpha1.InventoryProcessor) int { return cmp.Compare(a.ID, b.ID) })
+	}
+	if m := attrs.GetMemory(); m != nil {
+		inv.Memory = &v1alpha1.InventoryMemory{
+			Total:  bytesQuantity(m.GetTotal()),
+			Usable: bytesQuantity(m.GetUsable()),
+		}
+	}
+	for _, b := range attrs.GetBlock() {
+		inv.BlockDevices = append(inv.BlockDevices, v1alpha1.InventoryBlockDevice{
+			Name:              strings.TrimSpace(b.GetName()),
+			ControllerType:    strings.TrimSpace(b.GetControllerType()),
+			DriveType:         strings.TrimSpace(b.GetDriveType()),
+			Size:              bytesQuantity(b.GetSize()),
+			PhysicalBlockSize: int64(b.GetPhysicalBlockSize()), // #nosec G115 -- block sizes fit in an int64.
+			Vendor:            strings.TrimSpace(b.GetVendor()),
+			Model:             strings.TrimSpace(b.GetModel()),
+		})
+	}
+	slices.SortFunc(inv.BlockDevices, func(a, b v1alpha1.InventoryBlockDevice) int { return cmp.Compare(a.Name, b.Name) })
+	for _, n := range attrs.GetNetwork() {
+		nic := v1alpha1.InventoryNIC{
+			Name:  strings.TrimSpace(n.GetName()),
+			Speed: strings.TrimSpace(n.GetSpeed()),
+		}
+		if mac, err := net.ParseMAC(n.GetMac()); err == nil {
+			nic.MAC = mac.String()
+		}
+		inv.NICs = append(inv.NICs, nic)
+	}
+	slices.SortFunc(inv.NICs, func(a, b v1alpha1.InventoryNIC) int { return cmp.Compare(a.Name, b.Name) })
+	for _, p := range attrs.GetPci() {
+		inv.PCIDevices = append(inv.PCIDevices, toInventoryPCIDevice(p.GetVendor(), p.GetProduct(), p.GetClass(), p.GetDriver()))
+	}
+	slices.SortFunc(inv.PCIDevices, comparePCIDevices)
+	for _, g := range attrs.GetGpu() {
+		inv.GPUs = append(inv.GPUs, toInventoryPCIDevice(g.GetVendor(), g.GetProduct(), g.GetClass(), g.GetDriver()))
+	}
+	slices.SortFunc(inv.GPUs, comparePCIDevices)
+	if c := attrs.GetChassis(); c != nil {
+		inv.Chassis = &v1alpha1.InventoryChassis{
+			Serial: strings.TrimSpace(c.GetSerial()),
+			Vendor: strings.TrimSpace(c.GetVendor()),
+		}
+	}
+	if b := attrs.GetBios(); b != nil {
+		inv.BIOS = &v1alpha1.InventoryBIOS{
+			Vendor:      strings.TrimSpace(b.GetVendor()),
+			Version:     strings.TrimSpace(b.GetVersion()),
+			ReleaseDate: strings.TrimSpace(b.GetReleaseDate()),
+		}
+	}
+	if b := attrs.GetBaseboard(); b != nil {
+		inv.Baseboard = &v1alpha1.InventoryBaseboard{
+			Vendor:  strings.TrimSpace(b.GetVendor()),
+			Product: strings.TrimSpace(b.GetProduct()),
+			Version: strings.TrimSpace(b.GetVersion()),
+		}
+	}
+	if p := attrs.GetProduct(); p != nil {
+		inv.Product = &v1alpha1.InventoryProduct{
+			Name:   strings.TrimSpace(p.GetName()),
+			Vendor: strings.TrimSpace(p.GetVendor()),
+		}
+	}
+
+	return inv
+}
+
+func toInventoryPCIDevice(vendor, product, class, driver string) v1alpha1.InventoryPCIDevice {
+	return v1alpha1.InventoryPCIDevice{
+		Vendor:  strings.TrimSpace(vendor),
+		Product: strings.TrimSpace(product),
+		Class:   strings.TrimSpace(class),
+		Driver:  strings.TrimSpace(driver),
+	}
+}
+
+func comparePCIDevices(a, b v1alpha1.InventoryPCIDevice) int {
+	return cmp.Or(
+		cmp.Compare(a.Class, b.Class),
+		cmp.Compare(a.Vendor, b.Vendor),
+		cmp.Compare(a.Product, b.Product),
+		cmp.Compare(a.Driver, b.Driver),
+	)
+}
+
+// bytesQuantity returns a quantity for a number of bytes, nil when there are no bytes.
+func bytesQuantity(b uint64) *resource.Quantity {
+	if b == 0 {
+		return nil
+	}
+	return resource.NewQuantity(int64(b), resource.BinarySI) // #nosec G115 -- byte sizes fit in an int64.
+}
@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultActionLogLines is the number of lines of Action output kept per Action when Handler.ActionLogLines is not set.
+	DefaultActionLogLines = 20
+	// maxActionLogLineLength is the max length of a single line of Action output that is kept.
+	// Longer lines are truncated. This, along with the line count, keeps the Workflow object size bounded.
+	maxActionLogLineLength = 512
+	// maxActionLogTails is the max number of Actions whose output is kept.
+	// The output of the Action updated least recently is dropped to keep the output of a new Action.
+	maxActionLogTails = 1024
+	// maxActionLogAge is how long the output of an Action is kept after its last update.
+	// Output is removed when an Action reaches a final state, this drops the output of Actions that never do.
+	maxActionLogAge = time.Hour
+)
+
+// StreamActionLogs receives the output of an Action from a worker and keeps a bounded tail of it.
+// The tail is added to the Action's status message when the Action is reported as failed or timed out.
+// A stream carries the output of a single Action, of a Task of the worker, which is checked on its first request.
+func (h *Handler) StreamActionLogs(stream grpc.ClientStreamingServer[proto.ActionLogRequest, proto.ActionLogResponse]) error {
+	var worker, key string
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return stream.SendAndClose(&proto.ActionLogResponse{})
+			}
+			return err
+		}
+		if req.GetWorkflowId() == "" {
+			return status.Errorf(codes.InvalidArgument, errInvalidWorkflowID)
+		}
+		if req.GetTaskId() == "" {
+			return status.Errorf(codes.InvalidArgument, errInvalidTaskName)
+		}
+		if req.GetActionId() == "" {
+			return status.Errorf(codes.InvalidArgument, errInvalidActionName)
+		}
+		if key == "" {
+			if err := h.authorizeActionLogs(stream.Context(), req); err != nil {
+				return err
+			}
+			worker, key = req.GetWorkerId(), actionKey(req.GetWorkflowId(), req.GetTaskId(), req.GetActionId())
+		} else if req.GetWorkerId() != worker || actionKey(req.GetWorkflowId(), req.GetTaskId(), req.GetActionId()) != key {
+			return status.Errorf(codes.InvalidArgument, "a log stream carries the output of a single action")
+		}
+		h.actionLogs.append(key, h.actionLogLines(), h.now(), req.GetLines()...)
+	}
+}
+
+// authorizeActionLogs returns an error unless the worker of req is authenticated and runs the Action of req.
+func (h *Handler) authorizeActionLogs(ctx context.Context, req *proto.ActionLogRequest) error {
+	if err := h.authorizeWorker(ctx, req.GetWorkerId()); err != nil {
+		return err
+	}
+	namespace, name, _ := strings.Cut(req.GetWorkflowId(), "/")
+	wf, err := h.BackendReadWriter.Read(ctx, name, namespace)
+	if err != nil {
+		return errors.Join(ErrBackendRead, status.Errorf(codes.Internal, "error getting workflow: %v", err))
+	}
+	if worker, ok := actionWorker(wf, req.GetTaskId(), req.GetActionId()); !ok || worker != req.GetWorkerId() {
+		return status.Errorf(codes.PermissionDenied, "action %q of task %q is not run by worker %q", req.GetActionId(), req.GetTaskId(), req.GetWorkerId())
+	}
+
+	return nil
+}
+
+func (h *Handler) actionLogLines() int {
+	if h.ActionLogLines > 0 {
+		return h.ActionLogLines
+	}
+	return DefaultActionLogLines
+}
+
+// actionKey uniquely identifies an Action across Workflows.
+func actionKey(workflowID, taskID, actionID string) string {
+	return workflowID + "/" + taskID + "/" + actionID
+}
+
+// logTails holds a bounded tail of output lines per Action, for a bounded number of Actions.
+type logTails struct {
+	mu    sync.Mutex
+	tails map[string]*logTail
+}
+
+type logTail struct {
+	lines   []string
+	updated time.Time
+}
+
+// append adds lines to the tail for key, dropping the oldest lines so that at most max lines are kept.
+// Tails not updated for maxActionLogAge are removed and, when maxActionLogTails are kept, the least recently
+// updated tail is removed to make room for a new key.
+func (l *logTails) append(key string, maxLines int, now time.Time, lines ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tails == nil {
+		l.tails = make(map[string]*logTail)
+	}
+	tail, ok := l.tails[key]
+	if !ok {
+		l.evict(now)
+		tail = &logTail{}
+		l.tails[key] = tail
+	}
+	for _, line := range lines {
+		if len(line) > maxActionLogLineLength {
+			// Cut at the start of a character so that the line stays valid UTF-8.
+			n := maxActionLogLineLength
+			for n > 0 && !utf8.RuneStart(line[n]) {
+				n--
+			}
+			line = line[:n]
+		}
+		tail.lines = append(tail.lines, line)
+	}
+	if len(tail.lines) > maxLines {
+		tail.lines = append([]string(nil), tail.lines[len(tail.lines)-maxLines:]...)
+	}
+	tail.updated = now
+}
+
+// evict removes the tails not updated for maxActionLogAge and, when at least maxActionLogTails
+// are left, the least recently updated one. l.mu must be held.
+func (l *logTails) evict(now time.Time) {
+	var oldest string
+	for k, t := range l.tails {
+		if now.Sub(t.updated) > maxActionLogAge {
+			delete(l.tails, k)
+			continue
+		}
+		if oldest == "" || t.updated.Before(l.tails[oldest].updated) {
+			oldest = k
+		}
+	}
+	if len(l.tails) >= maxActionLogTails {
+		delete(l.tails, oldest)
+	}
+}
+
+// get returns the tail for key, joined by new lines.
+func (l *logTails) get(key string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t, ok := l.tails[key]; ok {
+		return strings.Join(t.lines, "\n")
+	}
+	return ""
+}
+
+// delete removes the tail for key.
+func (l *logTails) delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.tails, key)
+}
@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultStreamPollInterval is how often Workflows are read for a streaming worker when the backend can't watch Workflows.
+	defaultStreamPollInterval = 5 * time.Second
+	// defaultStreamResyncInterval is how often Workflows are read for a streaming worker when the backend watches Workflows.
+	// This is a safety net for missed notifications.
+	defaultStreamResyncInterval = time.Minute
+)
+
+// WorkflowWatcher is implemented by backends that can notify when the Workflows assigned to a worker change.
+// When the backend doesn't implement it, StreamActions falls back to polling the backend.
+type WorkflowWatcher interface {
+	// WatchWorkflows returns a channel that receives a value when a Workflow with a Task assigned to workerID changes.
+	// The watch must be stopped when ctx is canceled.
+	WatchWorkflows(ctx context.Context, workerID string) (<-chan struct{}, error)
+}
+
+// StreamActions pushes Actions to a worker as soon as they are runnable.
+// It is an alternative to polling GetAction, which stays available for compatibility.
+func (h *Handler) StreamActions(req *proto.ActionRequest, stream grpc.ServerStreamingServer[proto.ActionResponse]) error {
+	if req.GetWorkerId() == "" {
+		return status.Errorf(codes.InvalidArgument, "invalid worker id:")
+	}
+	ctx := stream.Context()
+	if err := h.authorizeWorker(ctx, req.GetWorkerId()); err != nil {
+		return err
+	}
+	log := h.Logger.WithValues("worker", req.GetWorkerId())
+
+	changed, interval := h.watchWorkflows(ctx, log, req.GetWorkerId())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// sent is the key of the last Action sent on this stream.
+	// Writing the current state of a Workflow triggers a notification, sent prevents serving the same Action again.
+	var sent string
+	for {
+		resp, err := h.streamAction(ctx, req, sent)
+		switch {
+		case err == nil && resp != nil:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+			sent = actionKey(resp.GetWorkflowId(), resp.GetTaskId(), resp.GetActionId())
+		case err != nil:
+			sent = ""
+			if errors.Is(err, ErrBackendRead) || errors.Is(err, ErrBackendWrite) {
+				log.Info("unable to get next action, will retry", "error", err)
+				break
+			}
+			switch status.Code(err) {
+			case codes.NotFound, codes.FailedPrecondition:
+				log.V(1).Info("no action available", "reason", err)
+			default:
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchWorkflows returns a channel that receives a value when a Workflow of a worker changes, when the backend can
+// watch Workflows, and how often Workflows must be read regardless.
+func (h *Handler) watchWorkflows(ctx context.Context, log logr.Logger, workerID string) (<-chan struct{}, time.Duration) {
+	var changed <-chan struct{}
+	interval := defaultStreamPollInterval
+	if w, ok := h.BackendReadWriter.(WorkflowWatcher); ok {
+		ch, err := w.WatchWorkflows(ctx, workerID)
+		if err != nil {
+			log.Info("unable to watch workflows, falling back to polling", "error", err)
+		} else {
+			changed = ch
+			interval = defaultStreamResyncInterval
+		}
+	}
+	if h.StreamResyncInterval > 0 {
+		interval = h.StreamResyncInterval
+	}
+
+	return changed, interval
+}
+
+// streamAction returns the next Action for a worker.
+// nil is returned, without an error, when the next Action is the one that was last sent.
+func (h *Handler) streamAction(ctx context.Context, req *proto.ActionRequest, sent string) (*proto.ActionResponse, error) {
+	wf, task, action, err := h.selectAction(ctx, req.GetWorkerId(), req.GetWorkerAttributes(), req.GetBootId())
+	if err != nil {
+		return nil, err
+	}
+	if actionKey(wf.Namespace+"/"+wf.Name, task.ID, action.ID) == sent {
+		return nil, nil
+	}
+
+	return h.serveAction(ctx, req.GetWorkerId(), wf, task, action)
+}
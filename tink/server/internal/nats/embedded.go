@@ -0,0 +1,53 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// Embedded is a NATS server, with JetStream enabled, run in the Tink server for single binary deployments.
+// It has no authentication or TLS, any client that can reach it can read and publish the messages of any worker.
+type Embedded struct {
+	// BindAddrPort is the address and port on which the NATS server listens for clients. A random port is used when the port is 0.
+	BindAddrPort netip.AddrPort
+	// StoreDir is the directory in which JetStream stores streams. Defaults to a directory in the system temp directory.
+	StoreDir string
+}
+
+// Start starts the NATS server and returns its client URL. The server is shut down when the context is done.
+func (e Embedded) Start(ctx context.Context, log logr.Logger) (string, error) {
+	port := int(e.BindAddrPort.Port())
+	if port == 0 {
+		port = server.RANDOM_PORT
+	}
+	s, err := server.NewServer(&server.Options{
+		ServerName: "tink-server",
+		Host:       e.BindAddrPort.Addr().String(),
+		Port:       port,
+		JetStream:  true,
+		StoreDir:   e.StoreDir,
+		// The Tink server handles signals.
+		NoSigs: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		s.Shutdown()
+		return "", errors.New("embedded NATS server not ready for connections")
+	}
+	go func() {
+		<-ctx.Done()
+		s.Shutdown()
+		s.WaitForShutdown()
+	}()
+	log.Info("started embedded NATS server", "url", s.ClientURL(), "jetStreamDir", s.StoreDir())
+
+	return s.ClientURL(), nil
+}
@@ -0,0 +1,450 @@
+// Package nats is a front end of the Tink server for workers that use the NATS transport of the Tink agent.
+// The next Action of each worker with a pending or running Workflow is published to the JetStream subject
+// "<stream>.<worker ID>.<actions subject>" and the events workers publish to "<stream>.<worker ID>.<events subject>"
+// update the status of their Workflows. Which Action is next, and how events change a Workflow, is decided by the
+// same logic as the gRPC server. The agents must read their Actions from JetStream, as only the JSON events they
+// publish in that mode can be read.
+// Workers with an Action of a canceled Workflow are told to stop it on the core NATS subject
+// "<stream>.<worker ID>.<actions subject>.cancel", which is not held by the stream. It is published on every poll
+// until the worker reports the Action.
+// An event without an Action announces the boot ID of a worker, the running Action that rebooted the worker is then
+// complete when it was reported running with another boot ID.
+// Workers are not authenticated: any client of the NATS server can read the Actions of any worker and publish
+// events for it. The front end must only be used with a NATS server that restricts each worker to its own subjects,
+// and the Tink server refuses to run it when workers are required to authenticate.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// eventVersion is the version of the JSON events of the agent that are read.
+	eventVersion = "v1"
+	// consumerName is the durable JetStream consumer of the events of all workers.
+	consumerName = "tink-server"
+	// defaultPollInterval is how often Workflows are read for the next Action of each worker.
+	defaultPollInterval = 5 * time.Second
+
+	// DefaultStreamName, DefaultActionsSubject and DefaultEventsSubject are the defaults of the NATS transport of the agent.
+	DefaultStreamName     = "tinkerbell"
+	DefaultActionsSubject = "workflow_actions"
+	DefaultEventsSubject  = "workflow_status"
+)
+
+// ActionServer holds the Workflow state logic shared with the gRPC server.
+type ActionServer interface {
+	// NextAction returns the next Action of a worker, nil is returned for an Action that was served before unless resend is true.
+	// Whether an Action was served is recorded per Action, so that the Actions of workers sharing a Workflow are served once.
+	NextAction(ctx context.Context, workerID string, resend bool) (*proto.ActionResponse, error)
+	// ReportAction updates the Workflow of an Action with its status.
+	ReportAction(ctx context.Context, req *proto.ActionStatusRequest) error
+	// CanceledAction returns the Action a worker must stop because its Workflow was canceled, nil when there is none.
+	CanceledAction(ctx context.Context, workerID string) (*proto.ActionCancelResponse, error)
+	// Rebooted completes the running Action that rebooted a worker when bootID isn't the one it was reported running with.
+	Rebooted(ctx context.Context, workerID, bootID string) error
+}
+
+// WorkerLister is implemented by backends that can list the workers with a pending or running Workflow.
+type WorkerLister interface {
+	ReadWorkers(ctx context.Context) ([]string, error)
+}
+
+type Config struct {
+	Log     logr.Logger
+	Actions ActionServer
+	Workers WorkerLister
+	// URL is the NATS server to connect to, for example "nats://127.0.0.1:4222".
+	URL string
+	// StreamName is the JetStream stream holding the Actions and events. It is created, as a work queue, when it doesn't exist.
+	StreamName     string
+	ActionsSubject string
+	EventsSubject  string
+	// PollInterval is how often Workflows are read for the next Action of each worker. Defaults to 5 seconds.
+	// Workflows are also read as soon as an event is received.
+	PollInterval time.Duration
+}
+
+// action is an Action in the format read by the NATS transport of the agent.
+type action struct {
+	WorkerID       string       `json:"worker_id"`
+	TaskID         string       `json:"task_id"`
+	WorkflowID     string       `json:"workflow_id"`
+	ID             string       `json:"id"`
+	Name           string       `json:"name"`
+	Image          string       `json:"image"`
+	Args           []string     `json:"args,omitempty"`
+	Env            []env        `json:"env,omitempty"`
+	Volumes        []string     `json:"volumes,omitempty"`
+	Namespaces     namespaces   `json:"namespaces,omitzero"`
+	Retries        int          `json:"retries,omitempty"`
+	BackoffSeconds int          `json:"backoffSeconds,omitempty"`
+	TimeoutSeconds int          `json:"timeoutSeconds,omitempty"`
+	UpcomingImages []string     `json:"upcomingImages,omitempty"`
+	ImagePolicy    *imagePolicy `json:"imagePolicy,omitempty"`
+	Reboot         bool         `json:"reboot,omitempty"`
+}
+
+type env struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type namespaces struct {
+	Network string `json:"network,omitempty"`
+	PID     string `json:"pid,omitempty"`
+}
+
+type imagePolicy struct {
+	PublicKeys []string          `json:"publicKeys,omitempty"`
+	Digests    map[string]string `json:"digests,omitempty"`
+}
+
+// event is an event published by the NATS transport of the agent.
+type event struct {
+	Version           string    `json:"version"`
+	AgentID           string    `json:"agentId"`
+	WorkflowID        string    `json:"workflowId"`
+	TaskID            string    `json:"taskId"`
+	ActionID          string    `json:"actionId"`
+	ActionName        string    `json:"actionName"`
+	State             string    `json:"state"`
+	Message           string    `json:"message"`
+	ExecutionStart    time.Time `json:"executionStart"`
+	ExecutionStop     time.Time `json:"executionStop"`
+	ExecutionDuration string    `json:"executionDuration"`
+	Attempts          []attempt `json:"attempts"`
+	BootID            string    `json:"bootId"`
+}
+
+// cancellation tells the NATS transport of the agent to stop a running Action.
+type cancellation struct {
+	WorkflowID string `json:"workflowId"`
+	TaskID     string `json:"taskId"`
+	ActionID   string `json:"actionId"`
+	Message    string `json:"message"`
+}
+
+type attempt struct {
+	Attempt        int       `json:"attempt"`
+	State          string    `json:"state"`
+	ExecutionStart time.Time `json:"executionStart"`
+	ExecutionStop  time.Time `json:"executionStop"`
+	Message        string    `json:"message"`
+}
+
+// Start publishes Actions and reads events until the context is done.
+func (c *Config) Start(ctx context.Context) error {
+	if c.Actions == nil || c.Workers == nil {
+		return errors.New("the NATS front end requires an action server and a worker lister")
+	}
+	if c.StreamName == "" {
+		c.StreamName = DefaultStreamName
+	}
+	if c.ActionsSubject == "" {
+		c.ActionsSubject = DefaultActionsSubject
+	}
+	if c.EventsSubject == "" {
+		c.EventsSubject = DefaultEventsSubject
+	}
+	nc, err := nats.Connect(c.URL, nats.Name(consumerName), nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return err
+	}
+	if err := c.ensureStream(ctx, js); err != nil {
+		return err
+	}
+
+	cons, err := js.CreateOrUpdateConsumer(ctx, c.StreamName, jetstream.ConsumerConfig{
+		Durable:       consumerName,
+		Description:   "Events of the Tink agents",
+		FilterSubject: fmt.Sprintf("%v.*.%v", c.StreamName, c.EventsSubject),
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating the events consumer: %w", err)
+	}
+	// poll is signaled when an event changed a Workflow, the next Action of its worker may then be runnable.
+	poll := make(chan struct{}, 1)
+	cc, err := cons.Consume(func(msg jetstream.Msg) {
+		if c.handleEvent(ctx, msg) {
+			select {
+			case poll <- struct{}{}:
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("error consuming events: %w", err)
+	}
+	defer cc.Stop()
+	c.Log.Info("NATS front end started", "url", c.URL, "stream", c.StreamName)
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// resend holds the workers whose current Action was served but couldn't be published.
+	resend := map[string]bool{}
+	for {
+		c.publishActions(ctx, js, resend)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll:
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensureStream creates the stream, as a work queue of the Actions and events of all workers, when it doesn't exist.
+func (c *Config) ensureStream(ctx context.Context, js jetstream.JetStream) error {
+	_, err := js.Stream(ctx, c.StreamName)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("error reading stream %q, the NATS server must have JetStream enabled: %w", c.StreamName, err)
+	}
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:        c.StreamName,
+		Description: "Tinkerbell Actions and events",
+		Subjects: []string{
+			fmt.Sprintf("%v.*.%v", c.StreamName, c.ActionsSubject),
+			fmt.Sprintf("%v.*.%v", c.StreamName, c.EventsSubject),
+		},
+		Retention: jetstream.WorkQueuePolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating stream %q: %w", c.StreamName, err)
+	}
+	c.Log.Info("created stream", "stream", c.StreamName)
+
+	return nil
+}
+
+// publishActions publishes the next Action of each worker that has one that was not published yet.
+func (c *Config) publishActions(ctx context.Context, js jetstream.JetStream, resend map[string]bool) {
+	workers, err := c.Workers.ReadWorkers(ctx)
+	if err != nil {
+		c.Log.Info("unable to read workers", "error", err)
+		return
+	}
+	for _, w := range workers {
+		// Worker IDs are subject tokens, which can't hold these characters.
+		if strings.ContainsAny(w, ".*> \t") {
+			c.Log.V(1).Info("worker ID can't be used in a NATS subject", "worker", w)
+			continue
+		}
+		c.publishCancel(ctx, js, w)
+		resp, err := c.Actions.NextAction(ctx, w, resend[w])
+		if err != nil {
+			switch status.Code(err) {
+			case codes.NotFound, codes.FailedPrecondition:
+				c.Log.V(1).Info("no action available", "worker", w, "reason", err)
+			default:
+				c.Log.Info("unable to get next action", "worker", w, "error", err)
+			}
+			continue
+		}
+		if resp == nil {
+			continue
+		}
+		if err := c.publish(ctx, js, resp); err != nil {
+			c.Log.Info("unable to publish action, will retry", "worker", w, "action", resp.GetName(), "error", err)
+			resend[w] = true
+			continue
+		}
+		delete(resend, w)
+		c.Log.Info("published action", "worker", w, "workflow", resp.GetWorkflowId(), "action", resp.GetName())
+	}
+}
+
+// publish publishes an Action to the actions subject of its worker.
+func (c *Config) publish(ctx context.Context, js jetstream.JetStream, resp *proto.ActionResponse) error {
+	b, err := json.Marshal([]action{toAction(resp)})
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(fmt.Sprintf("%v.%v.%v", c.StreamName, resp.GetWorkerId(), c.ActionsSubject))
+	msg.Data = b
+	msg.Header.Set("Content-Type", "application/json")
+	// An Action published again, after an error, is only stored once.
+	msg.Header.Set(jetstream.MsgIDHeader, fmt.Sprintf("%v/%v/%v", resp.GetWorkflowId(), resp.GetTaskId(), resp.GetActionId()))
+	_, err = js.PublishMsg(ctx, msg)
+
+	return err
+}
+
+// publishCancel tells a worker to stop its running Action when its Workflow was canceled.
+func (c *Config) publishCancel(ctx context.Context, js jetstream.JetStream, worker string) {
+	resp, err := c.Actions.CanceledAction(ctx, worker)
+	if err != nil {
+		c.Log.Info("unable to read canceled action", "worker", worker, "error", err)
+		return
+	}
+	if resp == nil {
+		return
+	}
+	b, err := json.Marshal(cancellation{
+		WorkflowID: resp.GetWorkflowId(),
+		TaskID:     resp.GetTaskId(),
+		ActionID:   resp.GetActionId(),
+		Message:    resp.GetMessage(),
+	})
+	if err != nil {
+		return
+	}
+	msg := nats.NewMsg(fmt.Sprintf("%v.%v.%v.cancel", c.StreamName, worker, c.ActionsSubject))
+	msg.Data = b
+	msg.Header.Set("Content-Type", "application/json")
+	// Cancellations are published with core NATS, the stream doesn't hold the subject.
+	if err := js.Conn().PublishMsg(msg); err != nil {
+		c.Log.Info("unable to publish cancellation, will retry", "worker", worker, "error", err)
+		return
+	}
+	c.Log.V(1).Info("published cancellation", "worker", worker, "workflow", resp.GetWorkflowId(), "action", resp.GetActionId())
+}
+
+// handleEvent updates the Workflow of an event and returns true when it did.
+// Events that can't be applied are terminated, events that failed to apply are delivered again.
+func (c *Config) handleEvent(ctx context.Context, msg jetstream.Msg) bool {
+	var e event
+	if err := json.Unmarshal(msg.Data(), &e); err != nil || e.Version != eventVersion {
+		c.Log.Info("discarding event that isn't a JSON event of a known version", "subject", msg.Subject(), "version", e.Version, "error", err)
+		_ = msg.Term()
+		return false
+	}
+	// Events must be published on the subject of the worker they report for. This only authenticates the worker
+	// when the NATS server restricts each worker to its own subjects.
+	if worker := strings.Split(msg.Subject(), "."); len(worker) != 3 || worker[1] != e.AgentID {
+		c.Log.Info("discarding event published by another worker", "subject", msg.Subject(), "worker", e.AgentID)
+		_ = msg.Term()
+		return false
+	}
+	// An event without an Action announces the boot ID of a worker that started reading its Actions.
+	if e.ActionID == "" {
+		if err := c.Actions.Rebooted(ctx, e.AgentID, e.BootID); err != nil {
+			c.Log.Info("unable to complete the action that rebooted the worker, will retry", "worker", e.AgentID, "error", err)
+			_ = msg.NakWithDelay(time.Second)
+			return false
+		}
+		_ = msg.Ack()
+		return true
+	}
+	if err := c.Actions.ReportAction(ctx, toStatusRequest(e)); err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.NotFound:
+			c.Log.Info("discarding event", "worker", e.AgentID, "action", e.ActionName, "error", err)
+			_ = msg.Term()
+		default:
+			c.Log.Info("unable to report action status, will retry", "worker", e.AgentID, "action", e.ActionName, "error", err)
+			_ = msg.NakWithDelay(time.Second)
+		}
+		return false
+	}
+	_ = msg.Ack()
+
+	return true
+}
+
+// toAction converts an ActionResponse the same way the gRPC transport of the agent does.
+func toAction(resp *proto.ActionResponse) action {
+	a := action{
+		WorkerID:       resp.GetWorkerId(),
+		TaskID:         resp.GetTaskId(),
+		WorkflowID:     resp.GetWorkflowId(),
+		ID:             resp.GetActionId(),
+		Name:           resp.GetName(),
+		Image:          resp.GetImage(),
+		Args:           resp.GetCommand(),
+		Volumes:        resp.GetVolumes(),
+		Namespaces:     namespaces{Network: resp.GetNetwork(), PID: resp.GetPid()},
+		Retries:        int(resp.GetRetries()),
+		BackoffSeconds: int(resp.GetBackoff()),
+		TimeoutSeconds: int(resp.GetTimeout()),
+		UpcomingImages: resp.GetUpcomingImages(),
+		Reboot:         resp.GetReboot(),
+	}
+	for _, v := range resp.GetEnvironment() {
+		k, v, _ := strings.Cut(v, "=")
+		a.Env = append(a.Env, env{Key: k, Value: v})
+	}
+	if p := resp.GetImagePolicy(); p != nil {
+		a.ImagePolicy = &imagePolicy{PublicKeys: p.GetPublicKeys(), Digests: p.GetDigests()}
+	}
+
+	return a
+}
+
+func toStatusRequest(e event) *proto.ActionStatusRequest {
+	req := &proto.ActionStatusRequest{
+		WorkflowId:        toPtr(e.WorkflowID),
+		WorkerId:          toPtr(e.AgentID),
+		TaskId:            toPtr(e.TaskID),
+		ActionId:          toPtr(e.ActionID),
+		ActionName:        toPtr(e.ActionName),
+		ActionState:       toState(e.State),
+		ExecutionStart:    timestamppb.New(e.ExecutionStart),
+		ExecutionStop:     timestamppb.New(e.ExecutionStop),
+		ExecutionDuration: toPtr(e.ExecutionDuration),
+		Message:           &proto.ActionMessage{Message: toPtr(e.Message)},
+		BootId:            toPtr(e.BootID),
+	}
+	for _, a := range e.Attempts {
+		req.Attempts = append(req.Attempts, &proto.ActionAttempt{
+			Attempt:        toPtr(int64(a.Attempt)),
+			State:          toState(a.State),
+			ExecutionStart: timestamppb.New(a.ExecutionStart),
+			ExecutionStop:  timestamppb.New(a.ExecutionStop),
+			Message:        toPtr(a.Message),
+		})
+	}
+
+	return req
+}
+
+// toState converts the state of an Action in an event of the agent.
+func toState(s string) *proto.StateType {
+	switch s {
+	case "running":
+		return toPtr(proto.StateType_RUNNING)
+	case "success":
+		return toPtr(proto.StateType_SUCCESS)
+	case "failure":
+		return toPtr(proto.StateType_FAILED)
+	case "timeout":
+		return toPtr(proto.StateType_TIMEOUT)
+	case "verification_failed":
+		return toPtr(proto.StateType_VERIFICATION_FAILED)
+	case "canceled":
+		return toPtr(proto.StateType_CANCELED)
+	default:
+		return toPtr(proto.StateType_UNSPECIFIED)
+	}
+}
+
+func toPtr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,352 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	v1alpha1 "github.com/tinkerbell/tinkerbell/pkg/api/v1alpha1/tinkerbell"
+	"github.com/tinkerbell/tinkerbell/pkg/proto"
+	grpcinternal "github.com/tinkerbell/tinkerbell/tink/server/internal/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// mockActionServer serves a single Action and records the status reports it receives.
+type mockActionServer struct {
+	mu      sync.Mutex
+	action  *proto.ActionResponse
+	served  bool
+	reports chan *proto.ActionStatusRequest
+	// canceled is returned by CanceledAction.
+	canceled *proto.ActionCancelResponse
+	// boots receives the boot IDs of Rebooted.
+	boots chan string
+}
+
+func (m *mockActionServer) NextAction(_ context.Context, workerID string, resend bool) (*proto.ActionResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if workerID != m.action.GetWorkerId() {
+		return nil, status.Error(codes.NotFound, "no workflows found")
+	}
+	if m.served && !resend {
+		return nil, nil
+	}
+	m.served = true
+	return m.action, nil
+}
+
+func (m *mockActionServer) ReportAction(_ context.Context, req *proto.ActionStatusRequest) error {
+	if req.GetActionId() != m.action.GetActionId() {
+		return status.Error(codes.NotFound, "action not found")
+	}
+	m.reports <- req
+	return nil
+}
+
+func (m *mockActionServer) CanceledAction(_ context.Context, workerID string) (*proto.ActionCancelResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if workerID != m.action.GetWorkerId() {
+		return nil, nil
+	}
+	return m.canceled, nil
+}
+
+func (m *mockActionServer) Rebooted(_ context.Context, _, bootID string) error {
+	m.boots <- bootID
+	return nil
+}
+
+type mockWorkerLister []string
+
+func (m mockWorkerLister) ReadWorkers(context.Context) ([]string, error) {
+	return m, nil
+}
+
+func TestFrontEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, err := Embedded{BindAddrPort: netip.MustParseAddrPort("127.0.0.1:0"), StoreDir: filepath.Join(t.TempDir(), "jetstream")}.Start(ctx, logr.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := &mockActionServer{
+		action: &proto.ActionResponse{
+			WorkflowId:  toPtr("default/machine1"),
+			TaskId:      toPtr("provision"),
+			WorkerId:    toPtr("00:00:5e:00:53:01"),
+			ActionId:    toPtr("stream"),
+			Name:        toPtr("stream"),
+			Image:       toPtr("quay.io/tinkerbell/actions/image2disk"),
+			Timeout:     toPtr(int64(600)),
+			Command:     []string{"/bin/sh", "-c"},
+			Environment: []string{"DEST_DISK=/dev/sda", "EMPTY="},
+			Pid:         toPtr("host"),
+			Retries:     toPtr(int64(2)),
+			Reboot:      toPtr(false),
+		},
+		reports: make(chan *proto.ActionStatusRequest, 10),
+		boots:   make(chan string, 10),
+	}
+	fe := &Config{
+		Log:          logr.Discard(),
+		Actions:      as,
+		Workers:      mockWorkerLister{"00:00:5e:00:53:01", "unknown", "in.valid"},
+		URL:          url,
+		PollInterval: 100 * time.Millisecond,
+	}
+	go func() {
+		if err := fe.Start(ctx); err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	}()
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	js, _ := jetstream.New(nc)
+	var cons jetstream.Consumer
+	// The stream is created by the front end.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		cons, err = js.CreateOrUpdateConsumer(ctx, DefaultStreamName, jetstream.ConsumerConfig{
+			Durable:       "agent",
+			FilterSubject: "tinkerbell.00:00:5e:00:53:01.workflow_actions",
+			AckPolicy:     jetstream.AckExplicitPolicy,
+		})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	msg, err := cons.Next(jetstream.FetchMaxWait(5 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = msg.Ack()
+	var got []map[string]any
+	if err := json.Unmarshal(msg.Data(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{{
+		"worker_id":      "00:00:5e:00:53:01",
+		"task_id":        "provision",
+		"workflow_id":    "default/machine1",
+		"id":             "stream",
+		"name":           "stream",
+		"image":          "quay.io/tinkerbell/actions/image2disk",
+		"args":           []any{"/bin/sh", "-c"},
+		"env":            []any{map[string]any{"key": "DEST_DISK", "value": "/dev/sda"}, map[string]any{"key": "EMPTY", "value": ""}},
+		"namespaces":     map[string]any{"pid": "host"},
+		"retries":        float64(2),
+		"timeoutSeconds": float64(600),
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected action (-want +got):\n%s", diff)
+	}
+
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := []string{
+		`action: {...}, message: running action, state: running`,
+		`{"version": "v2", "agentId": "00:00:5e:00:53:01", "actionId": "stream"}`,
+		`{"version": "v1", "agentId": "00:00:5e:00:53:02", "actionId": "stream"}`,
+		`{"version": "v1", "agentId": "00:00:5e:00:53:01", "workflowId": "default/machine1", "taskId": "provision", "actionId": "stream",
+		  "actionName": "stream", "state": "success", "message": "done", "executionStart": "2025-01-02T03:04:05Z", "executionDuration": "1s",
+		  "attempts": [{"attempt": 1, "state": "failure", "executionStart": "2025-01-02T03:04:05Z"}, {"attempt": 2, "state": "success"}], "bootId": "boot-1"}`,
+		`{"version": "v1", "agentId": "00:00:5e:00:53:01", "bootId": "boot-2"}`,
+	}
+	for _, e := range events {
+		if _, err := js.Publish(ctx, "tinkerbell.00:00:5e:00:53:01.workflow_status", []byte(e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the v1 JSON event of the worker of the subject is reported.
+	select {
+	case req := <-as.reports:
+		wantReq := toStatusRequest(event{
+			AgentID: "00:00:5e:00:53:01", WorkflowID: "default/machine1", TaskID: "provision", ActionID: "stream", ActionName: "stream",
+			State: "success", Message: "done", ExecutionStart: start, ExecutionDuration: "1s",
+			Attempts: []attempt{{Attempt: 1, State: "failure", ExecutionStart: start}, {Attempt: 2, State: "success"}}, BootID: "boot-1",
+		})
+		if !protobuf.Equal(wantReq, req) {
+			t.Errorf("got report %v, want %v", req, wantReq)
+		}
+		if req.GetActionState() != proto.StateType_SUCCESS || req.GetAttempts()[0].GetState() != proto.StateType_FAILED {
+			t.Errorf("unexpected states in report %v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no action status reported")
+	}
+	select {
+	case req := <-as.reports:
+		t.Errorf("unexpected report %v", req)
+	case <-time.After(200 * time.Millisecond):
+	}
+	// An event without an Action announces the boot ID of the worker.
+	select {
+	case got := <-as.boots:
+		if got != "boot-2" {
+			t.Errorf("got boot ID %q, want %q", got, "boot-2")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("boot ID not announced")
+	}
+
+	// The Action is published once.
+	if msg, err := cons.Next(jetstream.FetchMaxWait(300 * time.Millisecond)); err == nil {
+		t.Errorf("action published again: %s", msg.Data())
+	}
+
+	// The worker is told to stop the Action of a canceled Workflow.
+	sub, err := nc.SubscribeSync("tinkerbell.00:00:5e:00:53:01.workflow_actions.cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	as.mu.Lock()
+	as.canceled = &proto.ActionCancelResponse{
+		WorkflowId: toPtr("default/machine1"),
+		TaskId:     toPtr("provision"),
+		ActionId:   toPtr("stream"),
+		Message:    toPtr("workflow canceled"),
+	}
+	as.mu.Unlock()
+	cmsg, err := sub.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotCancel map[string]any
+	if err := json.Unmarshal(cmsg.Data, &gotCancel); err != nil {
+		t.Fatal(err)
+	}
+	wantCancel := map[string]any{"workflowId": "default/machine1", "taskId": "provision", "actionId": "stream", "message": "workflow canceled"}
+	if diff := cmp.Diff(wantCancel, gotCancel); diff != "" {
+		t.Errorf("unexpected cancellation (-want +got):\n%s", diff)
+	}
+}
+
+// workflowStore holds a single Workflow and counts its writes.
+type workflowStore struct {
+	mu       sync.Mutex
+	workflow *v1alpha1.Workflow
+	writes   int
+}
+
+func (s *workflowStore) Read(context.Context, string, string) (*v1alpha1.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workflow.DeepCopy(), nil
+}
+
+func (s *workflowStore) ReadAll(context.Context, string) ([]v1alpha1.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return []v1alpha1.Workflow{*s.workflow.DeepCopy()}, nil
+}
+
+func (s *workflowStore) Write(_ context.Context, wf *v1alpha1.Workflow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workflow = wf.DeepCopy()
+	s.writes++
+	return nil
+}
+
+func TestFrontEndMultipleWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, err := Embedded{BindAddrPort: netip.MustParseAddrPort("127.0.0.1:0"), StoreDir: filepath.Join(t.TempDir(), "jetstream")}.Start(ctx, logr.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	workers := []string{"00:00:5e:00:53:01", "00:00:5e:00:53:02"}
+	store := &workflowStore{workflow: &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "default"},
+		Status: v1alpha1.WorkflowStatus{
+			State: v1alpha1.WorkflowStatePending,
+			Tasks: []v1alpha1.Task{
+				{ID: "a", Name: "a", WorkerAddr: workers[0], Actions: []v1alpha1.Action{{ID: "a1", Name: "a1", Image: "a1", State: v1alpha1.WorkflowStatePending}}},
+				{ID: "b", Name: "b", WorkerAddr: workers[1], Actions: []v1alpha1.Action{{ID: "b1", Name: "b1", Image: "b1", State: v1alpha1.WorkflowStatePending}}},
+			},
+		},
+	}}
+	fe := &Config{
+		Log:          logr.Discard(),
+		Actions:      &grpcinternal.Handler{Logger: logr.Discard(), BackendReadWriter: store},
+		Workers:      mockWorkerLister(workers),
+		URL:          url,
+		PollInterval: 50 * time.Millisecond,
+	}
+	go func() {
+		if err := fe.Start(ctx); err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	}()
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	js, _ := jetstream.New(nc)
+	consumers := map[string]jetstream.Consumer{}
+	for i, w := range workers {
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			cons, err := js.CreateOrUpdateConsumer(ctx, DefaultStreamName, jetstream.ConsumerConfig{
+				Durable:       "agent" + strconv.Itoa(i),
+				FilterSubject: "tinkerbell." + w + ".workflow_actions",
+				AckPolicy:     jetstream.AckExplicitPolicy,
+			})
+			if err == nil {
+				consumers[w] = cons
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal(err)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	// Serving the Action of one worker must not make the Action of the other worker be served again.
+	time.Sleep(500 * time.Millisecond)
+	for w, want := range map[string]string{workers[0]: "a1", workers[1]: "b1"} {
+		batch, err := consumers[w].FetchNoWait(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for msg := range batch.Messages() {
+			var actions []action
+			if err := json.Unmarshal(msg.Data(), &actions); err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range actions {
+				got = append(got, a.ID)
+			}
+		}
+		if diff := cmp.Diff([]string{want}, got); diff != "" {
+			t.Errorf("unexpected actions published for worker %s (-want +got):\n%s", w, diff)
+		}
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.writes != len(workers) {
+		t.Errorf("got %d workflow writes, want %d", store.writes, len(workers))
+	}
+}
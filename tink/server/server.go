@@ -2,24 +2,95 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
+	"os"
 	"time"
 
 	"github.com/go-logr/logr"
 	grpcprometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/tinkerbell/tinkerbell/pkg/proto"
 	grpcinternal "github.com/tinkerbell/tinkerbell/tink/server/internal/grpc"
+	natsinternal "github.com/tinkerbell/tinkerbell/tink/server/internal/nats"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
+// DiscoveredLabel is the label added to Hardware, and Workflows, created by auto-enrollment.
+const DiscoveredLabel = grpcinternal.DiscoveredLabel
+
+// DefaultActionLogLines is the number of lines of Action output kept per Action when ActionLogLines is not set.
+const DefaultActionLogLines = grpcinternal.DefaultActionLogLines
+
 type Config struct {
 	Backend      grpcinternal.BackendReadWriter
 	BindAddrPort netip.AddrPort
 	Logger       logr.Logger
+	// AutoEnrollment configures creating Hardware for workers that are not known.
+	AutoEnrollment AutoEnrollment
+	// BlockOnInventoryDrift prevents Workflows that haven't started from running on Hardware
+	// whose inventory changed until the change is acknowledged.
+	BlockOnInventoryDrift bool
+	// TLS configures serving gRPC over TLS. Plaintext is served when no certificate is configured.
+	TLS TLS
+	// WorkerTokenSecret, when set, requires workers without a verified client certificate to authenticate
+	// with a worker token derived from this secret. See the workertoken package.
+	WorkerTokenSecret string
+	// AdminToken enables admin RPCs, like CancelWorkflow, for callers that send it in the
+	// "x-tinkerbell-admin-token" metadata key. Admin RPCs are denied when it is empty.
+	AdminToken string
+	// ActionLogLines is the number of lines of the output of an Action that are kept and added
+	// to its status message when it fails or times out. Defaults to DefaultActionLogLines.
+	ActionLogLines int
+	// NATS configures serving Actions to workers that use the NATS transport of the Tink agent.
+	NATS NATS
+}
+
+// NATS configures the NATS front end. It publishes the next Action of each worker to JetStream and updates
+// Workflows from the events workers publish. Workers must use the NATS transport of the agent with JetStream enabled.
+// Workers are not authenticated, it can't be enabled with a WorkerTokenSecret or a client CA.
+type NATS struct {
+	// Enabled turns on the NATS front end. The Backend must support listing workers.
+	Enabled bool
+	// URL is the NATS server to connect to, for example "nats://127.0.0.1:4222". It is not used when EmbeddedBindAddrPort is set.
+	URL string
+	// EmbeddedBindAddrPort, when set, runs a NATS server, with JetStream, in the Tink server listening on it.
+	// The embedded server has no authentication or TLS, it must only be reachable from trusted networks.
+	EmbeddedBindAddrPort netip.AddrPort
+	// EmbeddedStoreDir is the directory in which the embedded NATS server stores streams.
+	EmbeddedStoreDir string
+	// StreamName is the JetStream stream of Actions and events, it is created when it doesn't exist.
+	StreamName     string
+	ActionsSubject string
+	EventsSubject  string
+}
+
+// TLS configures serving gRPC over TLS.
+type TLS struct {
+	// CertFile is the path to a PEM encoded certificate, or certificate chain, for the server.
+	CertFile string
+	// KeyFile is the path to the PEM encoded private key of the certificate.
+	KeyFile string
+	// ClientCAFile is the path to a PEM encoded CA bundle used to verify client certificates.
+	// When set, clients must present a certificate signed by one of the CAs (mTLS) and the certificate
+	// must name the worker ID, in its Common Name or a SAN, that the client sends in requests.
+	ClientCAFile string
+}
+
+// AutoEnrollment configures creating Hardware, from the attributes a worker sends, for workers that are not known.
+type AutoEnrollment struct {
+	// Enabled turns on auto-enrollment. The Backend must support creating Hardware and Workflows.
+	Enabled bool
+	// Namespace is the namespace in which Hardware and Workflows are created.
+	Namespace string
+	// TemplateRef is the name of a Template used to create a Workflow for enrolled Hardware. Optional.
+	TemplateRef string
 }
 
 // Option is a functional option type.
@@ -46,6 +117,55 @@ func WithLogger(l logr.Logger) Option {
 	}
 }
 
+// WithAutoEnrollment sets the auto-enrollment configuration for the server.
+func WithAutoEnrollment(ae AutoEnrollment) Option {
+	return func(c *Config) {
+		c.AutoEnrollment = ae
+	}
+}
+
+// WithBlockOnInventoryDrift sets whether Workflows are blocked on Hardware with unacknowledged inventory drift.
+func WithBlockOnInventoryDrift(block bool) Option {
+	return func(c *Config) {
+		c.BlockOnInventoryDrift = block
+	}
+}
+
+// WithTLS sets the TLS configuration for the server.
+func WithTLS(t TLS) Option {
+	return func(c *Config) {
+		c.TLS = t
+	}
+}
+
+// WithWorkerTokenSecret sets the secret from which worker tokens are derived.
+func WithWorkerTokenSecret(secret string) Option {
+	return func(c *Config) {
+		c.WorkerTokenSecret = secret
+	}
+}
+
+// WithAdminToken sets the token that callers of admin RPCs must send.
+func WithAdminToken(token string) Option {
+	return func(c *Config) {
+		c.AdminToken = token
+	}
+}
+
+// WithActionLogLines sets the number of lines of Action output kept per Action.
+func WithActionLogLines(lines int) Option {
+	return func(c *Config) {
+		c.ActionLogLines = lines
+	}
+}
+
+// WithNATS sets the NATS front end configuration for the server.
+func WithNATS(n NATS) Option {
+	return func(c *Config) {
+		c.NATS = n
+	}
+}
+
 func NewConfig(opts ...Option) *Config {
 	c := &Config{}
 	for _, opt := range opts {
@@ -59,6 +179,44 @@ func (c *Config) Start(ctx context.Context, log logr.Logger) error {
 		BackendReadWriter: c.Backend,
 		Logger:            log,
 		NowFunc:           time.Now,
+
+		BlockOnInventoryDrift: c.BlockOnInventoryDrift,
+		WorkerTokenSecret:     []byte(c.WorkerTokenSecret),
+		AdminToken:            c.AdminToken,
+		ActionLogLines:        c.ActionLogLines,
+	}
+	if irw, ok := c.Backend.(grpcinternal.InventoryReadWriter); ok {
+		s.InventoryReadWriter = irw
+	}
+	if er, ok := c.Backend.(grpcinternal.HardwareEventRecorder); ok {
+		s.EventRecorder = er
+	}
+	if c.AutoEnrollment.Enabled {
+		rc, ok := c.Backend.(grpcinternal.AutoEnrollmentReadCreator)
+		if !ok {
+			return errors.New("auto-enrollment is enabled but the backend does not support creating Hardware and Workflows")
+		}
+		ns := c.AutoEnrollment.Namespace
+		if ns == "" {
+			ns = "default"
+		}
+		s.AutoCapabilities.Enrollment = grpcinternal.AutoEnrollment{
+			Enabled:     true,
+			Namespace:   ns,
+			TemplateRef: c.AutoEnrollment.TemplateRef,
+			ReadCreator: rc,
+		}
+		log.Info("auto-enrollment enabled", "namespace", ns, "templateRef", c.AutoEnrollment.TemplateRef)
+	}
+
+	if c.NATS.Enabled {
+		// Workers that use NATS are not authenticated, so they would bypass worker tokens and client certificates.
+		if c.WorkerTokenSecret != "" || c.TLS.ClientCAFile != "" {
+			return errors.New("the NATS front end doesn't authenticate workers and can't be enabled when worker tokens or client certificates are required")
+		}
+		if err := c.NATS.start(ctx, log.WithValues("frontend", "nats"), s, c.Backend); err != nil {
+			return err
+		}
 	}
 
 	params := []grpc.ServerOption{
@@ -66,6 +224,19 @@ func (c *Config) Start(ctx context.Context, log logr.Logger) error {
 		grpc.UnaryInterceptor(grpcprometheus.UnaryServerInterceptor),
 		grpc.StreamInterceptor(grpcprometheus.StreamServerInterceptor),
 	}
+	if c.TLS.CertFile != "" || c.TLS.KeyFile != "" {
+		tc, err := c.TLS.config()
+		if err != nil {
+			return err
+		}
+		params = append(params, grpc.Creds(credentials.NewTLS(tc)))
+		log.Info("serving gRPC over TLS", "mTLS", c.TLS.ClientCAFile != "")
+	} else if c.TLS.ClientCAFile != "" {
+		return errors.New("a TLS certificate and key are required to verify client certificates")
+	}
+	if c.WorkerTokenSecret != "" {
+		log.Info("worker token authentication enabled")
+	}
 
 	// register servers
 	gs := grpc.NewServer(params...)
@@ -99,3 +270,64 @@ func (c *Config) Start(ctx context.Context, log logr.Logger) error {
 
 	return nil
 }
+
+// config returns the tls.Config for serving gRPC.
+func (t TLS) config() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate and key: %w", err)
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if t.ClientCAFile != "" {
+		b, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in client CA file %q", t.ClientCAFile)
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tc, nil
+}
+
+// start starts the NATS front end, and the embedded NATS server when it is enabled, in the background.
+func (n NATS) start(ctx context.Context, log logr.Logger, actions natsinternal.ActionServer, backend grpcinternal.BackendReadWriter) error {
+	wl, ok := backend.(natsinternal.WorkerLister)
+	if !ok {
+		return errors.New("the NATS front end is enabled but the backend does not support listing workers")
+	}
+	url := n.URL
+	if n.EmbeddedBindAddrPort.IsValid() {
+		u, err := natsinternal.Embedded{BindAddrPort: n.EmbeddedBindAddrPort, StoreDir: n.EmbeddedStoreDir}.Start(ctx, log)
+		if err != nil {
+			return fmt.Errorf("failed to start embedded NATS server: %w", err)
+		}
+		url = u
+	}
+	if url == "" {
+		return errors.New("the NATS front end requires a NATS server URL or an embedded NATS server")
+	}
+	fe := &natsinternal.Config{
+		Log:            log,
+		Actions:        actions,
+		Workers:        wl,
+		URL:            url,
+		StreamName:     n.StreamName,
+		ActionsSubject: n.ActionsSubject,
+		EventsSubject:  n.EventsSubject,
+	}
+	go func() {
+		if err := fe.Start(ctx); err != nil {
+			log.Error(err, "NATS front end stopped")
+		}
+	}()
+
+	return nil
+}